@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/config"
+)
+
+// cookieJar replays the cookies a browser would keep for the API.
+type cookieJar map[string]*http.Cookie
+
+func (j cookieJar) do(e *testEnv, method, path, csrf string, body any) *httptest.ResponseRecorder {
+	var raw []byte
+	if body != nil {
+		raw, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, e.expand(path), bytes.NewReader(raw))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if csrf != "" {
+		req.Header.Set(config.Cookie.CSRFHeader, csrf)
+	}
+	for _, c := range j {
+		req.AddCookie(c)
+	}
+	res := httptest.NewRecorder()
+	e.router.ServeHTTP(res, req)
+	for _, c := range res.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(j, c.Name)
+		} else {
+			j[c.Name] = c
+		}
+	}
+	return res
+}
+
+// TestCookieAuth runs the browser flow with token_in_cookie on: tokens live
+// in HttpOnly cookies and every unsafe request must echo the CSRF cookie in
+// the CSRF header.
+func TestCookieAuth(t *testing.T) {
+	e := newTestEnv(t, func(c *config.Config) {
+		c.TokenInCookie = true
+		c.Cookie.SameSite = "strict"
+	})
+	e.seed(t, "owner", true)
+	jar := cookieJar{}
+
+	res := jar.do(e, http.MethodPost, "/api/login", "", gin.H{"email": "owner@example.com", "password": testPassword})
+	if res.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", res.Code, res.Body)
+	}
+	body := decode[map[string]any](t, res)
+	if _, ok := body["token"]; ok {
+		t.Fatalf("access token leaked into the body: %s", res.Body)
+	}
+	csrf, _ := body["csrf_token"].(string)
+	if csrf == "" {
+		t.Fatalf("no csrf_token in %s", res.Body)
+	}
+
+	for _, want := range []struct {
+		name, path string
+		httpOnly   bool
+	}{
+		{config.Cookie.AccessName, "/api", true},
+		{config.Cookie.RefreshName, "/api/token", true},
+		{config.Cookie.CSRFName, "/", false},
+	} {
+		c := jar[want.name]
+		if c == nil {
+			t.Fatalf("cookie %s not set", want.name)
+		}
+		if c.HttpOnly != want.httpOnly || !c.Secure || c.SameSite != http.SameSiteStrictMode || c.Path != want.path {
+			t.Fatalf("cookie %s: HttpOnly=%v Secure=%v SameSite=%v Path=%q", c.Name, c.HttpOnly, c.Secure, c.SameSite, c.Path)
+		}
+	}
+	if jar[config.Cookie.CSRFName].Value != csrf {
+		t.Fatal("csrf cookie and body disagree")
+	}
+
+	if res := jar.do(e, http.MethodGet, "/api/me", "", nil); res.Code != http.StatusOK {
+		t.Fatalf("GET with cookie: %d %s", res.Code, res.Body)
+	}
+
+	res = jar.do(e, http.MethodPost, "/api/projects", csrf, gin.H{"name": "Cookies"})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("POST with csrf: %d %s", res.Code, res.Body)
+	}
+	e.vars["project"] = id(decode[map[string]any](t, res)["id"])
+	statuses, err := e.stores.Statuses.List(mustAtoi(e.vars["project"]), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := []int{}
+	for i := len(statuses) - 1; i >= 0; i-- {
+		order = append(order, statuses[i].ID)
+	}
+
+	unsafe := []struct {
+		method, path string
+		body         any
+		want         int
+	}{
+		{http.MethodPost, "/api/projects", gin.H{"name": "Forged"}, http.StatusCreated},
+		{http.MethodPatch, "/api/me", gin.H{"name": "Owner", "email": "owner@example.com"}, http.StatusOK},
+		{http.MethodPut, "/api/projects/{project}/statuses/order", gin.H{"statusIds": order}, http.StatusOK},
+		{http.MethodDelete, "/api/projects/{project}", nil, http.StatusNoContent},
+	}
+	for _, u := range unsafe {
+		for name, header := range map[string]string{"missing": "", "wrong": csrf + "x"} {
+			if res := jar.do(e, u.method, u.path, header, u.body); res.Code != http.StatusForbidden {
+				t.Fatalf("%s %s with %s csrf: %d %s", u.method, u.path, name, res.Code, res.Body)
+			}
+		}
+		if res := jar.do(e, u.method, u.path, csrf, u.body); res.Code != u.want {
+			t.Fatalf("%s %s with csrf: %d %s", u.method, u.path, res.Code, res.Body)
+		}
+	}
+
+	// A CSRF token minted for another session is refused even when the
+	// header and cookie agree.
+	other := cookieJar{}
+	if res := other.do(e, http.MethodPost, "/api/login", "", gin.H{"email": "owner@example.com", "password": testPassword}); res.Code != http.StatusOK {
+		t.Fatalf("second login: %d", res.Code)
+	}
+	planted := other[config.Cookie.CSRFName].Value
+	jar[config.Cookie.CSRFName] = &http.Cookie{Name: config.Cookie.CSRFName, Value: planted}
+	if res := jar.do(e, http.MethodPatch, "/api/me", planted, gin.H{"name": "Owner", "email": "owner@example.com"}); res.Code != http.StatusForbidden {
+		t.Fatalf("PATCH with another session's csrf: %d %s", res.Code, res.Body)
+	}
+	jar[config.Cookie.CSRFName] = &http.Cookie{Name: config.Cookie.CSRFName, Value: csrf}
+
+	if res := jar.do(e, http.MethodPost, "/api/token/refresh", "", nil); res.Code != http.StatusForbidden {
+		t.Fatalf("refresh without csrf: %d %s", res.Code, res.Body)
+	}
+	refresh := jar[config.Cookie.RefreshName].Value
+	if res := jar.do(e, http.MethodPost, "/api/token/refresh", csrf, nil); res.Code != http.StatusOK {
+		t.Fatalf("refresh: %d %s", res.Code, res.Body)
+	}
+	if jar[config.Cookie.RefreshName].Value == refresh {
+		t.Fatal("refresh did not rotate the refresh cookie")
+	}
+	csrf = jar[config.Cookie.CSRFName].Value
+
+	if res := jar.do(e, http.MethodPost, "/api/logout", csrf, nil); res.Code != http.StatusNoContent {
+		t.Fatalf("logout: %d %s", res.Code, res.Body)
+	}
+	if _, ok := jar[config.Cookie.AccessName]; ok {
+		t.Fatal("logout left the access cookie")
+	}
+	if res := jar.do(e, http.MethodGet, "/api/me", "", nil); res.Code != http.StatusUnauthorized {
+		t.Fatalf("GET after logout: %d", res.Code)
+	}
+}
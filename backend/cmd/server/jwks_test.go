@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"planify/backend/internal/config"
+)
+
+// TestJWKSAfterRotation signs sessions with an Ed25519 key while a retiring
+// RSA key is still published, and checks that an HS256 token carrying the
+// same claims is refused once HMAC is no longer in the ring.
+func TestJWKSAfterRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+
+	e := newTestEnv(t, func(c *config.Config) {
+		c.JWT.ActiveKeyID = "2026"
+		c.JWT.Keys = []config.SigningKey{
+			{ID: "2025", Algorithm: "RS256", VerifyUntil: time.Now().Add(time.Hour),
+				PublicKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))},
+			{ID: "2026", Algorithm: "EdDSA",
+				PrivateKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}))},
+		}
+	})
+	e.seed(t, "owner", true)
+
+	res := e.do(http.MethodGet, "/.well-known/jwks.json", "", nil, "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("jwks: %d", res.Code)
+	}
+	var kids []string
+	for _, k := range decode[map[string]any](t, res)["keys"].([]any) {
+		kids = append(kids, k.(map[string]any)["kid"].(string)+"/"+k.(map[string]any)["alg"].(string))
+	}
+	if got := strings.Join(kids, ","); got != "2025/RS256,2026/EdDSA" {
+		t.Fatalf("jwks keys %s", got)
+	}
+
+	if res := e.do(http.MethodGet, "/api/me", "owner", nil, ""); res.Code != http.StatusOK {
+		t.Fatalf("EdDSA session: %d %s", res.Code, res.Body)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(e.tokens["owner"], jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Method.Alg() != "EdDSA" || parsed.Header["kid"] != "2026" {
+		t.Fatalf("session signed with %s/%v", parsed.Method.Alg(), parsed.Header["kid"])
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, parsed.Claims)
+	forged.Header["kid"] = "2026"
+	e.tokens["forged"], err = forged.SignedString(config.JwtKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := e.do(http.MethodGet, "/api/me", "forged", nil, ""); res.Code != http.StatusUnauthorized {
+		t.Fatalf("HS256 token: %d %s", res.Code, res.Body)
+	}
+}
@@ -1,97 +1,139 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"net/http"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	_ "github.com/go-sql-driver/mysql"
-
-	"planify/backend/internal/api/handler"
-	"planify/backend/internal/api/middleware"
+	"planify/backend/internal/auth"
+	"planify/backend/internal/background"
+	"planify/backend/internal/config"
+	"planify/backend/internal/database"
+	"planify/backend/internal/lockout"
+	"planify/backend/internal/mail"
+	"planify/backend/internal/migrate"
+	"planify/backend/internal/ratelimit"
 	"planify/backend/internal/repository"
+	"planify/backend/internal/server"
 )
 
 func main() {
-	dsn := "root:@tcp(127.0.0.1:3306)/planify?parseTime=true"
-	db, err := sql.Open("mysql", dsn)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	configPath := flag.String("config", os.Getenv("PLANIFY_CONFIG"), "path to a YAML or TOML config file")
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-	if err := db.Ping(); err != nil {
-		log.Fatal(err)
+	if cfg.Dev() {
+		log.Println("running in dev mode")
 	}
 
-	projectRepo := &repository.ProjectRepository{DB: db}
-	userRepo := &repository.UserRepository{DB: db}
-	taskRepo := &repository.TaskRepository{DB: db}
-
-	projectHandler := &handler.ProjectHandler{Repo: projectRepo}
-	authHandler := &handler.AuthHandler{UserRepo: userRepo}
-	userHandler := &handler.UserHandler{Repo: userRepo}
-	taskHandler := &handler.TaskHandler{Repo: taskRepo}
-	settingHandler := &handler.SettingsHandler{UserRepo: userRepo}
-
-	r := gin.Default()
-	r.StaticFS("/uploads", http.Dir("uploads"))
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	api := r.Group("/api")
-	{
-		api.POST("/login", authHandler.Login)
-		api.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "UP"}) })
-
-		auth := api.Group("")
-		auth.Use(middleware.AuthMiddleware())
-		{
-			auth.GET("/projects", projectHandler.GetAllProjects)
-			auth.GET("/projects/:id", projectHandler.GetProjectByID)
-			auth.POST("/projects", projectHandler.Create)
-			auth.PATCH("/projects/:id/duedate", projectHandler.UpdateProjectDueDate)
-			auth.POST("/projects/:id/tasks", taskHandler.CreateTask)
-
-			auth.GET("/users/search", userHandler.SearchUsers)
-			auth.GET("/me/tasks", userHandler.GetMyTasks)
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Database.AutoMigrate {
+		m, err := migrate.New(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		n, err := m.Up(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n > 0 {
+			log.Printf("applied %d migration(s)", n)
+		}
+	}
 
-			auth.GET("/tasks/:id", taskHandler.GetTaskByID)
-			auth.PATCH("/tasks/:id/move", taskHandler.UpdateTaskPosition)
-			auth.PATCH("/tasks/:id", taskHandler.UpdateTaskFields)
-			auth.POST("/tasks/:id/assignees", taskHandler.AddAssigneeByQuery)
-			auth.POST("/tasks/:id/collaborators", taskHandler.AddCollaboratorByQuery)
-			auth.GET("/tasks/:id/comments", taskHandler.ListComments)
-			auth.POST("/tasks/:id/comments", taskHandler.AddComment)
-			auth.GET("/tasks/:id/attachments", taskHandler.ListAttachments)
-			auth.POST("/tasks/:id/attachments", taskHandler.UploadAttachment)
+	if err := auth.LoadKeys(config.JWT); err != nil {
+		log.Fatal(err)
+	}
 
-			auth.GET("/me", userHandler.GetMe)
-			auth.PATCH("/me", userHandler.PatchMe)
-			auth.POST("/me/avatar", userHandler.UploadAvatar)
-			auth.PATCH("/me/password", userHandler.ChangePassword)
-			auth.GET("/me/summary", userHandler.GetMySummary)
-			auth.GET("/me/projects", userHandler.GetMyProjects)
+	jobs := background.New()
+	lockoutStore, err := lockout.NewStore(config.Lockout, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	limiter, err := ratelimit.NewBucketStore(config.RateLimit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	stores := repository.NewSQLStores(db)
+	if config.Board.RebalanceInterval > 0 {
+		jobs.Every("rebalance task ranks", config.Board.RebalanceInterval, func(ctx context.Context) error {
+			n, err := stores.Tasks.RebalanceRanks(config.Board.MaxRankLength)
+			if n > 0 {
+				log.Printf("[jobs] rebalanced task ranks in %d column(s)", n)
+			}
+			return err
+		})
+	}
+	r, err := newRouter(cfg, services{
+		Stores:  stores,
+		Mailer:  mail.New(config.Mail),
+		Lockout: lockout.NewGuard(lockoutStore, config.Lockout),
+		Limiter: limiter,
+		Jobs:    jobs,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
-			auth.GET("/users/:id", userHandler.GetUserByID)
-			auth.GET("/users/:id/summary", userHandler.GetUserSummary)
-			auth.GET("/users/:id/projects", userHandler.GetUserProjects)
+	srv, err := server.New(cfg.Server, r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := srv.ReloadCertificate(); err != nil {
+				log.Println("[server] reload TLS certificate:", err)
+			}
+		}
+	}()
 
-			auth.GET("/settings", settingHandler.GetSettings)          
-			auth.PATCH("/settings", settingHandler.UpdateSettings) 
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve() }()
+	scheme := "http"
+	if srv.TLS() {
+		scheme = "https"
+	}
+	fmt.Printf("Backend server is running on %s://%s\n", scheme, cfg.Server.Addr)
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			log.Fatal(err)
 		}
+	case <-ctx.Done():
 	}
 
-	fmt.Println("Backend server is running on http://localhost:8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal(err)
+	log.Println("[server] shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("[server] drain connections:", err)
+	}
+	if err := jobs.Stop(shutdownCtx); err != nil {
+		log.Println("[server] stop background jobs:", err)
+	}
+	if c, ok := limiter.(io.Closer); ok {
+		c.Close()
+	}
+	if err := db.Close(); err != nil {
+		log.Println("[server] close database:", err)
 	}
-}
\ No newline at end of file
+}
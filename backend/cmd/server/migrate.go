@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"planify/backend/internal/config"
+	"planify/backend/internal/database"
+	"planify/backend/internal/migrate"
+)
+
+const migrateUsage = `usage: planify migrate [-config file] <command>
+
+commands:
+  up              apply all pending migrations
+  down [n]        revert the last n migrations (default 1)
+  status          list migrations and whether they are applied
+  baseline [v]    mark migrations up to v (default 1) as applied without
+                  running them, for a database created before migrations
+  create <name>   add an empty up/down pair for every dialect; needs no config
+`
+
+func runMigrate(args []string) {
+	fset := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configPath := fset.String("config", os.Getenv("PLANIFY_CONFIG"), "path to a YAML or TOML config file")
+	dir := fset.String("dir", migrate.SourceDir, "migrations source directory, for create")
+	fset.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	fset.Parse(args)
+	if fset.NArg() == 0 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	cmd := fset.Arg(0)
+	if cmd == "create" {
+		if fset.NArg() != 2 {
+			fset.Usage()
+			os.Exit(2)
+		}
+		paths, err := migrate.Create(*dir, fset.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, p := range paths {
+			fmt.Println("created", p)
+		}
+		return
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	m, err := migrate.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		steps := 1
+		if fset.NArg() > 1 {
+			if steps, err = strconv.Atoi(fset.Arg(1)); err != nil || steps < 1 {
+				log.Fatal("down: steps must be a positive number")
+			}
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("reverted %d migration(s)\n", n)
+	case "baseline":
+		version := int64(1)
+		if fset.NArg() > 1 {
+			if version, err = strconv.ParseInt(fset.Arg(1), 10, 64); err != nil || version < 1 {
+				log.Fatal("baseline: version must be a positive number")
+			}
+		}
+		n, err := m.Baseline(ctx, version)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("marked %d migration(s) as applied\n", n)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fset.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"planify/backend/internal/config"
+)
+
+const (
+	idpClientID = "planify-test"
+	idpSecret   = "idp-secret"
+	idpRedirect = "http://localhost:8080/api/oidc/mock/callback"
+
+	// oidcStateCookie mirrors the handler's cookie name.
+	oidcStateCookie = "planify_oidc"
+)
+
+// mockIdP is an OpenID provider with discovery, a JWKS, an authorization
+// endpoint that approves every request and a token endpoint that enforces
+// PKCE. The ID token carries whatever claims the test put in next.
+type mockIdP struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	next   jwt.MapClaims
+	issued int
+	grants map[string]idpGrant
+}
+
+type idpGrant struct {
+	challenge, nonce, redirect string
+	claims                     jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, grants: map[string]idpGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                idp.srv.URL,
+		"authorization_endpoint":                idp.srv.URL + "/authorize",
+		"token_endpoint":                        idp.srv.URL + "/token",
+		"jwks_uri":                              idp.srv.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	b64 := base64.RawURLEncoding
+	json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+		"kty": "RSA", "use": "sig", "alg": "RS256", "kid": "idp",
+		"n": b64.EncodeToString(idp.key.N.Bytes()),
+		"e": b64.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+	}}})
+}
+
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != idpClientID ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" ||
+		!strings.Contains(" "+q.Get("scope")+" ", " openid ") {
+		http.Error(w, "bad authorization request: "+r.URL.RawQuery, http.StatusBadRequest)
+		return
+	}
+	idp.mu.Lock()
+	idp.issued++
+	code := strconv.Itoa(idp.issued)
+	idp.grants[code] = idpGrant{q.Get("code_challenge"), q.Get("nonce"), q.Get("redirect_uri"), idp.next}
+	idp.mu.Unlock()
+	target, _ := url.Parse(q.Get("redirect_uri"))
+	target.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	r.ParseForm()
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id != idpClientID || secret != idpSecret {
+		fail("invalid_client")
+		return
+	}
+	idp.mu.Lock()
+	g, ok := idp.grants[r.PostForm.Get("code")]
+	delete(idp.grants, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != g.redirect ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": idp.srv.URL, "aud": idpClientID, "nonce": g.nonce,
+		"iat": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+	}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "idp"
+	signed, err := tok.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "idp-access", "token_type": "Bearer", "expires_in": 60, "id_token": signed,
+	})
+}
+
+// approve has the IdP hand out claims and walks the browser through
+// Planify's login redirect and the IdP's authorization endpoint. It returns
+// the callback path Planify will be sent back to.
+func (idp *mockIdP) approve(t *testing.T, e *testEnv, jar cookieJar, claims jwt.MapClaims) string {
+	t.Helper()
+	idp.mu.Lock()
+	idp.next = claims
+	idp.mu.Unlock()
+	res := jar.do(e, http.MethodGet, "/api/oidc/mock/login", "", nil)
+	if res.Code != http.StatusFound || !strings.HasPrefix(res.Header().Get("Location"), idp.srv.URL+"/authorize?") {
+		t.Fatalf("login: %d %s", res.Code, res.Header().Get("Location"))
+	}
+	if c := jar[oidcStateCookie]; c == nil || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Path != "/api/oidc/mock" {
+		t.Fatalf("state cookie %+v", c)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	authz, err := client.Get(res.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authz.Body.Close()
+	if authz.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: %d", authz.StatusCode)
+	}
+	back, _ := url.Parse(authz.Header.Get("Location"))
+	return back.RequestURI()
+}
+
+// callback finishes the flow and returns the parameters handed to the
+// frontend's /#/sso/callback route.
+func callback(t *testing.T, e *testEnv, jar cookieJar, path string) url.Values {
+	t.Helper()
+	res := jar.do(e, http.MethodGet, path, "", nil)
+	loc := res.Header().Get("Location")
+	prefix := config.PublicURL + "/#/sso/callback?"
+	if res.Code != http.StatusFound || !strings.HasPrefix(loc, prefix) {
+		t.Fatalf("callback: %d %q", res.Code, loc)
+	}
+	if _, ok := jar[oidcStateCookie]; ok {
+		t.Fatal("callback kept the state cookie")
+	}
+	params, err := url.ParseQuery(strings.TrimPrefix(loc, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return params
+}
+
+// signedInAs checks that params carry a working session and returns its user.
+func signedInAs(t *testing.T, e *testEnv, params url.Values) map[string]any {
+	t.Helper()
+	if params.Get("error") != "" || params.Get("token") == "" || params.Get("refresh_token") == "" {
+		t.Fatalf("no session in %v", params)
+	}
+	e.tokens["sso"] = params.Get("token")
+	res := e.do(http.MethodGet, "/api/me", "sso", nil, "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("GET /api/me: %d %s", res.Code, res.Body)
+	}
+	return decode[map[string]any](t, res)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	e := newTestEnv(t, func(c *config.Config) {
+		c.OIDC.Providers = []config.OIDCProvider{{
+			ID: "mock", Name: "Mock IdP", Issuer: idp.srv.URL,
+			ClientID: idpClientID, ClientSecret: idpSecret, RedirectURL: idpRedirect,
+		}}
+	})
+	owner := e.seed(t, "owner", true)
+	e.seed(t, "squatter", false)
+
+	if res := e.do(http.MethodGet, "/api/oidc/providers", "", nil, ""); !strings.Contains(res.Body.String(), `"name":"Mock IdP"`) {
+		t.Fatalf("providers: %s", res.Body)
+	}
+
+	t.Run("provisions a new account", func(t *testing.T) {
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "ada", "email": "Ada@Example.com", "email_verified": true, "name": "Ada"})
+		me := signedInAs(t, e, callback(t, e, jar, path))
+		if me["email"] != "ada@example.com" || me["name"] != "Ada" {
+			t.Fatalf("provisioned %v", me)
+		}
+		if ok, _ := e.stores.Users.IsEmailVerified(mustAtoi(id(me["id"]))); !ok {
+			t.Fatal("provisioned account is not verified")
+		}
+		if u, err := e.stores.Users.GetByIdentity("mock", "ada"); err != nil || id(me["id"]) != strconv.Itoa(u.ID) {
+			t.Fatalf("identity not linked: %v", err)
+		}
+	})
+
+	t.Run("links an existing account by email", func(t *testing.T) {
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "owner-sub", "email": "owner@example.com", "email_verified": "true"})
+		me := signedInAs(t, e, callback(t, e, jar, path))
+		if id(me["id"]) != strconv.Itoa(owner) {
+			t.Fatalf("signed in as %v, want the existing owner", me)
+		}
+
+		// Once linked, the subject wins even if the IdP email changes.
+		path = idp.approve(t, e, jar, jwt.MapClaims{"sub": "owner-sub", "email": "renamed@corp.example", "email_verified": true})
+		if me := signedInAs(t, e, callback(t, e, jar, path)); id(me["id"]) != strconv.Itoa(owner) {
+			t.Fatalf("linked subject signed in as %v", me)
+		}
+	})
+
+	t.Run("refuses to link an unverified account", func(t *testing.T) {
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "victim", "email": "squatter@example.com", "email_verified": true})
+		if got := callback(t, e, jar, path).Get("error"); got != "account_unavailable" {
+			t.Fatalf("error %q", got)
+		}
+		if _, err := e.stores.Users.GetByIdentity("mock", "victim"); err == nil {
+			t.Fatal("identity linked to an unverified account")
+		}
+		if ok, _ := e.stores.Users.IsEmailVerified(mustAtoi(e.vars["squatter"])); ok {
+			t.Fatal("unverified account was marked verified")
+		}
+	})
+
+	t.Run("refuses an email without a domain", func(t *testing.T) {
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "local", "email": "root", "email_verified": true})
+		if got := callback(t, e, jar, path).Get("error"); got != "account_unavailable" {
+			t.Fatalf("error %q", got)
+		}
+	})
+
+	t.Run("refuses an unverified email", func(t *testing.T) {
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "mallory", "email": "owner@example.com", "email_verified": false})
+		if got := callback(t, e, jar, path).Get("error"); got != "account_unavailable" {
+			t.Fatalf("error %q", got)
+		}
+	})
+
+	t.Run("refuses to provision when auto-provisioning is off", func(t *testing.T) {
+		config.OIDC.AutoProvision = false
+		defer func() { config.OIDC.AutoProvision = true }()
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "bob", "email": "bob@example.com", "email_verified": true})
+		if got := callback(t, e, jar, path).Get("error"); got != "account_unavailable" {
+			t.Fatalf("error %q", got)
+		}
+	})
+
+	t.Run("checks state", func(t *testing.T) {
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "ada", "email": "ada@example.com", "email_verified": true})
+		u, _ := url.Parse(path)
+		q := u.Query()
+		q.Set("state", q.Get("state")+"x")
+		u.RawQuery = q.Encode()
+		if got := callback(t, e, jar, u.RequestURI()).Get("error"); got != "invalid_state" {
+			t.Fatalf("tampered state: error %q", got)
+		}
+
+		path = idp.approve(t, e, jar, jwt.MapClaims{"sub": "ada", "email": "ada@example.com", "email_verified": true})
+		if got := callback(t, e, cookieJar{}, path).Get("error"); got != "invalid_state" {
+			t.Fatalf("no state cookie: error %q", got)
+		}
+	})
+
+	t.Run("checks nonce", func(t *testing.T) {
+		jar := cookieJar{}
+		path := idp.approve(t, e, jar, jwt.MapClaims{"sub": "ada", "email": "ada@example.com", "email_verified": true, "nonce": "replayed"})
+		if got := callback(t, e, jar, path).Get("error"); got != "login_failed" {
+			t.Fatalf("error %q", got)
+		}
+	})
+
+	t.Run("checks the PKCE verifier", func(t *testing.T) {
+		// The first code was issued for the first login's challenge; the
+		// second login replaced the cookie and with it the verifier.
+		jar := cookieJar{}
+		first, _ := url.Parse(idp.approve(t, e, jar, jwt.MapClaims{"sub": "ada", "email": "ada@example.com", "email_verified": true}))
+		second, _ := url.Parse(idp.approve(t, e, jar, jwt.MapClaims{"sub": "ada", "email": "ada@example.com", "email_verified": true}))
+		q := second.Query()
+		q.Set("code", first.Query().Get("code"))
+		second.RawQuery = q.Encode()
+		if got := callback(t, e, jar, second.RequestURI()).Get("error"); got != "login_failed" {
+			t.Fatalf("error %q", got)
+		}
+	})
+}
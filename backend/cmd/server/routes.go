@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/api/handler"
+	"planify/backend/internal/api/middleware"
+	"planify/backend/internal/auth"
+	"planify/backend/internal/background"
+	"planify/backend/internal/config"
+	"planify/backend/internal/lockout"
+	"planify/backend/internal/mail"
+	"planify/backend/internal/model"
+	"planify/backend/internal/ratelimit"
+	"planify/backend/internal/repository"
+	"planify/backend/internal/sso"
+)
+
+// services is what the routes need from the outside world. main wires the
+// SQL stores and configured backends; the route tests use in-memory ones.
+type services struct {
+	Stores  *repository.Stores
+	Mailer  mail.Mailer
+	Lockout *lockout.Guard
+	Limiter ratelimit.BucketStore
+	Jobs    *background.Group
+}
+
+func newRouter(cfg *config.Config, s services) (*gin.Engine, error) {
+	projectHandler := &handler.ProjectHandler{Repo: s.Stores.Projects}
+	authenticator, err := auth.NewChain(s.Stores.Users, s.Stores.Projects)
+	if err != nil {
+		return nil, err
+	}
+	authHandler := handler.NewAuthHandler(authenticator, s.Stores.Users, s.Stores.Sessions, s.Stores.MFA, s.Mailer, s.Lockout, s.Stores.AuthEvents)
+	ssoProviders, err := sso.NewRegistry(config.OIDC.Providers)
+	if err != nil {
+		return nil, err
+	}
+	ssoHandler := &handler.SSOHandler{Auth: authHandler, Providers: ssoProviders}
+	resetHandler := handler.NewPasswordResetHandler(s.Stores.Users, s.Stores.PasswordResets, s.Stores.Sessions, s.Mailer, s.Lockout, s.Stores.AuthEvents, s.Jobs)
+	userHandler := &handler.UserHandler{Repo: s.Stores.Users, Sessions: s.Stores.Sessions, Lockout: s.Lockout, Events: s.Stores.AuthEvents}
+	taskHandler := &handler.TaskHandler{Repo: s.Stores.Tasks}
+	statusHandler := &handler.StatusHandler{Repo: s.Stores.Statuses}
+	transitionHandler := &handler.TransitionHandler{Repo: s.Stores.Transitions}
+	settingHandler := &handler.SettingsHandler{UserRepo: s.Stores.Users}
+	accessTokenHandler := &handler.AccessTokenHandler{Repo: s.Stores.AccessTokens}
+	adminHandler := &handler.AdminHandler{UserRepo: s.Stores.Users, Events: s.Stores.AuthEvents, Lockout: s.Lockout}
+
+	r := gin.Default()
+	r.StaticFS("/uploads", http.Dir(cfg.Uploads.Dir))
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.Server.CORSOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", config.Cookie.CSRFHeader},
+		ExposeHeaders:    append([]string{"ETag"}, middleware.RateLimitHeaders...),
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	api := r.Group("/api")
+	{
+		api.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "UP"}) })
+
+		public := api.Group("")
+		public.Use(middleware.RateLimit(s.Limiter, "public"))
+		{
+			public.POST("/login", authHandler.Login)
+			public.POST("/login/2fa", authHandler.LoginMFA)
+			public.POST("/register", authHandler.Register)
+			public.POST("/verify-email", authHandler.VerifyEmail)
+			public.POST("/password/forgot", resetHandler.Forgot)
+			public.POST("/password/reset", resetHandler.Reset)
+			public.POST("/token/refresh", authHandler.Refresh)
+			public.GET("/oidc/providers", ssoHandler.ListProviders)
+			public.GET("/oidc/:provider/login", ssoHandler.Login)
+			public.GET("/oidc/:provider/callback", ssoHandler.Callback)
+		}
+
+		auth := api.Group("")
+		auth.Use(middleware.AuthMiddleware(s.Stores.Sessions, s.Stores.AccessTokens), middleware.RateLimit(s.Limiter, "api"))
+		{
+			verified := middleware.RequireVerifiedEmail(s.Stores.Users)
+			sessionOnly := middleware.SessionOnly()
+			projectsRead := middleware.RequireScope(model.ScopeProjectsRead)
+			projectsAdmin := middleware.RequireScope(model.ScopeProjectsAdmin)
+			tasksRead := middleware.RequireScope(model.ScopeTasksRead)
+			tasksWrite := middleware.RequireScope(model.ScopeTasksWrite)
+			profileRead := middleware.RequireScope(model.ScopeProfileRead)
+			profileWrite := middleware.RequireScope(model.ScopeProfileWrite)
+			taskWrites := middleware.RateLimit(s.Limiter, "task-writes")
+			uploads := middleware.RateLimit(s.Limiter, "uploads")
+
+			auth.GET("/projects", projectsRead, projectHandler.GetAllProjects)
+			auth.POST("/projects", projectsAdmin, verified, projectHandler.Create)
+
+			auth.GET("/users/search", profileRead, userHandler.SearchUsers)
+			auth.GET("/me/tasks", tasksRead, userHandler.GetMyTasks)
+
+			manageWorkflow := middleware.RequireCapability(model.CapManageWorkflow)
+
+			project := auth.Group("/projects/:id")
+			project.Use(middleware.ProjectMember(s.Stores.Projects), verified)
+			{
+				project.GET("", projectsRead, projectHandler.GetProjectByID)
+				project.DELETE("", projectsAdmin, middleware.RequireCapability(model.CapDeleteProject), projectHandler.Delete)
+				project.PATCH("/duedate", projectsAdmin, middleware.RequireCapability(model.CapChangeDueDate), projectHandler.UpdateProjectDueDate)
+				project.PATCH("/security", projectsAdmin, middleware.RequireCapability(model.CapManageSettings), projectHandler.UpdateSecurity)
+				project.GET("/statuses", projectsRead, statusHandler.List)
+				project.POST("/statuses", projectsAdmin, manageWorkflow, statusHandler.Create)
+				project.PUT("/statuses/order", projectsAdmin, manageWorkflow, statusHandler.Reorder)
+				project.PATCH("/statuses/:statusId", projectsAdmin, manageWorkflow, statusHandler.Update)
+				project.GET("/transitions", projectsRead, transitionHandler.List)
+				project.POST("/transitions", projectsAdmin, manageWorkflow, transitionHandler.Create)
+				project.DELETE("/transitions/:transitionId", projectsAdmin, manageWorkflow, transitionHandler.Delete)
+				project.POST("/tasks", tasksWrite, taskWrites, middleware.RequireCapability(model.CapEditTasks), taskHandler.CreateTask)
+
+				project.GET("/members", projectsRead, projectHandler.ListMembers)
+				project.POST("/members", projectsAdmin, middleware.RequireCapability(model.CapManageMembers), projectHandler.AddMember)
+				project.PATCH("/members/:userId", projectsAdmin, middleware.RequireCapability(model.CapManageMembers), projectHandler.UpdateMemberRole)
+				project.DELETE("/members/:userId", projectsAdmin, projectHandler.RemoveMember)
+				project.POST("/transfer-ownership", sessionOnly, middleware.RequireCapability(model.CapTransferOwnership), projectHandler.TransferOwnership)
+			}
+
+			edit := middleware.RequireCapability(model.CapEditTasks)
+			task := auth.Group("/tasks/:id")
+			task.Use(middleware.TaskMember(s.Stores.Projects, s.Stores.Tasks), verified)
+			{
+				task.GET("", tasksRead, taskHandler.GetTaskByID)
+				task.PATCH("/move", tasksWrite, taskWrites, edit, taskHandler.UpdateTaskPosition)
+				task.PATCH("", tasksWrite, taskWrites, edit, taskHandler.UpdateTaskFields)
+				task.POST("/assignees", tasksWrite, taskWrites, edit, taskHandler.AddAssigneeByQuery)
+				task.POST("/collaborators", tasksWrite, taskWrites, edit, taskHandler.AddCollaboratorByQuery)
+				task.GET("/comments", tasksRead, taskHandler.ListComments)
+				task.POST("/comments", tasksWrite, taskWrites, middleware.RequireCapability(model.CapComment), taskHandler.AddComment)
+				task.GET("/attachments", tasksRead, taskHandler.ListAttachments)
+				task.POST("/attachments", tasksWrite, uploads, edit, taskHandler.UploadAttachment)
+			}
+
+			auth.GET("/me", profileRead, userHandler.GetMe)
+			auth.PATCH("/me", sessionOnly, userHandler.PatchMe)
+			auth.POST("/me/avatar", profileWrite, uploads, userHandler.UploadAvatar)
+			auth.GET("/me/summary", profileRead, userHandler.GetMySummary)
+			auth.GET("/me/projects", projectsRead, userHandler.GetMyProjects)
+
+			account := auth.Group("")
+			account.Use(sessionOnly)
+			{
+				account.PATCH("/me/password", userHandler.ChangePassword)
+				account.POST("/me/verification", authHandler.ResendVerification)
+				account.POST("/logout", authHandler.Logout)
+				account.GET("/me/sessions", authHandler.ListSessions)
+				account.DELETE("/me/sessions/:id", authHandler.RevokeSession)
+				account.GET("/me/2fa", authHandler.GetMFAStatus)
+				account.POST("/me/2fa/enroll", authHandler.EnrollMFA)
+				account.POST("/me/2fa/enable", authHandler.EnableMFA)
+				account.POST("/me/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+				account.POST("/me/2fa/disable", authHandler.DisableMFA)
+				account.GET("/me/tokens", accessTokenHandler.List)
+				account.POST("/me/tokens", accessTokenHandler.Create)
+				account.DELETE("/me/tokens/:id", accessTokenHandler.Revoke)
+			}
+
+			auth.GET("/users/:id", profileRead, userHandler.GetUserByID)
+			auth.GET("/users/:id/summary", profileRead, userHandler.GetUserSummary)
+			auth.GET("/users/:id/projects", profileRead, userHandler.GetUserProjects)
+
+			auth.GET("/settings", profileRead, settingHandler.GetSettings)
+			auth.PATCH("/settings", profileWrite, settingHandler.UpdateSettings)
+
+			admin := auth.Group("/admin")
+			admin.Use(sessionOnly, middleware.RequireAdmin(s.Stores.Users))
+			{
+				admin.POST("/unlock", adminHandler.Unlock)
+				admin.GET("/auth-events", adminHandler.ListAuthEvents)
+			}
+		}
+	}
+	return r, nil
+}
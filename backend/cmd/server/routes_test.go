@@ -0,0 +1,948 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/background"
+	"planify/backend/internal/config"
+	"planify/backend/internal/lockout"
+	"planify/backend/internal/mail"
+	"planify/backend/internal/model"
+	"planify/backend/internal/ratelimit"
+	"planify/backend/internal/repository"
+)
+
+const (
+	testPassword  = "Correct-Horse-42"
+	resetPassword = "Battery-Staple-7"
+)
+
+// outbox records outgoing mail so tests can follow the links in it.
+type outbox struct {
+	sent chan mail.Message
+}
+
+func (o *outbox) Send(_ context.Context, msg mail.Message) error {
+	o.sent <- msg
+	return nil
+}
+
+var linkToken = regexp.MustCompile(`token=(\S+)`)
+
+// token waits for the next message and returns the token in its link.
+func (o *outbox) token(t *testing.T) string {
+	t.Helper()
+	select {
+	case msg := <-o.sent:
+		m := linkToken.FindStringSubmatch(msg.Text)
+		if m == nil {
+			t.Fatalf("no link in mail %q", msg.Text)
+		}
+		tok, err := url.QueryUnescape(m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail sent")
+		return ""
+	}
+}
+
+type testEnv struct {
+	router  *gin.Engine
+	stores  *repository.Stores
+	mem     *repository.Memory
+	outbox  *outbox
+	lockout lockout.Store
+	// vars fills {name} placeholders in paths; tokens holds each actor's
+	// bearer token.
+	vars   map[string]string
+	tokens map[string]string
+}
+
+// newTestEnv builds the router on in-memory stores. configure may adjust the
+// test configuration before it is applied.
+func newTestEnv(t *testing.T, configure ...func(*config.Config)) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	cfg := config.Defaults()
+	cfg.Uploads.Dir = t.TempDir()
+	cfg.RateLimit.Enabled = false
+	cfg.PasswordHash.Memory = 8 * 1024
+	cfg.PasswordHash.Iterations = 1
+	for _, f := range configure {
+		f(cfg)
+	}
+	// Defaults reads the live settings, so take the snapshot before Apply.
+	restore := config.Defaults()
+	config.Apply(cfg)
+	t.Cleanup(func() { config.Apply(restore) })
+	if err := auth.LoadKeys(config.JWT); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.Uploads.Dir, "default-avatar.jpg"), []byte("jpeg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stores, mem := repository.NewMemoryStores()
+	lockoutStore, err := lockout.NewStore(config.Lockout, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := ratelimit.NewBucketStore(config.RateLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := background.New()
+	t.Cleanup(func() { jobs.Stop(context.Background()) })
+	box := &outbox{sent: make(chan mail.Message, 16)}
+	r, err := newRouter(cfg, services{
+		Stores:  stores,
+		Mailer:  box,
+		Lockout: lockout.NewGuard(lockoutStore, config.Lockout),
+		Limiter: limiter,
+		Jobs:    jobs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testEnv{router: r, stores: stores, mem: mem, outbox: box, lockout: lockoutStore, vars: map[string]string{}, tokens: map[string]string{}}
+}
+
+// upload is a multipart body with a single "file" field.
+type upload struct {
+	name, data string
+}
+
+func (e *testEnv) expand(s string) string {
+	for k, v := range e.vars {
+		s = strings.ReplaceAll(s, "{"+k+"}", v)
+	}
+	return s
+}
+
+func (e *testEnv) do(method, path, as string, body any, ifMatch string) *httptest.ResponseRecorder {
+	if f, ok := body.(func(*testEnv) any); ok {
+		body = f(e)
+	}
+	var rd io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case upload:
+		buf := &bytes.Buffer{}
+		w := multipart.NewWriter(buf)
+		if b.name != "" {
+			fw, _ := w.CreateFormFile("file", b.name)
+			fw.Write([]byte(b.data))
+		}
+		w.Close()
+		rd, contentType = buf, w.FormDataContentType()
+	default:
+		raw, _ := json.Marshal(b)
+		rd, contentType = bytes.NewReader(raw), "application/json"
+	}
+	req := httptest.NewRequest(method, e.expand(path), rd)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if as != "" {
+		req.Header.Set("Authorization", "Bearer "+e.tokens[as])
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", e.expand(ifMatch))
+	}
+	res := httptest.NewRecorder()
+	e.router.ServeHTTP(res, req)
+	return res
+}
+
+// login signs name in and keeps the access token under as.
+func (e *testEnv) login(t *testing.T, as, name, password string) map[string]any {
+	t.Helper()
+	res := e.do(http.MethodPost, "/api/login", "", gin.H{"email": name + "@example.com", "password": password}, "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("login %s: %d %s", name, res.Code, res.Body)
+	}
+	body := decode[map[string]any](t, res)
+	if tok, ok := body["token"].(string); ok {
+		e.tokens[as] = tok
+	}
+	return body
+}
+
+// seed creates a user who can sign in with testPassword.
+func (e *testEnv) seed(t *testing.T, name string, verified bool) int {
+	t.Helper()
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := e.stores.Users.Create(strings.ToUpper(name[:1])+name[1:], name+"@example.com", hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified {
+		e.stores.Users.MarkEmailVerified(u.ID)
+	}
+	e.vars[name] = strconv.Itoa(u.ID)
+	e.login(t, name, name, testPassword)
+	return u.ID
+}
+
+func decode[T any](t *testing.T, res *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(res.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", res.Body, err)
+	}
+	return v
+}
+
+func id(v any) string {
+	return strconv.Itoa(int(v.(float64)))
+}
+
+// totpCode computes the current RFC 6238 code for a base32 secret.
+func totpCode(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[off:off+4])&0x7fffffff)%1000000)
+}
+
+type routeCase struct {
+	route   string // "METHOD /pattern" as registered with gin
+	name    string
+	path    string // request path; {name} placeholders come from testEnv.vars
+	as      string
+	body    any
+	ifMatch string // If-Match header, expanded like path
+	want    int
+	check   func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder)
+}
+
+// withStatus fills in statusId from the named path variable.
+func withStatus(name string, body gin.H) func(*testEnv) any {
+	return func(e *testEnv) any {
+		out := gin.H{"statusId": mustAtoi(e.vars[name])}
+		for k, v := range body {
+			out[k] = v
+		}
+		return out
+	}
+}
+
+// wantConflict checks a 412 body carries the current state and its ETag.
+func wantConflict(field string, value any) func(*testing.T, *testEnv, *httptest.ResponseRecorder) {
+	return func(t *testing.T, _ *testEnv, res *httptest.ResponseRecorder) {
+		body := decode[map[string]any](t, res)
+		current, _ := body["current"].(map[string]any)
+		if body["code"] != "version_conflict" || current[field] != value {
+			t.Fatalf("unexpected conflict body %s", res.Body)
+		}
+		if strings.TrimPrefix(res.Header().Get("ETag"), "W/") != fmt.Sprintf("%q", fmt.Sprint(current["version"])) {
+			t.Fatalf("ETag %q does not match version %v", res.Header().Get("ETag"), current["version"])
+		}
+	}
+}
+
+func wantLen(n int) func(*testing.T, *testEnv, *httptest.ResponseRecorder) {
+	return func(t *testing.T, _ *testEnv, res *httptest.ResponseRecorder) {
+		if got := decode[[]any](t, res); len(got) != n {
+			t.Fatalf("got %d items, want %d: %s", len(got), n, res.Body)
+		}
+	}
+}
+
+// TestRoutes drives every route through the real router, middleware and
+// handlers with the in-memory stores. Cases run in order and later ones rely
+// on state left by earlier ones.
+func TestRoutes(t *testing.T) {
+	e := newTestEnv(t)
+	for _, name := range []string{"owner", "admin", "member", "viewer", "outsider", "forgetful", "mfa", "root"} {
+		e.seed(t, name, true)
+	}
+	e.seed(t, "unverified", false)
+	if err := e.mem.SetAdmin(mustAtoi(e.vars["root"]), true); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := mustAtoi(e.vars["owner"])
+	p, err := e.stores.Projects.Create(repository.CreateProjectPayload{Name: "Apollo", OwnerID: owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, role := range map[string]model.Role{
+		"admin": model.RoleAdmin, "member": model.RoleMember, "viewer": model.RoleViewer, "unverified": model.RoleMember,
+	} {
+		if err := e.stores.Projects.AddMember(p.ID, mustAtoi(e.vars[name]), role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	statuses, err := e.stores.Statuses.List(p.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"todo", "doing", "done"} {
+		e.vars[name] = strconv.Itoa(statuses[i].ID)
+	}
+	taskID, _, _, err := e.stores.Tasks.CreateTask(p.ID, statuses[0].ID, "Design", model.RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.vars["project"] = strconv.Itoa(p.ID)
+	e.vars["task"] = strconv.Itoa(taskID)
+
+	cases := []routeCase{
+		{route: "GET /api/health", path: "/api/health", want: 200},
+		{route: "GET /.well-known/jwks.json", path: "/.well-known/jwks.json", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if _, ok := decode[map[string]any](t, res)["keys"].([]any); !ok {
+					t.Fatalf("no key set: %s", res.Body)
+				}
+			}},
+		{route: "GET /uploads/*filepath", path: "/uploads/default-avatar.jpg", want: 200},
+		{route: "HEAD /uploads/*filepath", path: "/uploads/default-avatar.jpg", want: 200},
+
+		{route: "POST /api/register", path: "/api/register", want: 201,
+			body: gin.H{"name": "Newcomer", "email": "Newcomer@Example.com", "password": testPassword},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[map[string]any](t, res)
+				if body["email"] != "newcomer@example.com" || body["emailVerified"] != false {
+					t.Fatalf("unexpected body %v", body)
+				}
+				e.vars["newcomer"] = id(body["id"])
+				e.vars["verify_token"] = e.outbox.token(t)
+			}},
+		{route: "POST /api/register", name: "taken", path: "/api/register", want: 409,
+			body: gin.H{"name": "Again", "email": "newcomer@example.com", "password": testPassword}},
+		{route: "POST /api/register", name: "weak password", path: "/api/register", want: 400,
+			body: gin.H{"name": "Weak", "email": "weak@example.com", "password": "short"}},
+		{route: "POST /api/verify-email", name: "bad token", path: "/api/verify-email", want: 400,
+			body: gin.H{"token": "nope"}},
+		{route: "POST /api/verify-email", path: "/api/verify-email", want: 200,
+			body: func(e *testEnv) any { return gin.H{"token": e.vars["verify_token"]} }},
+		{route: "POST /api/login", name: "wrong password", path: "/api/login", want: 401,
+			body: gin.H{"email": "member@example.com", "password": "Wrong-Horse-42"}},
+		{route: "POST /api/login", path: "/api/login", want: 200,
+			body: gin.H{"email": "NEWCOMER@example.com", "password": testPassword},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[map[string]any](t, res)
+				if body["email_verified"] != true {
+					t.Fatalf("newcomer not verified: %v", body)
+				}
+				e.tokens["newcomer"] = body["token"].(string)
+				e.vars["refresh"] = body["refresh_token"].(string)
+			}},
+		{route: "POST /api/token/refresh", path: "/api/token/refresh", want: 200,
+			body: func(e *testEnv) any { return gin.H{"refresh_token": e.vars["refresh"]} },
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if decode[map[string]any](t, res)["refresh_token"] == e.vars["refresh"] {
+					t.Fatal("refresh token was not rotated")
+				}
+			}},
+		{route: "POST /api/token/refresh", name: "reuse revokes session", path: "/api/token/refresh", want: 401,
+			body: func(e *testEnv) any { return gin.H{"refresh_token": e.vars["refresh"]} }},
+		{route: "GET /api/me", name: "revoked session", path: "/api/me", as: "newcomer", want: 401},
+
+		{route: "POST /api/password/forgot", path: "/api/password/forgot", want: 202,
+			body: gin.H{"email": "forgetful@example.com"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				e.vars["reset_token"] = e.outbox.token(t)
+			}},
+		{route: "POST /api/password/reset", name: "bad token", path: "/api/password/reset", want: 400,
+			body: gin.H{"token": "nope", "password": resetPassword}},
+		{route: "POST /api/password/reset", path: "/api/password/reset", want: 200,
+			body: func(e *testEnv) any { return gin.H{"token": e.vars["reset_token"], "password": resetPassword} }},
+		{route: "GET /api/me", name: "sessions revoked by reset", path: "/api/me", as: "forgetful", want: 401},
+		{route: "POST /api/login", name: "after reset", path: "/api/login", want: 200,
+			body: gin.H{"email": "forgetful@example.com", "password": resetPassword},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				e.tokens["forgetful"] = decode[map[string]any](t, res)["token"].(string)
+			}},
+		{route: "POST /api/login/2fa", name: "bad token", path: "/api/login/2fa", want: 401,
+			body: gin.H{"mfa_token": "nope", "code": "123456"}},
+
+		{route: "GET /api/oidc/providers", path: "/api/oidc/providers", want: 200, check: wantLen(0)},
+		{route: "GET /api/oidc/:provider/login", path: "/api/oidc/nowhere/login", want: 404},
+		{route: "GET /api/oidc/:provider/callback", path: "/api/oidc/nowhere/callback", want: 404},
+
+		{route: "GET /api/projects", name: "anonymous", path: "/api/projects", want: 401},
+		{route: "GET /api/projects", path: "/api/projects", as: "viewer", want: 200, check: wantLen(1)},
+		{route: "GET /api/projects", name: "no projects", path: "/api/projects", as: "outsider", want: 200},
+		{route: "POST /api/projects", name: "unverified", path: "/api/projects", as: "unverified", want: 403,
+			body: gin.H{"name": "Nope"}},
+		{route: "POST /api/projects", name: "missing name", path: "/api/projects", as: "owner", want: 400,
+			body: gin.H{"description": "no name"}},
+		{route: "POST /api/projects", name: "unknown team member", path: "/api/projects", as: "owner", want: 400,
+			body: gin.H{"name": "Ghosts", "teamIds": []int{999999}},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if got := decode[map[string]any](t, res)["error"]; got != "Unknown team member" {
+					t.Fatalf("error %v", got)
+				}
+			}},
+		{route: "POST /api/projects", path: "/api/projects", as: "owner", want: 201,
+			body: func(e *testEnv) any {
+				return gin.H{"name": "Gemini", "teamIds": []int{mustAtoi(e.vars["member"])}}
+			},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				e.vars["gemini"] = id(decode[map[string]any](t, res)["id"])
+				statuses, err := e.stores.Statuses.List(mustAtoi(e.vars["gemini"]), false)
+				if err != nil || len(statuses) != 3 {
+					t.Fatalf("gemini statuses: %v %+v", err, statuses)
+				}
+				e.vars["gemini_todo"] = strconv.Itoa(statuses[0].ID)
+			}},
+		{route: "GET /api/users/search", path: "/api/users/search?q=MEM", as: "owner", want: 200, check: wantLen(1)},
+		{route: "GET /api/users/search", name: "empty query", path: "/api/users/search", as: "owner", want: 400},
+
+		{route: "GET /api/projects/:id", path: "/api/projects/{project}", as: "viewer", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[map[string]any](t, res)
+				columns, _ := body["columns"].([]any)
+				team, _ := body["team"].([]any)
+				if len(columns) != 3 || len(team) != 5 {
+					t.Fatalf("got %d columns and %d members: %s", len(columns), len(team), res.Body)
+				}
+			}},
+		{route: "GET /api/projects/:id", name: "non-member", path: "/api/projects/{project}", as: "outsider", want: 404},
+		{route: "GET /api/projects/:id", name: "bad id", path: "/api/projects/abc", as: "owner", want: 400},
+		{route: "PATCH /api/projects/:id/duedate", name: "member", path: "/api/projects/{project}/duedate", as: "member", want: 403,
+			body: gin.H{"dueDate": "2031-01-01"}},
+		{route: "PATCH /api/projects/:id/duedate", path: "/api/projects/{project}/duedate", as: "admin", want: 200,
+			body: gin.H{"dueDate": "2031-01-01"}, ifMatch: `"1"`},
+		{route: "PATCH /api/projects/:id/duedate", name: "stale if-match", path: "/api/projects/{project}/duedate", as: "admin", want: 412,
+			body: gin.H{"dueDate": "2031-06-06"}, ifMatch: `"1"`, check: wantConflict("due_date", "2031-01-01")},
+		{route: "GET /api/projects/:id", name: "etag", path: "/api/projects/{project}", as: "viewer", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if etag := res.Header().Get("ETag"); etag != `W/"2"` {
+					t.Fatalf("ETag = %q", etag)
+				}
+			}},
+		{route: "PATCH /api/projects/:id/security", name: "without own 2fa", path: "/api/projects/{project}/security", as: "admin", want: 400,
+			body: gin.H{"require2fa": true}},
+		{route: "PATCH /api/projects/:id/security", name: "weak if-match", path: "/api/projects/{project}/security", as: "admin", want: 400,
+			body: gin.H{"require2fa": false}, ifMatch: `W/"2"`},
+		{route: "PATCH /api/projects/:id/security", path: "/api/projects/{project}/security", as: "admin", want: 200,
+			body: gin.H{"require2fa": false}, ifMatch: `"2"`},
+		{route: "POST /api/projects/:id/tasks", name: "viewer", path: "/api/projects/{project}/tasks", as: "viewer", want: 403,
+			body: withStatus("todo", gin.H{"title": "Nope"})},
+		{route: "POST /api/projects/:id/tasks", name: "unverified", path: "/api/projects/{project}/tasks", as: "unverified", want: 403,
+			body: withStatus("todo", gin.H{"title": "Nope"})},
+		{route: "POST /api/projects/:id/tasks", path: "/api/projects/{project}/tasks", as: "member", want: 201,
+			body: withStatus("todo", gin.H{"title": "Build"}),
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[map[string]any](t, res)
+				if body["position"] != 1.0 {
+					t.Fatalf("position = %v, want 1", body["position"])
+				}
+				e.vars["build"] = id(body["id"])
+			}},
+		{route: "POST /api/projects/:id/tasks", name: "another project's status", path: "/api/projects/{project}/tasks", as: "member", want: 400,
+			body: withStatus("gemini_todo", gin.H{"title": "Misfiled"})},
+		{route: "GET /api/projects/:id/statuses", path: "/api/projects/{project}/statuses", as: "viewer", want: 200, check: wantLen(3)},
+		{route: "POST /api/projects/:id/statuses", name: "member", path: "/api/projects/{project}/statuses", as: "member", want: 403,
+			body: gin.H{"title": "Review"}},
+		{route: "POST /api/projects/:id/statuses", name: "bad category", path: "/api/projects/{project}/statuses", as: "admin", want: 400,
+			body: gin.H{"title": "Review", "category": "blocked"}},
+		{route: "POST /api/projects/:id/statuses", path: "/api/projects/{project}/statuses", as: "admin", want: 201,
+			body: gin.H{"title": "Review", "category": "in_progress", "color": "#ff8800"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				st := decode[model.Status](t, res)
+				if st.Position != 3 || st.Category != model.CategoryInProgress || st.Color == nil {
+					t.Fatalf("unexpected status %+v", st)
+				}
+				e.vars["review"] = strconv.Itoa(st.ID)
+			}},
+		{route: "PATCH /api/projects/:id/statuses/:statusId", path: "/api/projects/{project}/statuses/{review}", as: "admin", want: 200,
+			body: gin.H{"title": "QA", "color": ""},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if st := decode[model.Status](t, res); st.Title != "QA" || st.Color != nil {
+					t.Fatalf("unexpected status %+v", st)
+				}
+			}},
+		{route: "PATCH /api/projects/:id/statuses/:statusId", name: "another project's status", path: "/api/projects/{project}/statuses/{gemini_todo}", as: "admin", want: 404,
+			body: gin.H{"title": "Stolen"}},
+		{route: "PATCH /api/projects/:id/statuses/:statusId", name: "archive with tasks", path: "/api/projects/{project}/statuses/{todo}", as: "admin", want: 409,
+			body: gin.H{"archived": true}},
+		{route: "PUT /api/projects/:id/statuses/order", name: "incomplete", path: "/api/projects/{project}/statuses/order", as: "admin", want: 400,
+			body: func(e *testEnv) any { return gin.H{"statusIds": []int{mustAtoi(e.vars["review"])}} }},
+		{route: "PUT /api/projects/:id/statuses/order", path: "/api/projects/{project}/statuses/order", as: "admin", want: 200,
+			body: func(e *testEnv) any {
+				var ids []int
+				for _, name := range []string{"review", "todo", "doing", "done"} {
+					ids = append(ids, mustAtoi(e.vars[name]))
+				}
+				return gin.H{"statusIds": ids}
+			},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if list := decode[[]model.Status](t, res); strconv.Itoa(list[0].ID) != e.vars["review"] {
+					t.Fatalf("unexpected order %+v", list)
+				}
+			}},
+		{route: "PATCH /api/projects/:id/statuses/:statusId", name: "archive", path: "/api/projects/{project}/statuses/{review}", as: "admin", want: 200,
+			body: gin.H{"archived": true}},
+		{route: "GET /api/projects/:id/statuses", name: "with archived", path: "/api/projects/{project}/statuses?archived=true", as: "viewer", want: 200, check: wantLen(4)},
+		{route: "POST /api/projects/:id/tasks", name: "archived status", path: "/api/projects/{project}/tasks", as: "member", want: 400,
+			body: withStatus("review", gin.H{"title": "Nope"})},
+		{route: "POST /api/projects/:id/transitions", name: "member", path: "/api/projects/{project}/transitions", as: "member", want: 403,
+			body: func(e *testEnv) any { return gin.H{"toStatusId": mustAtoi(e.vars["doing"])} }},
+		{route: "POST /api/projects/:id/transitions", name: "unknown field", path: "/api/projects/{project}/transitions", as: "admin", want: 400,
+			body: func(e *testEnv) any {
+				return gin.H{"toStatusId": mustAtoi(e.vars["doing"]), "requiredFields": []string{"estimate"}}
+			}},
+		{route: "POST /api/projects/:id/transitions", name: "another project's status", path: "/api/projects/{project}/transitions", as: "admin", want: 400,
+			body: func(e *testEnv) any { return gin.H{"toStatusId": mustAtoi(e.vars["gemini_todo"])} }},
+		{route: "POST /api/projects/:id/transitions", path: "/api/projects/{project}/transitions", as: "admin", want: 201,
+			body: func(e *testEnv) any { return gin.H{"toStatusId": mustAtoi(e.vars["doing"])} },
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if tr := decode[model.Transition](t, res); tr.FromStatusID != nil || tr.Roles == nil {
+					t.Fatalf("unexpected transition %s", res.Body)
+				}
+			}},
+		{route: "POST /api/projects/:id/transitions", name: "duplicate", path: "/api/projects/{project}/transitions", as: "admin", want: 409,
+			body: func(e *testEnv) any { return gin.H{"toStatusId": mustAtoi(e.vars["doing"])} }},
+		{route: "POST /api/projects/:id/transitions", name: "guarded", path: "/api/projects/{project}/transitions", as: "owner", want: 201,
+			body: func(e *testEnv) any {
+				return gin.H{
+					"fromStatusId":   mustAtoi(e.vars["doing"]),
+					"toStatusId":     mustAtoi(e.vars["done"]),
+					"roles":          []string{"owner", "admin"},
+					"requiredFields": []string{"assignee", "comment"},
+				}
+			}},
+		{route: "POST /api/projects/:id/transitions", name: "to delete", path: "/api/projects/{project}/transitions", as: "admin", want: 201,
+			body: func(e *testEnv) any { return gin.H{"toStatusId": mustAtoi(e.vars["todo"])} },
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				e.vars["rule"] = strconv.Itoa(decode[model.Transition](t, res).ID)
+			}},
+		{route: "DELETE /api/projects/:id/transitions/:transitionId", path: "/api/projects/{project}/transitions/{rule}", as: "admin", want: 204},
+		{route: "DELETE /api/projects/:id/transitions/:transitionId", name: "gone", path: "/api/projects/{project}/transitions/{rule}", as: "admin", want: 404},
+		{route: "GET /api/projects/:id/transitions", path: "/api/projects/{project}/transitions", as: "viewer", want: 200, check: wantLen(2)},
+		{route: "POST /api/projects/:id/tasks", name: "guarded status", path: "/api/projects/{project}/tasks", as: "admin", want: 422,
+			body: withStatus("done", gin.H{"title": "Born done"}),
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[struct {
+					Code  string                 `json:"code"`
+					Unmet []model.UnmetCondition `json:"unmet"`
+				}](t, res)
+				if body.Code != "transition_blocked" || len(body.Unmet) != 1 || body.Unmet[0].Condition != "transition" {
+					t.Fatalf("unexpected unmet conditions %s", res.Body)
+				}
+			}},
+		{route: "GET /api/projects/:id/members", path: "/api/projects/{project}/members", as: "viewer", want: 200, check: wantLen(5)},
+		{route: "POST /api/projects/:id/members", name: "member", path: "/api/projects/{project}/members", as: "member", want: 403,
+			body: func(e *testEnv) any { return gin.H{"userId": mustAtoi(e.vars["newcomer"])} }},
+		{route: "POST /api/projects/:id/members", name: "admin grants admin", path: "/api/projects/{project}/members", as: "admin", want: 403,
+			body: func(e *testEnv) any { return gin.H{"userId": mustAtoi(e.vars["newcomer"]), "role": "admin"} }},
+		{route: "POST /api/projects/:id/members", path: "/api/projects/{project}/members", as: "admin", want: 200, check: wantLen(6),
+			body: func(e *testEnv) any { return gin.H{"userId": mustAtoi(e.vars["newcomer"]), "role": "viewer"} }},
+		{route: "POST /api/projects/:id/members", name: "twice", path: "/api/projects/{project}/members", as: "admin", want: 409,
+			body: func(e *testEnv) any { return gin.H{"userId": mustAtoi(e.vars["newcomer"])} }},
+		{route: "POST /api/projects/:id/members", name: "unknown user", path: "/api/projects/{project}/members", as: "admin", want: 404,
+			body: gin.H{"userId": 999999}},
+		{route: "PATCH /api/projects/:id/members/:userId", path: "/api/projects/{project}/members/{newcomer}", as: "admin", want: 200,
+			body: gin.H{"role": "member"}},
+		{route: "PATCH /api/projects/:id/members/:userId", name: "no change", path: "/api/projects/{project}/members/{newcomer}", as: "admin", want: 200,
+			body: gin.H{"role": "member"}},
+		{route: "PATCH /api/projects/:id/members/:userId", name: "owner", path: "/api/projects/{project}/members/{owner}", as: "admin", want: 403,
+			body: gin.H{"role": "member"}},
+		{route: "PATCH /api/projects/:id/members/:userId", name: "not a member", path: "/api/projects/{project}/members/{outsider}", as: "admin", want: 404,
+			body: gin.H{"role": "member"}},
+		{route: "DELETE /api/projects/:id/members/:userId", path: "/api/projects/{project}/members/{newcomer}", as: "admin", want: 204},
+		{route: "DELETE /api/projects/:id/members/:userId", name: "gone", path: "/api/projects/{project}/members/{newcomer}", as: "admin", want: 404},
+		{route: "DELETE /api/projects/:id/members/:userId", name: "owner leaves", path: "/api/projects/{project}/members/{owner}", as: "owner", want: 403},
+		{route: "POST /api/projects/:id/transfer-ownership", name: "non-member", path: "/api/projects/{gemini}/transfer-ownership", as: "owner", want: 400,
+			body: func(e *testEnv) any { return gin.H{"userId": mustAtoi(e.vars["outsider"])} }},
+		{route: "POST /api/projects/:id/transfer-ownership", path: "/api/projects/{gemini}/transfer-ownership", as: "owner", want: 200,
+			body: func(e *testEnv) any { return gin.H{"userId": mustAtoi(e.vars["member"])} },
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				for _, m := range decode[[]model.ProjectMember](t, res) {
+					if strconv.Itoa(m.ID) == e.vars["member"] && m.Role != model.RoleOwner {
+						t.Fatalf("new owner has role %s", m.Role)
+					}
+				}
+			}},
+		{route: "DELETE /api/projects/:id", name: "former owner", path: "/api/projects/{gemini}", as: "owner", want: 403},
+		{route: "DELETE /api/projects/:id", name: "stale if-match", path: "/api/projects/{gemini}", as: "member", want: 412,
+			ifMatch: `"7"`, check: wantConflict("name", "Gemini")},
+		{route: "DELETE /api/projects/:id", path: "/api/projects/{gemini}", as: "member", want: 204, ifMatch: `"1"`},
+		{route: "GET /api/projects/:id", name: "deleted", path: "/api/projects/{gemini}", as: "member", want: 404},
+
+		{route: "GET /api/tasks/:id", path: "/api/tasks/{task}", as: "viewer", want: 200},
+		{route: "GET /api/tasks/:id", name: "non-member", path: "/api/tasks/{task}", as: "outsider", want: 404},
+		{route: "GET /api/tasks/:id", name: "missing", path: "/api/tasks/999999", as: "owner", want: 404},
+		{route: "PATCH /api/tasks/:id/move", name: "viewer", path: "/api/tasks/{task}/move", as: "viewer", want: 403,
+			body: withStatus("doing", gin.H{"position": 0})},
+		{route: "PATCH /api/tasks/:id/move", name: "another project's status", path: "/api/tasks/{task}/move", as: "member", want: 400,
+			body: withStatus("gemini_todo", gin.H{"position": 0})},
+		{route: "PATCH /api/tasks/:id/move", name: "archived status", path: "/api/tasks/{task}/move", as: "member", want: 400,
+			body: withStatus("review", gin.H{"position": 0})},
+		{route: "PATCH /api/tasks/:id/move", path: "/api/tasks/{task}/move", as: "member", want: 200,
+			body: withStatus("doing", gin.H{"position": 0}), ifMatch: `"1"`},
+		{route: "PATCH /api/tasks/:id/move", name: "stale if-match", path: "/api/tasks/{task}/move", as: "member", want: 412,
+			body: withStatus("todo", gin.H{"position": 0}), ifMatch: `"1"`, check: wantConflict("statusName", "In Progress")},
+		{route: "PATCH /api/projects/:id/statuses/:statusId", name: "negative wip limit", path: "/api/projects/{project}/statuses/{doing}", as: "admin", want: 400,
+			body: gin.H{"wipLimit": -1}},
+		{route: "PATCH /api/projects/:id/statuses/:statusId", name: "hard wip limit", path: "/api/projects/{project}/statuses/{doing}", as: "admin", want: 200,
+			body: gin.H{"wipLimit": 1, "wipMode": "hard"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if st := decode[model.Status](t, res); st.WIPLimit == nil || *st.WIPLimit != 1 || st.WIPMode != model.WIPHard {
+					t.Fatalf("unexpected status %+v", st)
+				}
+			}},
+		{route: "PATCH /api/tasks/:id/move", name: "hard wip limit", path: "/api/tasks/{build}/move", as: "member", want: 409,
+			body: withStatus("doing", gin.H{"position": 1}),
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[struct {
+					Code       string               `json:"code"`
+					Violations []model.WIPViolation `json:"violations"`
+				}](t, res)
+				if body.Code != "wip_limit_exceeded" || len(body.Violations) != 1 || body.Violations[0].Count != 2 {
+					t.Fatalf("unexpected violations %s", res.Body)
+				}
+			}},
+		{route: "POST /api/projects/:id/tasks", name: "hard wip limit", path: "/api/projects/{project}/tasks", as: "member", want: 409,
+			body: withStatus("doing", gin.H{"title": "Squeezed"})},
+		{route: "PATCH /api/projects/:id/statuses/:statusId", name: "soft wip limit", path: "/api/projects/{project}/statuses/{doing}", as: "admin", want: 200,
+			body: gin.H{"wipMode": "soft"}},
+		{route: "PATCH /api/tasks/:id/move", name: "soft wip limit", path: "/api/tasks/{build}/move", as: "member", want: 200,
+			body: withStatus("doing", gin.H{"position": 1}),
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if w := decode[map[string]any](t, res)["warnings"].([]any); len(w) != 1 {
+					t.Fatalf("unexpected warnings %s", res.Body)
+				}
+			}},
+		{route: "GET /api/projects/:id", name: "wip counts", path: "/api/projects/{project}", as: "viewer", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				columns := decode[map[string]any](t, res)["columns"].([]any)
+				doing := columns[1].(map[string]any)
+				if doing["taskCount"] != 2.0 || doing["wipLimit"] != 1.0 || len(doing["wipViolations"].([]any)) != 1 {
+					t.Fatalf("unexpected column %+v", doing)
+				}
+			}},
+		{route: "POST /api/projects/:id/tasks", name: "soft wip limit", path: "/api/projects/{project}/tasks", as: "member", want: 201,
+			body: withStatus("doing", gin.H{"title": "Squeezed"}),
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				w, _ := decode[map[string]any](t, res)["warnings"].([]any)
+				if len(w) != 1 || w[0].(map[string]any)["count"] != 3.0 {
+					t.Fatalf("unexpected warnings %s", res.Body)
+				}
+			}},
+		{route: "PATCH /api/tasks/:id/move", name: "unknown neighbour", path: "/api/tasks/{build}/move", as: "member", want: 400,
+			body: withStatus("doing", gin.H{"beforeTaskId": 999999})},
+		{route: "PATCH /api/tasks/:id/move", name: "before a task", path: "/api/tasks/{build}/move", as: "member", want: 200,
+			body: func(e *testEnv) any {
+				return gin.H{"statusId": mustAtoi(e.vars["doing"]), "beforeTaskId": mustAtoi(e.vars["task"])}
+			}},
+		{route: "GET /api/projects/:id", name: "task order", path: "/api/projects/{project}", as: "viewer", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				columns := decode[map[string]any](t, res)["columns"].([]any)
+				tasks := columns[1].(map[string]any)["tasks"].([]any)
+				first, second := tasks[0].(map[string]any), tasks[1].(map[string]any)
+				if first["title"] != "Build" || first["position"] != 0.0 || second["position"] != 1.0 ||
+					first["rank"].(string) >= second["rank"].(string) {
+					t.Fatalf("unexpected order %+v", tasks)
+				}
+			}},
+		{route: "PATCH /api/tasks/:id", path: "/api/tasks/{task}", as: "member", want: 200,
+			body: gin.H{"title": "Design v2", "priority": "High"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				td := decode[model.TaskDetail](t, res)
+				if td.Title != "Design v2" || td.StatusName != "In Progress" {
+					t.Fatalf("unexpected task %+v", td)
+				}
+				e.vars["task_etag"] = res.Header().Get("ETag")
+			}},
+		{route: "PATCH /api/tasks/:id", name: "stale if-match", path: "/api/tasks/{task}", as: "admin", want: 412,
+			body: gin.H{"title": "Design v1.5"}, ifMatch: `"1"`, check: wantConflict("title", "Design v2")},
+		{route: "PATCH /api/tasks/:id", name: "malformed if-match", path: "/api/tasks/{task}", as: "admin", want: 400,
+			body: gin.H{"title": "Design v1.5"}, ifMatch: "v1"},
+		{route: "PATCH /api/tasks/:id", name: "if-match", path: "/api/tasks/{task}", as: "admin", want: 200,
+			body: gin.H{"description": "Wireframes"}, ifMatch: "{task_etag}",
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				td := decode[model.TaskDetail](t, res)
+				if td.Description == nil || *td.Description != "Wireframes" || res.Header().Get("ETag") == e.vars["task_etag"] {
+					t.Fatalf("unexpected task %+v, ETag %q", td, res.Header().Get("ETag"))
+				}
+			}},
+		{route: "GET /api/tasks/:id", name: "etag", path: "/api/tasks/{task}", as: "viewer", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if etag := res.Header().Get("ETag"); etag != fmt.Sprintf("%q", strconv.Itoa(decode[model.TaskDetail](t, res).Version)) {
+					t.Fatalf("ETag = %q", etag)
+				}
+			}},
+		{route: "PATCH /api/tasks/:id", name: "empty", path: "/api/tasks/{task}", as: "admin", want: 200,
+			body: gin.H{}, ifMatch: `"4"`,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if etag := res.Header().Get("ETag"); etag != `"4"` {
+					t.Fatalf("ETag = %q, want the version unchanged", etag)
+				}
+			}},
+		{route: "POST /api/tasks/:id/assignees", name: "stale if-match", path: "/api/tasks/{task}/assignees", as: "member", want: 412,
+			body: gin.H{"query": "MEMBER@example.com"}, ifMatch: "{task_etag}", check: wantConflict("description", "Wireframes")},
+		{route: "PATCH /api/tasks/:id", name: "member due date", path: "/api/tasks/{task}", as: "member", want: 403,
+			body: gin.H{"dueDate": "2031-02-02"}},
+		{route: "PATCH /api/tasks/:id", name: "unverified", path: "/api/tasks/{task}", as: "unverified", want: 403,
+			body: gin.H{"title": "Nope"}},
+		{route: "POST /api/tasks/:id/assignees", path: "/api/tasks/{task}/assignees", as: "member", want: 200,
+			body: gin.H{"query": "MEMBER@example.com"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if td := decode[model.TaskDetail](t, res); len(td.Assignees) != 1 {
+					t.Fatalf("assignees = %+v", td.Assignees)
+				}
+			}},
+		{route: "POST /api/tasks/:id/assignees", name: "unknown user", path: "/api/tasks/{task}/assignees", as: "member", want: 400,
+			body: gin.H{"query": "nobody"}},
+		{route: "POST /api/tasks/:id/collaborators", path: "/api/tasks/{task}/collaborators", as: "member", want: 200,
+			body: gin.H{"query": "Viewer"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if td := decode[model.TaskDetail](t, res); len(td.Collaborators) != 1 {
+					t.Fatalf("collaborators = %+v", td.Collaborators)
+				}
+			}},
+		{route: "POST /api/tasks/:id/comments", name: "viewer", path: "/api/tasks/{task}/comments", as: "viewer", want: 403,
+			body: gin.H{"text": "Nope"}},
+		{route: "POST /api/tasks/:id/comments", name: "empty", path: "/api/tasks/{task}/comments", as: "member", want: 400,
+			body: gin.H{"text": ""}},
+		{route: "POST /api/tasks/:id/comments", path: "/api/tasks/{task}/comments", as: "member", want: 201, check: wantLen(1),
+			body: gin.H{"text": "Looks good"}},
+		{route: "POST /api/tasks/:id/comments", name: "newest first", path: "/api/tasks/{task}/comments", as: "admin", want: 201,
+			body: gin.H{"text": "Ship it"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				comments := decode[[]model.TaskComment](t, res)
+				if len(comments) != 2 || comments[0].Text != "Ship it" || *comments[1].Author.Name != "Member" {
+					t.Fatalf("unexpected comments %s", res.Body)
+				}
+			}},
+		{route: "GET /api/tasks/:id/comments", path: "/api/tasks/{task}/comments", as: "viewer", want: 200, check: wantLen(2)},
+		{route: "POST /api/tasks/:id/attachments", path: "/api/tasks/{task}/attachments", as: "member", want: 201,
+			body: upload{"notes.txt", "hello"}},
+		{route: "POST /api/tasks/:id/attachments", name: "no file", path: "/api/tasks/{task}/attachments", as: "member", want: 400,
+			body: upload{}},
+		{route: "GET /api/tasks/:id/attachments", path: "/api/tasks/{task}/attachments", as: "viewer", want: 200, check: wantLen(1)},
+		{route: "GET /api/me/tasks", path: "/api/me/tasks", as: "member", want: 200, check: wantLen(1)},
+		{route: "PATCH /api/tasks/:id/move", name: "no matching rule", path: "/api/tasks/{task}/move", as: "member", want: 422,
+			body: withStatus("todo", gin.H{"position": 0})},
+		{route: "PATCH /api/tasks/:id/move", name: "role and comment", path: "/api/tasks/{task}/move", as: "member", want: 422,
+			body: withStatus("done", gin.H{"position": 0}),
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[struct {
+					Code  string                 `json:"code"`
+					Unmet []model.UnmetCondition `json:"unmet"`
+				}](t, res)
+				if body.Code != "transition_blocked" || len(body.Unmet) != 2 ||
+					body.Unmet[0].Condition != "role" || body.Unmet[1].Field != model.FieldComment {
+					t.Fatalf("unexpected unmet conditions %s", res.Body)
+				}
+			}},
+		{route: "PATCH /api/tasks/:id/move", name: "with comment", path: "/api/tasks/{task}/move", as: "admin", want: 200,
+			body: withStatus("done", gin.H{"position": 0, "comment": "Verified on staging"})},
+		{route: "GET /api/tasks/:id/comments", name: "move comment", path: "/api/tasks/{task}/comments", as: "viewer", want: 200, check: wantLen(3)},
+
+		{route: "GET /api/me", path: "/api/me", as: "member", want: 200},
+		{route: "PATCH /api/me", name: "taken email", path: "/api/me", as: "member", want: 409,
+			body: gin.H{"name": "Member", "email": "owner@example.com"}},
+		{route: "PATCH /api/me", path: "/api/me", as: "member", want: 200,
+			body: gin.H{"name": "Mem Ber", "email": "member@example.com"}},
+		{route: "POST /api/me/avatar", path: "/api/me/avatar", as: "member", want: 200,
+			body: upload{"me.png", "png"}},
+		{route: "GET /api/me/summary", path: "/api/me/summary", as: "member", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				s := decode[repository.Summary](t, res)
+				if s.AssignedCount != 1 || s.CommentCount != 1 || s.ProjectCount != 1 {
+					t.Fatalf("unexpected summary %+v", s)
+				}
+			}},
+		{route: "GET /api/me/projects", path: "/api/me/projects", as: "member", want: 200, check: wantLen(1)},
+		{route: "GET /api/users/:id", path: "/api/users/{member}", as: "owner", want: 200},
+		{route: "GET /api/users/:id", name: "missing", path: "/api/users/999999", as: "owner", want: 404},
+		{route: "GET /api/users/:id/summary", path: "/api/users/{viewer}/summary", as: "owner", want: 200},
+		{route: "GET /api/users/:id/projects", path: "/api/users/{viewer}/projects", as: "owner", want: 200, check: wantLen(1)},
+		{route: "GET /api/users/:id/projects", name: "nothing shared", path: "/api/users/{member}/projects", as: "outsider", want: 200, check: wantLen(0)},
+		{route: "GET /api/users/:id/projects", name: "self", path: "/api/users/{member}/projects", as: "member", want: 200, check: wantLen(1)},
+		{route: "GET /api/users/:id/summary", name: "nothing shared", path: "/api/users/{member}/summary", as: "outsider", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				s := decode[repository.Summary](t, res)
+				if s.AssignedCount != 0 || s.CommentCount != 0 || s.ProjectCount != 0 || len(s.RecentActivity) != 0 {
+					t.Fatalf("summary leaks unshared projects %+v", s)
+				}
+			}},
+		{route: "GET /api/users/:id/summary", name: "shared", path: "/api/users/{member}/summary", as: "viewer", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if s := decode[repository.Summary](t, res); s.AssignedCount != 1 || s.CommentCount != 1 || s.ProjectCount != 1 {
+					t.Fatalf("unexpected summary %+v", s)
+				}
+			}},
+		{route: "GET /api/settings", path: "/api/settings", as: "member", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if s := decode[model.UserSettings](t, res); s.AppearanceTheme != "Automatic" {
+					t.Fatalf("unexpected settings %+v", s)
+				}
+			}},
+		{route: "PATCH /api/settings", path: "/api/settings", as: "member", want: 200,
+			body: gin.H{"appearanceTheme": "Dark", "notificationsAssign": true}},
+
+		{route: "PATCH /api/me/password", name: "wrong current", path: "/api/me/password", as: "forgetful", want: 403,
+			body: gin.H{"currentPassword": testPassword, "newPassword": "Another-Horse-9"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if s, _ := e.lockout.Get(lockout.AccountKey("forgetful@example.com")); s.Failures != 1 {
+					t.Fatalf("failure not recorded: %+v", s)
+				}
+				e.login(t, "forgetful-laptop", "forgetful", resetPassword)
+			}},
+		{route: "PATCH /api/me/password", path: "/api/me/password", as: "forgetful", want: 200,
+			body: gin.H{"currentPassword": resetPassword, "newPassword": "Another-Horse-9"},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if s, _ := e.lockout.Get(lockout.AccountKey("forgetful@example.com")); s.Failures != 0 {
+					t.Fatalf("failures not cleared: %+v", s)
+				}
+			}},
+		{route: "GET /api/me", name: "other sessions revoked by password change", path: "/api/me", as: "forgetful-laptop", want: 401},
+		{route: "GET /api/me", name: "current session kept after password change", path: "/api/me", as: "forgetful", want: 200},
+		{route: "POST /api/me/verification", path: "/api/me/verification", as: "unverified", want: 202,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) { e.outbox.token(t) }},
+		{route: "POST /api/me/verification", name: "already verified", path: "/api/me/verification", as: "member", want: 200},
+		{route: "POST /api/login", name: "second session", path: "/api/login", want: 200,
+			body: gin.H{"email": "member@example.com", "password": testPassword},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				e.vars["session"] = decode[map[string]any](t, res)["session_id"].(string)
+			}},
+		{route: "GET /api/me/sessions", path: "/api/me/sessions", as: "member", want: 200, check: wantLen(2)},
+		{route: "DELETE /api/me/sessions/:id", path: "/api/me/sessions/{session}", as: "member", want: 204},
+		{route: "DELETE /api/me/sessions/:id", name: "already revoked", path: "/api/me/sessions/{session}", as: "member", want: 404},
+
+		{route: "POST /api/me/tokens", name: "unknown scope", path: "/api/me/tokens", as: "member", want: 400,
+			body: gin.H{"name": "CI", "scopes": []string{"everything"}}},
+		{route: "POST /api/me/tokens", path: "/api/me/tokens", as: "member", want: 201,
+			body: gin.H{"name": "CI", "scopes": []string{"projects:read"}},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[map[string]any](t, res)
+				e.tokens["pat"] = body["token"].(string)
+				e.vars["pat"] = id(body["accessToken"].(map[string]any)["id"])
+			}},
+		{route: "GET /api/me/tokens", path: "/api/me/tokens", as: "member", want: 200, check: wantLen(1)},
+		{route: "GET /api/projects", name: "access token", path: "/api/projects", as: "pat", want: 200, check: wantLen(1)},
+		{route: "GET /api/me", name: "access token without scope", path: "/api/me", as: "pat", want: 403},
+		{route: "GET /api/me/tokens", name: "access token", path: "/api/me/tokens", as: "pat", want: 403},
+		{route: "DELETE /api/me/tokens/:id", path: "/api/me/tokens/{pat}", as: "member", want: 204},
+		{route: "DELETE /api/me/tokens/:id", name: "already revoked", path: "/api/me/tokens/{pat}", as: "member", want: 404},
+		{route: "GET /api/projects", name: "revoked access token", path: "/api/projects", as: "pat", want: 401},
+
+		{route: "GET /api/me/2fa", path: "/api/me/2fa", as: "mfa", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				if decode[map[string]any](t, res)["enabled"] != false {
+					t.Fatalf("unexpected status %s", res.Body)
+				}
+			}},
+		{route: "POST /api/me/2fa/enroll", path: "/api/me/2fa/enroll", as: "mfa", want: 200,
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				e.vars["secret"] = decode[map[string]any](t, res)["secret"].(string)
+			}},
+		{route: "POST /api/me/2fa/enable", name: "wrong code", path: "/api/me/2fa/enable", as: "mfa", want: 400,
+			body: gin.H{"code": "abcdef"}},
+		{route: "POST /api/me/2fa/enable", path: "/api/me/2fa/enable", as: "mfa", want: 200,
+			body: func(e *testEnv) any { return gin.H{"code": totpCode(t, e.vars["secret"])} },
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				codes := decode[map[string]any](t, res)["recoveryCodes"].([]any)
+				e.vars["recovery0"], e.vars["recovery1"] = codes[0].(string), codes[1].(string)
+			}},
+		{route: "POST /api/me/2fa/enroll", name: "already enabled", path: "/api/me/2fa/enroll", as: "mfa", want: 409},
+		{route: "POST /api/login", name: "second factor required", path: "/api/login", want: 200,
+			body: gin.H{"email": "mfa@example.com", "password": testPassword},
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				body := decode[map[string]any](t, res)
+				if body["mfa_required"] != true || body["token"] != nil {
+					t.Fatalf("expected a 2fa challenge: %v", body)
+				}
+				e.vars["mfa_token"] = body["mfa_token"].(string)
+			}},
+		{route: "POST /api/login/2fa", path: "/api/login/2fa", want: 200,
+			body: func(e *testEnv) any { return gin.H{"mfa_token": e.vars["mfa_token"], "code": e.vars["recovery0"]} }},
+		{route: "POST /api/login/2fa", name: "recovery code reused", path: "/api/login/2fa", want: 401,
+			body: func(e *testEnv) any { return gin.H{"mfa_token": e.vars["mfa_token"], "code": e.vars["recovery0"]} }},
+		{route: "POST /api/me/2fa/recovery-codes", path: "/api/me/2fa/recovery-codes", as: "mfa", want: 200,
+			body: func(e *testEnv) any { return gin.H{"code": e.vars["recovery1"]} },
+			check: func(t *testing.T, e *testEnv, res *httptest.ResponseRecorder) {
+				e.vars["recovery0"] = decode[map[string]any](t, res)["recoveryCodes"].([]any)[0].(string)
+			}},
+		{route: "POST /api/me/2fa/disable", name: "wrong code", path: "/api/me/2fa/disable", as: "mfa", want: 403,
+			body: gin.H{"code": "abcdef"}},
+		{route: "POST /api/me/2fa/disable", path: "/api/me/2fa/disable", as: "mfa", want: 204,
+			body: func(e *testEnv) any { return gin.H{"code": e.vars["recovery0"]} }},
+
+		{route: "POST /api/admin/unlock", name: "not an admin", path: "/api/admin/unlock", as: "member", want: 403,
+			body: gin.H{"login": "member@example.com"}},
+		{route: "POST /api/admin/unlock", name: "nothing to unlock", path: "/api/admin/unlock", as: "root", want: 400,
+			body: gin.H{}},
+		{route: "POST /api/admin/unlock", path: "/api/admin/unlock", as: "root", want: 204,
+			body: func(e *testEnv) any { return gin.H{"userId": mustAtoi(e.vars["member"])} }},
+		{route: "GET /api/admin/auth-events", path: "/api/admin/auth-events?event=unlock", as: "root", want: 200, check: wantLen(2)},
+
+		{route: "DELETE /api/projects/:id/members/:userId", name: "leave", path: "/api/projects/{project}/members/{viewer}", as: "viewer", want: 204},
+		{route: "POST /api/logout", path: "/api/logout", as: "forgetful", want: 204},
+		{route: "GET /api/me", name: "after logout", path: "/api/me", as: "forgetful", want: 401},
+	}
+
+	covered := map[string]bool{}
+	for _, c := range cases {
+		covered[c.route] = true
+		method, _, _ := strings.Cut(c.route, " ")
+		t.Run(c.route+" "+c.name, func(t *testing.T) {
+			res := e.do(method, c.path, c.as, c.body, c.ifMatch)
+			if res.Code != c.want {
+				t.Fatalf("status %d, want %d: %s", res.Code, c.want, res.Body)
+			}
+			if c.check != nil {
+				c.check(t, e, res)
+			}
+		})
+	}
+	for _, r := range e.router.Routes() {
+		if !covered[r.Method+" "+r.Path] {
+			t.Errorf("route %s %s has no test case", r.Method, r.Path)
+		}
+	}
+}
+
+func mustAtoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
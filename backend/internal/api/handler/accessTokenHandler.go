@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+type AccessTokenHandler struct {
+	Repo repository.AccessTokenStore
+}
+
+type CreateAccessTokenPayload struct {
+	Name          string        `json:"name" binding:"required"`
+	Scopes        []model.Scope `json:"scopes" binding:"required"`
+	ExpiresInDays int           `json:"expiresInDays"`
+}
+
+func (h *AccessTokenHandler) List(c *gin.Context) {
+	tokens, err := h.Repo.List(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Create issues a token and returns the secret once; only its hash is kept.
+func (h *AccessTokenHandler) Create(c *gin.Context) {
+	var p CreateAccessTokenPayload
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be between 1 and 100 characters"})
+		return
+	}
+	if len(p.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+	seen := map[model.Scope]bool{}
+	scopes := []model.Scope{}
+	for _, s := range p.Scopes {
+		if !s.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + string(s), "scopes": model.Scopes})
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	ttl := config.AccessTokens.DefaultTTL
+	if p.ExpiresInDays != 0 {
+		ttl = time.Duration(p.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl <= 0 || ttl > config.AccessTokens.MaxTTL {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "expiresInDays must be between 1 and " + strconv.Itoa(int(config.AccessTokens.MaxTTL/(24*time.Hour))),
+		})
+		return
+	}
+
+	secret, hash, display, err := auth.NewPersonalToken(config.AccessTokens.Prefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token"})
+		return
+	}
+	t := &model.AccessToken{
+		UserID:    c.GetInt("userID"),
+		Name:      p.Name,
+		Prefix:    display,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+	}
+	if err := h.Repo.Create(t, hash, config.AccessTokens.MaxPerUser); err != nil {
+		if errors.Is(err, repository.ErrTokenLimit) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Access token limit reached, revoke an unused token first"})
+			return
+		}
+		log.Println("[auth] create access token:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"token": secret, "accessToken": t})
+}
+
+func (h *AccessTokenHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+	if err := h.Repo.Revoke(id, c.GetInt("userID")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Access token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
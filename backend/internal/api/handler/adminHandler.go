@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/lockout"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+type AdminHandler struct {
+	UserRepo repository.UserStore
+	Events   repository.AuthEventStore
+	Lockout  *lockout.Guard
+}
+
+// Unlock clears login lockouts for a user, a raw login name and/or an IP.
+func (h *AdminHandler) Unlock(c *gin.Context) {
+	var body struct {
+		UserID int    `json:"userId"`
+		Login  string `json:"login"`
+		IP     string `json:"ip"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	var keys []string
+	var target *int
+	if body.UserID != 0 {
+		u, err := h.UserRepo.GetByID(body.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock"})
+			return
+		}
+		target = &u.ID
+		if body.Login == "" {
+			body.Login = u.Email
+		}
+	}
+	login := strings.ToLower(strings.TrimSpace(body.Login))
+	if login != "" {
+		keys = append(keys, lockout.AccountKey(login))
+	}
+	if body.IP != "" {
+		keys = append(keys, lockout.IPKey(body.IP))
+	}
+	if len(keys) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId, login or ip is required"})
+		return
+	}
+	if err := h.Lockout.Clear(keys...); err != nil {
+		log.Println("[auth] clear lockout:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock"})
+		return
+	}
+	admin := c.GetInt("userID")
+	for _, k := range keys {
+		recordAuthEvent(h.Events, model.AuthEvent{
+			UserID: target,
+			Event:  model.AuthEventUnlock,
+			Login:  login,
+			IP:     c.ClientIP(),
+			Detail: k + " by admin " + strconv.Itoa(admin),
+		})
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ListAuthEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+	events, err := h.Events.List(c.Query("event"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch auth events"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+func recordAuthEvent(events repository.AuthEventStore, e model.AuthEvent) {
+	if err := events.Record(e); err != nil {
+		log.Printf("[auth] record %s event: %v", e.Event, err)
+	}
+}
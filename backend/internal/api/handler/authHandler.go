@@ -1,23 +1,53 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 
+	"planify/backend/internal/auth"
 	"planify/backend/internal/config"
+	"planify/backend/internal/lockout"
+	"planify/backend/internal/mail"
+	"planify/backend/internal/model"
+	"planify/backend/internal/ratelimit"
 	"planify/backend/internal/repository"
 )
 
 type LoginPayload struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
 type AuthHandler struct {
-	UserRepo *repository.UserRepository
+	Authenticator auth.Authenticator
+
+	UserRepo repository.UserStore
+	Sessions repository.SessionStore
+	MFA      repository.MFAStore
+	Mailer   mail.Mailer
+
+	MFAAttempts *ratelimit.Window
+	Lockout     *lockout.Guard
+	Events      repository.AuthEventStore
+}
+
+func NewAuthHandler(authn auth.Authenticator, users repository.UserStore, sessions repository.SessionStore, mfa repository.MFAStore, mailer mail.Mailer, guard *lockout.Guard, events repository.AuthEventStore) *AuthHandler {
+	return &AuthHandler{
+		Authenticator: authn,
+		UserRepo:      users,
+		Sessions:      sessions,
+		MFA:           mfa,
+		Mailer:        mailer,
+		MFAAttempts:   ratelimit.NewWindow(config.MFA.MaxAttempts, config.MFA.LoginTokenTTL),
+		Lockout:       guard,
+		Events:        events,
+	}
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -26,32 +56,120 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
-	u, err := h.UserRepo.GetUserByEmail(p.Email)
-	if err != nil || u == nil || u.Password != p.Password {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+	login := strings.ToLower(strings.TrimSpace(p.Email))
+	accountKey := lockout.AccountKey(login)
+	if lockedOut(c, h.Lockout, login) {
+		return
+	}
+	u, err := h.Authenticator.Authenticate(c.Request.Context(), strings.TrimSpace(p.Email), p.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			recordLoginFailure(c, h.Lockout, h.Events, login)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "authentication backend unavailable"})
 		return
 	}
 
-	now := time.Now()
-	ttl := config.AccessTokenTTL()
-	claims := jwt.MapClaims{
-		"uid":   u.ID,         
-		"email": u.Email,
-		"iat":   now.Unix(),
-		"exp":   now.Add(ttl).Unix(),
+	if err := h.Lockout.Clear(accountKey); err != nil {
+		log.Println("[auth] clear lockout:", err)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString(config.JwtKey)
+
+	mfaEnabled, err := h.MFA.IsEnabled(u.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
+		log.Printf("[auth] lookup 2fa for user %d: %v", u.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create session"})
 		return
 	}
+	if mfaEnabled {
+		h.challengeMFA(c, u)
+		return
+	}
+	h.startSession(c, u)
+}
+
+// lockedOut answers 429 when login or the client IP must wait before
+// another password attempt.
+func lockedOut(c *gin.Context, guard *lockout.Guard, login string) bool {
+	wait, err := guard.Wait(lockout.AccountKey(login), lockout.IPKey(c.ClientIP()))
+	if err != nil {
+		log.Println("[auth] check lockout:", err)
+	}
+	if wait <= 0 {
+		return false
+	}
+	secs := int((wait + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, try again later", "retryAfter": secs})
+	return true
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":      signed,
-		"token_type": "Bearer",
-		"expires_in": int(ttl / time.Second),
-		"user_id":    u.ID,
-		"email":      u.Email,
+func recordLoginFailure(c *gin.Context, guard *lockout.Guard, events repository.AuthEventStore, login string) {
+	ip := c.ClientIP()
+	locked, err := guard.Fail(
+		lockout.Limit{Key: lockout.AccountKey(login), Threshold: config.Lockout.AccountThreshold},
+		lockout.Limit{Key: lockout.IPKey(ip), Threshold: config.Lockout.IPThreshold},
+	)
+	if err != nil {
+		log.Println("[auth] record login failure:", err)
+	}
+	for _, key := range locked {
+		log.Printf("[auth] locked %s for %s", key, config.Lockout.LockDuration)
+		recordAuthEvent(events, model.AuthEvent{Event: model.AuthEventLockout, Login: login, IP: ip, Detail: key})
+	}
+}
+
+func (h *AuthHandler) startSession(c *gin.Context, u *model.User) {
+	t, err := h.createSession(c, u)
+	if err != nil {
+		log.Printf("[auth] create session for user %d: %v", u.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create session"})
+		return
+	}
+	writeTokens(c, t, gin.H{
+		"user_id":        u.ID,
+		"email":          u.Email,
+		"email_verified": u.EmailVerified,
 	})
 }
+
+func (h *AuthHandler) createSession(c *gin.Context, u *model.User) (issuedTokens, error) {
+	sid, err := auth.NewID()
+	if err != nil {
+		return issuedTokens{}, err
+	}
+	refresh, refreshHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return issuedTokens{}, err
+	}
+	now := time.Now()
+	s := &model.Session{
+		ID:         sid,
+		UserID:     u.ID,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(config.RefreshTokenTTL()),
+	}
+	if err := h.Sessions.Create(s, refreshHash); err != nil {
+		return issuedTokens{}, err
+	}
+	signed, ttl, err := auth.SignAccessToken(u.ID, u.Email, sid)
+	if err != nil {
+		return issuedTokens{}, err
+	}
+	return issuedTokens{
+		SessionID:  sid,
+		Access:     signed,
+		AccessTTL:  ttl,
+		Refresh:    refresh,
+		RefreshTTL: config.RefreshTokenTTL(),
+	}, nil
+}
+
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.Keys().JWKS())
+}
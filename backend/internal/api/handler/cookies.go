@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/config"
+)
+
+type issuedTokens struct {
+	SessionID  string
+	Access     string
+	AccessTTL  time.Duration
+	Refresh    string
+	RefreshTTL time.Duration
+}
+
+// writeTokens sends freshly issued tokens to the client. In cookie mode the
+// tokens never appear in the body; the browser only gets the CSRF value it
+// has to echo back in config.Cookie.CSRFHeader.
+func writeTokens(c *gin.Context, t issuedTokens, extra gin.H) {
+	body := gin.H{
+		"expires_in": int(t.AccessTTL / time.Second),
+		"session_id": t.SessionID,
+	}
+	if config.TokenInCookie {
+		body["csrf_token"] = setTokenCookies(c, t)
+	} else {
+		body["token"] = t.Access
+		body["token_type"] = "Bearer"
+		body["refresh_token"] = t.Refresh
+		body["refresh_expires_in"] = int(t.RefreshTTL / time.Second)
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// setTokenCookies stores the tokens in cookies and returns the CSRF value.
+func setTokenCookies(c *gin.Context, t issuedTokens) string {
+	csrf := auth.CSRFToken(t.SessionID)
+	setCookie(c, config.Cookie.AccessName, t.Access, "/api", t.AccessTTL, true)
+	setCookie(c, config.Cookie.RefreshName, t.Refresh, "/api/token", t.RefreshTTL, true)
+	setCookie(c, config.Cookie.CSRFName, csrf, "/", t.RefreshTTL, false)
+	return csrf
+}
+
+func clearAuthCookies(c *gin.Context) {
+	setCookie(c, config.Cookie.AccessName, "", "/api", -1, true)
+	setCookie(c, config.Cookie.RefreshName, "", "/api/token", -1, true)
+	setCookie(c, config.Cookie.CSRFName, "", "/", -1, false)
+}
+
+func setCookie(c *gin.Context, name, value, path string, ttl time.Duration, httpOnly bool) {
+	maxAge := int(ttl / time.Second)
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   config.Cookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   config.Cookie.Secure,
+		HttpOnly: httpOnly,
+		SameSite: sameSiteMode(config.Cookie.SameSite),
+	})
+}
+
+func sameSiteMode(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
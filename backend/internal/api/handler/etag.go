@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tasks and projects carry a version that every edit bumps. It goes out as
+// the ETag, and a client that sends it back in If-Match only gets its edit
+// applied if nobody else changed the row in between.
+
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// setWeakETag is for the project board, whose columns and tasks change
+// without bumping the project's version, so two boards with the same tag are
+// only roughly equivalent. If-Match needs a strong tag, so project writes
+// send the board's "version" field as "n" instead.
+func setWeakETag(c *gin.Context, version int) {
+	c.Header("ETag", `W/"`+strconv.Itoa(version)+`"`)
+}
+
+// ifMatch returns the version named by If-Match, or 0 when the header is
+// missing or "*". Anything that is not one of our strong ETags is rejected:
+// RFC 7232 compares If-Match strongly, which a weak tag can never pass.
+func ifMatch(c *gin.Context) (int, bool) {
+	h := strings.TrimSpace(c.GetHeader("If-Match"))
+	if h == "" || h == "*" {
+		return 0, true
+	}
+	if strings.HasPrefix(h, "W/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `If-Match needs a strong ETag such as "3"; weak tags never match`})
+		return 0, false
+	}
+	v, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(h, `"`), `"`))
+	if err != nil || v < 1 || !strings.HasPrefix(h, `"`) || !strings.HasSuffix(h, `"`) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be an ETag returned by this API"})
+		return 0, false
+	}
+	return v, true
+}
+
+// versionConflict answers a failed If-Match with the current state, so the
+// client can merge and retry with the new ETag, which the caller sets.
+func versionConflict(c *gin.Context, current any) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "This was changed by someone else since you loaded it",
+		"code":    "version_conflict",
+		"current": current,
+	})
+}
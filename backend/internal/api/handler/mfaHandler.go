@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+const purposeMFALogin = "mfa_login"
+
+func (h *AuthHandler) challengeMFA(c *gin.Context, u *model.User) {
+	token, err := auth.SignPurposeToken(purposeMFALogin, u.ID, nil, config.MFA.LoginTokenTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required": true,
+		"mfa_token":    token,
+		"expires_in":   int(config.MFA.LoginTokenTTL / time.Second),
+	})
+}
+
+// LoginMFA finishes a two-step login: the intermediate token from Login plus
+// a current TOTP code or an unused recovery code yield a real session.
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var body struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	uid, _, err := auth.ParsePurposeToken(body.MFAToken, purposeMFALogin)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, sign in again"})
+		return
+	}
+	if !h.MFAAttempts.Allow(strconv.Itoa(uid)) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, sign in again later"})
+		return
+	}
+	ok, err := h.checkSecondFactor(uid, body.Code)
+	if err != nil {
+		log.Printf("[auth] check 2fa for user %d: %v", uid, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify code"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		return
+	}
+	u, err := h.UserRepo.GetByID(uid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, sign in again"})
+		return
+	}
+	h.startSession(c, u)
+}
+
+func (h *AuthHandler) GetMFAStatus(c *gin.Context) {
+	uid := c.GetInt("userID")
+	m, err := h.MFA.Get(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, gin.H{"enabled": false, "recoveryCodesRemaining": 0})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+		return
+	}
+	remaining, err := h.MFA.RemainingRecoveryCodes(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": m.Enabled, "recoveryCodesRemaining": remaining})
+}
+
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	u, err := h.UserRepo.GetByID(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+	if err := h.MFA.SavePending(u.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+	uri := auth.TOTPURI(config.MFA.Issuer, u.Email, secret)
+	c.JSON(http.StatusOK, gin.H{
+		"secret":     secret,
+		"otpauthUri": uri,
+		"qrPayload":  uri,
+	})
+}
+
+func (h *AuthHandler) EnableMFA(c *gin.Context) {
+	var body struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	uid := c.GetInt("userID")
+	m, err := h.MFA.Get(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment first"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	if m.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	step, ok := auth.ValidateTOTP(m.Secret, body.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	if err := h.MFA.Enable(uid, step, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "recoveryCodes": codes})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	uid, ok := h.requireSecondFactor(c)
+	if !ok {
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+	if err := h.MFA.ReplaceRecoveryCodes(uid, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	uid, ok := h.requireSecondFactor(c)
+	if !ok {
+		return
+	}
+	if err := h.MFA.Disable(uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) requireSecondFactor(c *gin.Context) (int, bool) {
+	var body struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return 0, false
+	}
+	uid := c.GetInt("userID")
+	if !h.MFAAttempts.Allow(strconv.Itoa(uid)) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, try again later"})
+		return 0, false
+	}
+	ok, err := h.checkSecondFactor(uid, body.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify code"})
+		return 0, false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid verification code"})
+		return 0, false
+	}
+	return uid, true
+}
+
+func (h *AuthHandler) checkSecondFactor(uid int, code string) (bool, error) {
+	m, err := h.MFA.Get(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !m.Enabled {
+		return false, nil
+	}
+	if step, ok := auth.ValidateTOTP(m.Secret, code, time.Now()); ok {
+		return h.MFA.ConsumeStep(uid, step)
+	}
+	err = h.MFA.UseRecoveryCode(uid, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.NewRecoveryCodes(config.MFA.RecoveryCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(code)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/background"
+	"planify/backend/internal/config"
+	"planify/backend/internal/lockout"
+	"planify/backend/internal/mail"
+	"planify/backend/internal/model"
+	"planify/backend/internal/ratelimit"
+	"planify/backend/internal/repository"
+)
+
+type PasswordResetHandler struct {
+	UserRepo  repository.UserStore
+	ResetRepo repository.PasswordResetStore
+	Sessions  repository.SessionStore
+	Mailer    mail.Mailer
+
+	ByIP    *ratelimit.Window
+	ByEmail *ratelimit.Window
+
+	Lockout *lockout.Guard
+	Events  repository.AuthEventStore
+	Jobs    *background.Group
+}
+
+func NewPasswordResetHandler(users repository.UserStore, resets repository.PasswordResetStore, sessions repository.SessionStore, mailer mail.Mailer, guard *lockout.Guard, events repository.AuthEventStore, jobs *background.Group) *PasswordResetHandler {
+	cfg := config.PasswordReset
+	return &PasswordResetHandler{
+		UserRepo:  users,
+		ResetRepo: resets,
+		Sessions:  sessions,
+		Mailer:    mailer,
+		ByIP:      ratelimit.NewWindow(cfg.RateLimitIP, cfg.RateWindow),
+		ByEmail:   ratelimit.NewWindow(cfg.RateLimitEmail, cfg.RateWindow),
+		Lockout:   guard,
+		Events:    events,
+		Jobs:      jobs,
+	}
+}
+
+// Forgot always answers 202 with the same body so callers cannot tell
+// whether an account exists. Mail is sent in the background for the same
+// reason.
+func (h *PasswordResetHandler) Forgot(c *gin.Context) {
+	if !h.ByIP.Allow(c.ClientIP()) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+		return
+	}
+	var body struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	if h.ByEmail.Allow(email) {
+		h.Jobs.Go("password-reset mail", func(ctx context.Context) { h.issue(ctx, email) })
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If that email is registered, a reset link has been sent"})
+}
+
+func (h *PasswordResetHandler) Reset(c *gin.Context) {
+	var body struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := auth.CheckPasswordPolicy(body.Password); err != nil {
+		writePolicyError(c, err)
+		return
+	}
+	hash, err := auth.HashPassword(body.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	uid, err := h.ResetRepo.Consume(auth.HashToken(body.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := h.UserRepo.UpdatePasswordHash(uid, hash); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := h.Sessions.RevokeAllForUser(uid, ""); err != nil {
+		log.Printf("[auth] revoke sessions after reset for user %d: %v", uid, err)
+	}
+	h.unlock(c, uid)
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// unlock lifts a login lockout once the owner has proven control of the
+// mailbox.
+func (h *PasswordResetHandler) unlock(c *gin.Context, uid int) {
+	u, err := h.UserRepo.GetByID(uid)
+	if err != nil {
+		log.Printf("[auth] lookup user %d after reset: %v", uid, err)
+		return
+	}
+	login := strings.ToLower(u.Email)
+	key := lockout.AccountKey(login)
+	if err := h.Lockout.Clear(key); err != nil {
+		log.Println("[auth] clear lockout:", err)
+		return
+	}
+	recordAuthEvent(h.Events, model.AuthEvent{
+		UserID: &u.ID,
+		Event:  model.AuthEventUnlock,
+		Login:  login,
+		IP:     c.ClientIP(),
+		Detail: key + " by password reset",
+	})
+}
+
+func (h *PasswordResetHandler) issue(ctx context.Context, email string) {
+	u, err := h.UserRepo.GetUserByEmail(email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println("[auth] password reset lookup:", err)
+		}
+		return
+	}
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Println("[auth] password reset token:", err)
+		return
+	}
+	ttl := config.PasswordReset.TokenTTL
+	if err := h.ResetRepo.Create(u.ID, hash, time.Now().Add(ttl)); err != nil {
+		log.Println("[auth] store password reset token:", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	link := config.PublicURL + "/#/reset-password?token=" + url.QueryEscape(token)
+	err = h.Mailer.Send(ctx, mail.Message{
+		To:      []string{u.Email},
+		Subject: "Reset your Planify password",
+		Text: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset your Planify password. If it was you, open this link:\n\n%s\n\nIt expires in %s and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			u.Name, link, ttl),
+	})
+	if err != nil {
+		log.Printf("[auth] send password reset to user %d: %v", u.ID, err)
+	}
+}
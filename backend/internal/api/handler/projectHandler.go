@@ -2,7 +2,9 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
+	"planify/backend/internal/model"
 	"planify/backend/internal/repository"
 	"strconv"
 
@@ -11,11 +13,16 @@ import (
 
 
 type ProjectHandler struct {
-	Repo *repository.ProjectRepository
+	Repo repository.ProjectStore
 }
 
 func (h *ProjectHandler) GetAllProjects(c *gin.Context) {
-	projects, err := h.Repo.GetAll()
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	projects, err := h.Repo.GetAll(userID.(int))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
 		return
@@ -31,13 +38,14 @@ func (h *ProjectHandler) GetProjectByID(c *gin.Context) {
 	}
 	project, err := h.Repo.GetByID(id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	setWeakETag(c, project["version"].(int))
 	c.JSON(http.StatusOK, project)
 }
 
@@ -52,7 +60,15 @@ func (h *ProjectHandler) UpdateProjectDueDate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
+	var ok bool
+	if payload.Version, ok = ifMatch(c); !ok {
+		return
+	}
 	if err := h.Repo.UpdateDueDate(projectID, payload); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, projectID)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update due date"})
 		return
 	}
@@ -75,8 +91,233 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 
 	project, err := h.Repo.Create(payload)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, repository.ErrUnknownMember) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown team member"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
 		return
 	}
 	c.JSON(http.StatusCreated, project)
-}
\ No newline at end of file
+}
+func (h *ProjectHandler) Delete(c *gin.Context) {
+	projectID := c.GetInt("projectID")
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.Delete(projectID, version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, projectID)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ProjectHandler) UpdateSecurity(c *gin.Context) {
+	var body struct {
+		Require2FA *bool `json:"require2fa" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if *body.Require2FA && !c.GetBool("hasMFA") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Enable two-factor authentication on your own account first"})
+		return
+	}
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.SetRequire2FA(c.GetInt("projectID"), *body.Require2FA, version); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, c.GetInt("projectID"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update security settings"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"require2fa": *body.Require2FA})
+}
+
+func (h *ProjectHandler) ListMembers(c *gin.Context) {
+	members, err := h.Repo.ListMembers(c.GetInt("projectID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *ProjectHandler) AddMember(c *gin.Context) {
+	var body struct {
+		UserID int        `json:"userId" binding:"required"`
+		Role   model.Role `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if body.Role == "" {
+		body.Role = model.RoleMember
+	}
+	if !h.canAssignRole(c, body.Role) {
+		return
+	}
+	projectID := c.GetInt("projectID")
+	if err := h.Repo.AddMember(projectID, body.UserID, body.Role); err != nil {
+		if errors.Is(err, repository.ErrAlreadyMember) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+	h.ListMembers(c)
+}
+
+func (h *ProjectHandler) UpdateMemberRole(c *gin.Context) {
+	target, ok := h.loadTargetMember(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		Role model.Role `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if !h.canManageMember(c, target) || !h.canAssignRole(c, body.Role) {
+		return
+	}
+	if err := h.Repo.UpdateMemberRole(c.GetInt("projectID"), target.ID, body.Role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	h.ListMembers(c)
+}
+
+func (h *ProjectHandler) RemoveMember(c *gin.Context) {
+	target, ok := h.loadTargetMember(c)
+	if !ok {
+		return
+	}
+	self := target.ID == c.GetInt("userID")
+	if self && target.Role == model.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Transfer ownership before leaving the project"})
+		return
+	}
+	if !self {
+		if !model.Role(c.GetString("projectRole")).Can(model.CapManageMembers) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			return
+		}
+		if !h.canManageMember(c, target) {
+			return
+		}
+	}
+	if err := h.Repo.RemoveMember(c.GetInt("projectID"), target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ProjectHandler) TransferOwnership(c *gin.Context) {
+	var body struct {
+		UserID int `json:"userId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	uid := c.GetInt("userID")
+	if body.UserID == uid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already own this project"})
+		return
+	}
+	projectID := c.GetInt("projectID")
+	if err := h.Repo.TransferOwnership(projectID, uid, body.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New owner must already be a project member"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	h.ListMembers(c)
+}
+
+func (h *ProjectHandler) loadTargetMember(c *gin.Context) (*model.ProjectMember, bool) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+	role, err := h.Repo.GetMemberRole(c.GetInt("projectID"), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch member"})
+		return nil, false
+	}
+	return &model.ProjectMember{ID: userID, Role: role}, true
+}
+
+// Ownership only changes hands through TransferOwnership, and only the owner
+// may promote, demote or remove admins.
+func (h *ProjectHandler) canManageMember(c *gin.Context, target *model.ProjectMember) bool {
+	actor := model.Role(c.GetString("projectRole"))
+	if target.Role == model.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Use ownership transfer to change the owner"})
+		return false
+	}
+	if target.Role == model.RoleAdmin && actor != model.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage admins"})
+		return false
+	}
+	return true
+}
+
+func (h *ProjectHandler) canAssignRole(c *gin.Context, role model.Role) bool {
+	actor := model.Role(c.GetString("projectRole"))
+	if !role.Valid() || role == model.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return false
+	}
+	if role == model.RoleAdmin && actor != model.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage admins"})
+		return false
+	}
+	return true
+}
+
+// conflict answers a failed If-Match with the project as it is now.
+func (h *ProjectHandler) conflict(c *gin.Context, projectID int) {
+	project, err := h.Repo.GetByID(projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project"})
+		return
+	}
+	setWeakETag(c, project["version"].(int))
+	versionConflict(c, project)
+}
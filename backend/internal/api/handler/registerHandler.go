@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/config"
+	"planify/backend/internal/mail"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+type RegisterPayload struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (h *AuthHandler) Register(c *gin.Context) {
+	if !config.Signup.Enabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is disabled"})
+		return
+	}
+	var p RegisterPayload
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	p.Email = strings.ToLower(strings.TrimSpace(p.Email))
+	if !signupDomainAllowed(p.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is restricted for this email domain"})
+		return
+	}
+	if err := auth.CheckPasswordPolicy(p.Password); err != nil {
+		writePolicyError(c, err)
+		return
+	}
+	hash, err := auth.HashPassword(p.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+	u, err := h.UserRepo.Create(p.Name, p.Email, hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+	if err := h.sendVerification(c.Request.Context(), u); err != nil {
+		log.Printf("[auth] send verification to user %d: %v", u.ID, err)
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":            u.ID,
+		"name":          u.Name,
+		"email":         u.Email,
+		"emailVerified": u.EmailVerified,
+	})
+}
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var body struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	uid, claims, err := auth.ParsePurposeToken(body.Token, auth.PurposeVerifyEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+	u, err := h.UserRepo.GetByID(uid)
+	if err != nil || claims["email"] != u.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+	if err := h.UserRepo.MarkEmailVerified(u.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	uid, ok := c.Get("userID")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u, err := h.UserRepo.GetByID(uid.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if u.EmailVerified {
+		c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+		return
+	}
+	if err := h.sendVerification(c.Request.Context(), u); err != nil {
+		log.Printf("[auth] resend verification to user %d: %v", u.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+func (h *AuthHandler) sendVerification(ctx context.Context, u *model.User) error {
+	token, err := auth.SignPurposeToken(auth.PurposeVerifyEmail, u.ID, jwt.MapClaims{"email": u.Email}, config.Signup.VerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := config.PublicURL + "/#/verify-email?token=" + url.QueryEscape(token)
+	return h.Mailer.Send(ctx, mail.Message{
+		To:      []string{u.Email},
+		Subject: "Confirm your Planify account",
+		Text: fmt.Sprintf("Hi %s,\n\nConfirm your email address to finish setting up Planify:\n\n%s\n\nThis link expires in %s.\n",
+			u.Name, link, config.Signup.VerificationTTL),
+	})
+}
+
+func signupDomainAllowed(email string) bool {
+	if len(config.Signup.AllowedDomains) == 0 {
+		return true
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, d := range config.Signup.AllowedDomains {
+		if strings.EqualFold(strings.TrimPrefix(d, "@"), domain) {
+			return true
+		}
+	}
+	return false
+}
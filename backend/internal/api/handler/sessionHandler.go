@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/config"
+	"planify/backend/internal/repository"
+)
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+	}
+	if body.RefreshToken == "" && config.TokenInCookie {
+		// The refresh cookie is sent cross-site only under SameSite=None, so
+		// this endpoint still needs the double-submit check.
+		csrf, _ := c.Cookie(config.Cookie.CSRFName)
+		if csrf == "" || c.GetHeader(config.Cookie.CSRFHeader) != csrf {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
+			return
+		}
+		body.RefreshToken, _ = c.Cookie(config.Cookie.RefreshName)
+	}
+	if body.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	next, nextHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not refresh session"})
+		return
+	}
+	s, err := h.Sessions.Rotate(auth.HashToken(body.RefreshToken), nextHash, c.ClientIP(), time.Now().Add(config.RefreshTokenTTL()))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTokenReused):
+			log.Println("[auth] refresh token reuse detected, session revoked")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected"})
+		case errors.Is(err, sql.ErrNoRows), errors.Is(err, repository.ErrSessionRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		default:
+			log.Println("[auth] rotate refresh token:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not refresh session"})
+		}
+		return
+	}
+	u, err := h.UserRepo.GetByID(s.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	signed, ttl, err := auth.SignAccessToken(u.ID, u.Email, s.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
+		return
+	}
+	writeTokens(c, issuedTokens{
+		SessionID:  s.ID,
+		Access:     signed,
+		AccessTTL:  ttl,
+		Refresh:    next,
+		RefreshTTL: config.RefreshTokenTTL(),
+	}, nil)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	uid := c.GetInt("userID")
+	if err := h.Sessions.Revoke(c.GetString("sessionID"), uid); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if config.TokenInCookie {
+		clearAuthCookies(c)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.Sessions.ListActive(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	current := c.GetString("sessionID")
+	for i := range sessions {
+		sessions[i].Device = describeDevice(sessions[i].UserAgent)
+		sessions[i].Current = sessions[i].ID == current
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	if err := h.Sessions.Revoke(c.Param("id"), c.GetInt("userID")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func describeDevice(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	os := ""
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"},
+		{"Windows", "Windows"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			os = o.name
+			break
+		}
+	}
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
@@ -9,7 +9,7 @@ import (
 )
 
 type SettingsHandler struct {
-	UserRepo *repository.UserRepository
+	UserRepo repository.UserStore
 }
 
 func (h *SettingsHandler) GetSettings(c *gin.Context) {
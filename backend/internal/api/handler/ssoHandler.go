@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+
+	"planify/backend/internal/auth"
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
+	"planify/backend/internal/sso"
+)
+
+const (
+	purposeOIDCState = "oidc_state"
+	oidcStateCookie  = "planify_oidc"
+)
+
+type SSOHandler struct {
+	Auth      *AuthHandler
+	Providers *sso.Registry
+}
+
+func (h *SSOHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Providers.List())
+}
+
+// Login starts the authorization-code flow. State, nonce and the PKCE
+// verifier travel in a signed, short-lived cookie scoped to the callback, so
+// no server-side storage is needed between the two legs.
+func (h *SSOHandler) Login(c *gin.Context) {
+	p, err := h.Providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+	state, err := auth.NewID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	nonce, err := auth.NewID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+	target, err := p.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Println("[sso]", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+	cookie, err := auth.SignPurposeToken(purposeOIDCState, 0, jwt.MapClaims{
+		"prv": p.ID(),
+		"st":  state,
+		"nce": nonce,
+		"pkv": verifier,
+	}, config.OIDC.StateTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    cookie,
+		Path:     "/api/oidc/" + p.ID(),
+		MaxAge:   int(config.OIDC.StateTTL / time.Second),
+		Secure:   config.Cookie.Secure,
+		HttpOnly: true,
+		// Lax is required: the IdP sends the browser back with a top-level GET.
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, target)
+}
+
+func (h *SSOHandler) Callback(c *gin.Context) {
+	p, err := h.Providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+	raw, _ := c.Cookie(oidcStateCookie)
+	http.SetCookie(c.Writer, &http.Cookie{Name: oidcStateCookie, Path: "/api/oidc/" + p.ID(), MaxAge: -1})
+
+	if e := c.Query("error"); e != "" {
+		h.finish(c, url.Values{"error": {e}})
+		return
+	}
+	claims := jwt.MapClaims{}
+	if _, err := auth.ParseToken(raw, claims); err != nil || claims["pur"] != purposeOIDCState ||
+		claims["prv"] != p.ID() || claims["st"] != c.Query("state") || c.Query("state") == "" {
+		h.finish(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+	verifier, _ := claims["pkv"].(string)
+	nonce, _ := claims["nce"].(string)
+
+	id, err := p.Exchange(c.Request.Context(), c.Query("code"), verifier, nonce)
+	if err != nil {
+		log.Println("[sso]", err)
+		h.finish(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+	u, err := h.resolveUser(id)
+	if err != nil {
+		log.Printf("[sso] resolve %s/%s: %v", id.Provider, id.Subject, err)
+		h.finish(c, url.Values{"error": {"account_unavailable"}})
+		return
+	}
+
+	mfaEnabled, err := h.Auth.MFA.IsEnabled(u.ID)
+	if err != nil {
+		h.finish(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+	if mfaEnabled {
+		token, err := auth.SignPurposeToken(purposeMFALogin, u.ID, nil, config.MFA.LoginTokenTTL)
+		if err != nil {
+			h.finish(c, url.Values{"error": {"login_failed"}})
+			return
+		}
+		h.finish(c, url.Values{"mfa_required": {"true"}, "mfa_token": {token}})
+		return
+	}
+
+	t, err := h.Auth.createSession(c, u)
+	if err != nil {
+		log.Printf("[sso] create session for user %d: %v", u.ID, err)
+		h.finish(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+	params := url.Values{
+		"expires_in": {strconv.Itoa(int(t.AccessTTL / time.Second))},
+		"session_id": {t.SessionID},
+	}
+	if config.TokenInCookie {
+		params.Set("csrf_token", setTokenCookies(c, t))
+	} else {
+		params.Set("token", t.Access)
+		params.Set("refresh_token", t.Refresh)
+	}
+	h.finish(c, params)
+}
+
+// resolveUser maps an external identity to a Planify user: an existing link
+// wins, then a user with the same verified email is linked, and finally a new
+// account is provisioned when that is allowed.
+func (h *SSOHandler) resolveUser(id *sso.Identity) (*model.User, error) {
+	users := h.Auth.UserRepo
+	u, err := users.GetByIdentity(id.Provider, id.Subject)
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if id.Email == "" || !id.EmailVerified {
+		return nil, errors.New("identity has no verified email")
+	}
+	email := strings.ToLower(id.Email)
+	at := strings.Index(email, "@")
+	if at < 1 {
+		return nil, errors.New("identity email is not an address")
+	}
+
+	u, err = users.GetUserByEmail(email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if !config.OIDC.AutoProvision {
+			return nil, errors.New("no matching account and auto-provisioning is off")
+		}
+		name := id.Name
+		if name == "" {
+			name = email[:at]
+		}
+		if u, err = users.CreateExternal(name, email); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !u.EmailVerified:
+		// Anyone can register an address they do not own and wait for its
+		// owner to arrive through SSO, so only proven accounts are linked.
+		return nil, errors.New("matching account has not verified its email")
+	}
+	if err := users.LinkIdentity(u.ID, id.Provider, id.Subject, email); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// finish sends the browser back to the frontend. The frontend uses hash
+// routing, so results go into the query part of the fragment, which never
+// reaches server logs or Referer headers.
+func (h *SSOHandler) finish(c *gin.Context, params url.Values) {
+	target := config.OIDC.PostLoginRedirect
+	if target == "" {
+		target = config.PublicURL + "/#/sso/callback"
+	}
+	sep := "?"
+	if strings.Contains(target, "?") {
+		sep = "&"
+	}
+	if !strings.Contains(target, "#") {
+		sep = "#"
+	}
+	c.Redirect(http.StatusFound, target+sep+params.Encode())
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+// StatusHandler manages a project's board columns. Routes sit under
+// /projects/:id, so ProjectMember has already set projectID.
+type StatusHandler struct {
+	Repo repository.StatusStore
+}
+
+func (h *StatusHandler) List(c *gin.Context) {
+	statuses, err := h.Repo.List(c.GetInt("projectID"), c.Query("archived") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statuses"})
+		return
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+func (h *StatusHandler) Create(c *gin.Context) {
+	var payload repository.CreateStatusPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || strings.TrimSpace(payload.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if payload.Category != "" && !payload.Category.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status category"})
+		return
+	}
+	var mode *model.WIPMode
+	if payload.WIPMode != "" {
+		mode = &payload.WIPMode
+	}
+	if !validWIP(c, payload.WIPLimit, mode) {
+		return
+	}
+	status, err := h.Repo.Create(c.GetInt("projectID"), payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status"})
+		return
+	}
+	c.JSON(http.StatusCreated, status)
+}
+
+func (h *StatusHandler) Update(c *gin.Context) {
+	statusID, err := strconv.Atoi(c.Param("statusId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status ID"})
+		return
+	}
+	var payload repository.UpdateStatusPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if payload.Title != nil && strings.TrimSpace(*payload.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status title cannot be empty"})
+		return
+	}
+	if payload.Category != nil && !payload.Category.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status category"})
+		return
+	}
+	if !validWIP(c, payload.WIPLimit, payload.WIPMode) {
+		return
+	}
+	status, err := h.Repo.Update(c.GetInt("projectID"), statusID, payload)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Status not found"})
+	case errors.Is(err, repository.ErrStatusNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": "Move the tasks out of this status before archiving it"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+	default:
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+func (h *StatusHandler) Reorder(c *gin.Context) {
+	var body struct {
+		StatusIDs []int `json:"statusIds" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	projectID := c.GetInt("projectID")
+	if err := h.Repo.Reorder(projectID, body.StatusIDs); err != nil {
+		if errors.Is(err, repository.ErrStatusOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "statusIds must list every status of the project once"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder statuses"})
+		return
+	}
+	statuses, err := h.Repo.List(projectID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statuses"})
+		return
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+// statusError maps the status validation errors shared by task creation and
+// moves.
+func statusError(c *gin.Context, err error) bool {
+	if errors.Is(err, repository.ErrInvalidStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status does not belong to this project or is archived"})
+		return true
+	}
+	return false
+}
+
+// wipError reports a hard WIP limit refusing a task.
+func wipError(c *gin.Context, err error) bool {
+	var full *repository.WIPLimitError
+	if !errors.As(err, &full) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":      "The column is at its work-in-progress limit",
+		"code":       "wip_limit_exceeded",
+		"violations": full.Violations,
+	})
+	return true
+}
+
+// transitionError reports the workflow rules refusing a task.
+func transitionError(c *gin.Context, err error, message string) bool {
+	var blocked *repository.TransitionError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error": message,
+		"code":  "transition_blocked",
+		"unmet": blocked.Unmet,
+	})
+	return true
+}
+
+// validWIP checks the WIP settings shared by the create and update payloads.
+func validWIP(c *gin.Context, limit *int, mode *model.WIPMode) bool {
+	if limit != nil && *limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "WIP limit cannot be negative"})
+		return false
+	}
+	if mode != nil && !mode.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "WIP mode must be soft or hard"})
+		return false
+	}
+	return true
+}
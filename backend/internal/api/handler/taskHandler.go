@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -8,11 +10,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
 	"planify/backend/internal/repository"
 )
 
 type TaskHandler struct {
-	Repo *repository.TaskRepository
+	Repo repository.TaskStore
 }
 
 func (h *TaskHandler) UpdateTaskPosition(c *gin.Context) {
@@ -26,11 +30,34 @@ func (h *TaskHandler) UpdateTaskPosition(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
-	if err := h.Repo.UpdatePosition(taskID, payload); err != nil {
+	var ok bool
+	if payload.Version, ok = ifMatch(c); !ok {
+		return
+	}
+	role := model.Role(c.GetString("projectRole"))
+	warnings, err := h.Repo.UpdatePosition(taskID, payload, c.GetInt("userID"), role)
+	if err != nil {
+		if statusError(c, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, taskID)
+			return
+		}
+		if transitionError(c, err, "This move does not meet the project's workflow rules") || wipError(c, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidPlacement) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "afterTaskId and beforeTaskId must be tasks in the target column, in order"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task position"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Task position updated successfully"})
+	if warnings == nil {
+		warnings = []model.WIPViolation{}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Task position updated successfully", "warnings": warnings})
 }
 
 func (h *TaskHandler) GetTaskByID(c *gin.Context) {
@@ -44,6 +71,7 @@ func (h *TaskHandler) GetTaskByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
@@ -53,22 +81,31 @@ func (h *TaskHandler) UpdateTaskFields(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
 		return
 	}
-	var payload struct {
-		Title       *string `json:"title"`
-		Description *string `json:"description"`
-		DueDate     *string `json:"dueDate"`
-		Priority    *string `json:"priority"`
-	}
+	var payload repository.UpdateTaskFieldsPayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
-	if err := h.Repo.UpdateFields(taskID, payload.Title, payload.Description, payload.DueDate, payload.Priority); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task fields"})
+	if payload.DueDate != nil && !model.Role(c.GetString("projectRole")).Can(model.CapChangeDueDate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to change the due date"})
 		return
 	}
-	td, _ := h.Repo.GetByID(taskID)
-	c.JSON(http.StatusOK, td)
+	var ok bool
+	if payload.Version, ok = ifMatch(c); !ok {
+		return
+	}
+	if err := h.Repo.UpdateFields(taskID, payload); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrVersionConflict):
+			h.conflict(c, taskID)
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task fields"})
+		}
+		return
+	}
+	h.respond(c, taskID)
 }
 
 func (h *TaskHandler) UploadAttachment(c *gin.Context) {
@@ -77,14 +114,13 @@ func (h *TaskHandler) UploadAttachment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
 		return
 	}
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+	file, ok := formFile(c, config.Uploads.MaxAttachmentSize)
+	if !ok {
 		return
 	}
-	_ = os.MkdirAll("uploads", 0o755)
+	_ = os.MkdirAll(config.Uploads.Dir, 0o755)
 	stored := strconv.FormatInt(time.Now().UnixNano(), 10) + "_" + filepath.Base(file.Filename)
-	if err := c.SaveUploadedFile(file, filepath.Join("uploads", stored)); err != nil {
+	if err := c.SaveUploadedFile(file, filepath.Join(config.Uploads.Dir, stored)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 		return
 	}
@@ -123,12 +159,22 @@ func (h *TaskHandler) AddAssigneeByQuery(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
 		return
 	}
-	if err := h.Repo.AddAssigneeByQuery(taskID, body.Query); err != nil {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.AddAssigneeByQuery(taskID, body.Query, version); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, taskID)
+			return
+		}
+		if wipError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
 		return
 	}
-	td, _ := h.Repo.GetByID(taskID)
-	c.JSON(http.StatusOK, td)
+	h.respond(c, taskID)
 }
 
 func (h *TaskHandler) AddCollaboratorByQuery(c *gin.Context) {
@@ -144,12 +190,19 @@ func (h *TaskHandler) AddCollaboratorByQuery(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
 		return
 	}
-	if err := h.Repo.AddCollaboratorByQuery(taskID, body.Query); err != nil {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.AddCollaboratorByQuery(taskID, body.Query, version); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, taskID)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
 		return
 	}
-	td, _ := h.Repo.GetByID(taskID)
-	c.JSON(http.StatusOK, td)
+	h.respond(c, taskID)
 }
 
 func (h *TaskHandler) AddComment(c *gin.Context) {
@@ -159,14 +212,14 @@ func (h *TaskHandler) AddComment(c *gin.Context) {
 		return
 	}
 	var body struct {
-		AuthorID *int   `json:"authorId"`
-		Text     string `json:"text"`
+		Text string `json:"text"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil || body.Text == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
 		return
 	}
-	if err := h.Repo.AddComment(taskID, body.AuthorID, body.Text); err != nil {
+	authorID := c.GetInt("userID")
+	if err := h.Repo.AddComment(taskID, &authorID, body.Text); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
 		return
 	}
@@ -202,10 +255,39 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
 		return
 	}
-	id, pos, err := h.Repo.CreateTask(projectID, b.StatusId, b.Title)
+	id, pos, warnings, err := h.Repo.CreateTask(projectID, b.StatusId, b.Title, model.Role(c.GetString("projectRole")))
 	if err != nil {
+		if statusError(c, err) || wipError(c, err) ||
+			transitionError(c, err, "Tasks cannot be created in this status under the project's workflow rules") {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"id": id, "title": b.Title, "position": pos, "statusId": b.StatusId})
-}
\ No newline at end of file
+	if warnings == nil {
+		warnings = []model.WIPViolation{}
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": id, "title": b.Title, "position": pos, "statusId": b.StatusId, "warnings": warnings})
+}
+
+// respond sends the task after an edit, with its new ETag.
+func (h *TaskHandler) respond(c *gin.Context, taskID int) {
+	td, err := h.Repo.GetByID(taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+	setETag(c, td.Version)
+	c.JSON(http.StatusOK, td)
+}
+
+// conflict answers a failed If-Match with the task as it is now.
+func (h *TaskHandler) conflict(c *gin.Context, taskID int) {
+	td, err := h.Repo.GetByID(taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+	setETag(c, td.Version)
+	versionConflict(c, td)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+// TransitionHandler manages the rules for moving tasks between a project's
+// statuses. While a project has no rules every move is allowed.
+type TransitionHandler struct {
+	Repo repository.TransitionStore
+}
+
+func (h *TransitionHandler) List(c *gin.Context) {
+	rules, err := h.Repo.List(c.GetInt("projectID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transitions"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *TransitionHandler) Create(c *gin.Context) {
+	var body struct {
+		FromStatusID   *int              `json:"fromStatusId"`
+		ToStatusID     int               `json:"toStatusId" binding:"required"`
+		Roles          []model.Role      `json:"roles"`
+		RequiredFields []model.TaskField `json:"requiredFields"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if body.FromStatusID != nil && *body.FromStatusID == body.ToStatusID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A transition must lead to a different status"})
+		return
+	}
+	t := model.Transition{
+		ProjectID:      c.GetInt("projectID"),
+		FromStatusID:   body.FromStatusID,
+		ToStatusID:     body.ToStatusID,
+		Roles:          []model.Role{},
+		RequiredFields: []model.TaskField{},
+	}
+	for _, role := range body.Roles {
+		if !role.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role: " + string(role)})
+			return
+		}
+		t.Roles = append(t.Roles, role)
+	}
+	for _, f := range body.RequiredFields {
+		if !f.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid required field: " + string(f)})
+			return
+		}
+		t.RequiredFields = append(t.RequiredFields, f)
+	}
+	err := h.Repo.Create(&t)
+	switch {
+	case statusError(c, err):
+	case errors.Is(err, repository.ErrTransitionExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "A transition between these statuses already exists"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transition"})
+	default:
+		c.JSON(http.StatusCreated, t)
+	}
+}
+
+func (h *TransitionHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("transitionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transition ID"})
+		return
+	}
+	err = h.Repo.Delete(c.GetInt("projectID"), id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transition not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transition"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
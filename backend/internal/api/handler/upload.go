@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formFile reads the "file" field and rejects files over limit bytes. The
+// body is capped slightly above the limit to leave room for the multipart
+// framing.
+func formFile(c *gin.Context, limit int64) (*multipart.FileHeader, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is larger than " + strconv.FormatInt(limit>>20, 10) + " MB"})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+		return nil, false
+	}
+	if file.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is larger than " + strconv.FormatInt(limit>>20, 10) + " MB"})
+		return nil, false
+	}
+	return file, true
+}
@@ -1,18 +1,27 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"planify/backend/internal/config"
+	"planify/backend/internal/auth"
+	"planify/backend/internal/lockout"
 	"planify/backend/internal/repository"
 )
 
 type UserHandler struct {
-	Repo *repository.UserRepository
+	Repo     repository.UserStore
+	Sessions repository.SessionStore
+	Lockout  *lockout.Guard
+	Events   repository.AuthEventStore
 }
 
 func absoluteOrDefault(host, url string) string {
@@ -120,6 +129,10 @@ func (h *UserHandler) PatchMe(c *gin.Context) {
 		return
 	}
 	u, err := h.Repo.UpdateProfile(uid.(int), b.Name, b.Email)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update"})
 		return
@@ -140,15 +153,14 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	}
 	uid := uidV.(int)
 
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+	file, ok := formFile(c, config.Uploads.MaxAvatarSize)
+	if !ok {
 		return
 	}
 
-	_ = os.MkdirAll("uploads", 0o755)
+	_ = os.MkdirAll(config.Uploads.Dir, 0o755)
 	stored := strconv.FormatInt(time.Now().UnixNano(), 10) + "_" + filepath.Base(file.Filename)
-	if err := c.SaveUploadedFile(file, filepath.Join("uploads", stored)); err != nil {
+	if err := c.SaveUploadedFile(file, filepath.Join(config.Uploads.Dir, stored)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save"})
 		return
 	}
@@ -169,28 +181,62 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 	var b struct {
-		NewPassword string `json:"newPassword"`
-		Password    string `json:"password"`
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
 	}
 	if err := c.ShouldBindJSON(&b); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
 		return
 	}
-	pw := b.Password
-	if pw == "" {
-		pw = b.NewPassword
-	}
-	if pw == "" {
+	if b.CurrentPassword == "" || b.NewPassword == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing password"})
 		return
 	}
-	if err := h.Repo.UpdatePasswordHash(uid.(int), pw); err != nil {
+	u, err := h.Repo.GetByID(uid.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	login := strings.ToLower(u.Email)
+	if lockedOut(c, h.Lockout, login) {
+		return
+	}
+	if match, _, _ := auth.VerifyPassword(u.Password, b.CurrentPassword); !match {
+		recordLoginFailure(c, h.Lockout, h.Events, login)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if err := h.Lockout.Clear(lockout.AccountKey(login)); err != nil {
+		log.Println("[auth] clear lockout:", err)
+	}
+	if err := auth.CheckPasswordPolicy(b.NewPassword); err != nil {
+		writePolicyError(c, err)
+		return
+	}
+	hash, err := auth.HashPassword(b.NewPassword)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
 		return
 	}
+	if err := h.Repo.UpdatePasswordHash(u.ID, hash); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if err := h.Sessions.RevokeAllForUser(u.ID, c.GetString("sessionID")); err != nil {
+		log.Printf("[auth] revoke sessions after password change for user %d: %v", u.ID, err)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "ok"})
 }
 
+func writePolicyError(c *gin.Context, err error) {
+	var pe *auth.PolicyError
+	if errors.As(err, &pe) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password does not meet requirements", "details": pe.Problems})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
 func (h *UserHandler) GetMySummary(c *gin.Context) {
 	uid, ok := c.Get("userID")
 	if !ok {
@@ -226,7 +272,7 @@ func (h *UserHandler) GetUserSummary(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
-	s, err := h.Repo.GetSummary(id)
+	s, err := h.Repo.GetSharedSummary(id, profileViewer(c, id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch summary"})
 		return
@@ -241,10 +287,20 @@ func (h *UserHandler) GetUserProjects(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
-	list, err := h.Repo.GetMyProjects(id)
+	list, err := h.Repo.GetSharedProjects(id, profileViewer(c, id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
 		return
 	}
 	c.JSON(http.StatusOK, list)
-}
\ No newline at end of file
+}
+
+// profileViewer is who another user's profile is shown to: 0 when users look at
+// their own, so nothing is hidden, or the caller, who only sees the projects
+// they share with that user.
+func profileViewer(c *gin.Context, userID int) int {
+	if uid := c.GetInt("userID"); uid != userID {
+		return uid
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/repository"
+)
+
+// RequireAdmin limits a route to instance administrators (users.is_admin).
+func RequireAdmin(users repository.UserStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, err := users.IsAdmin(c.GetInt("userID"))
+		if err != nil {
+			log.Println("[auth] lookup admin flag:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authorize request"})
+			return
+		}
+		if !admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+			return
+		}
+		c.Next()
+	}
+}
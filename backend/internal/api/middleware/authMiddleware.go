@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -9,35 +11,47 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 
+	"planify/backend/internal/auth"
 	"planify/backend/internal/config"
+	"planify/backend/internal/repository"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(sessions repository.SessionStore, tokens repository.AccessTokenStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		h := c.GetHeader("Authorization")
-		if strings.TrimSpace(h) == "" {
-		log.Printf("[auth] missing Authorization header (path=%s %s)", c.Request.Method, c.Request.URL.Path)
-		c.JSON(http.StatusUnauthorized, gin.H{"error":"Authorization header is required"})
-		c.Abort()
-		return
-    }
-		parts := strings.Fields(h)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			log.Println("[auth] bad Authorization header:", h)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
-			return
+		tokenStr, fromCookie := cookieToken(c)
+		if !fromCookie {
+			h := c.GetHeader("Authorization")
+			if strings.TrimSpace(h) == "" {
+				log.Printf("[auth] missing Authorization header (path=%s %s)", c.Request.Method, c.Request.URL.Path)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+				c.Abort()
+				return
+			}
+			parts := strings.Fields(h)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+				log.Println("[auth] bad Authorization header:", h)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
+				return
+			}
+			tokenStr = parts[1]
+			if strings.HasPrefix(tokenStr, config.AccessTokens.Prefix) {
+				authenticateAccessToken(c, tokens, tokenStr)
+				return
+			}
 		}
-		tokenStr := parts[1]
 
 		claims := jwt.MapClaims{}
-		tok, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			return config.JwtKey, nil
-		})
+		tok, err := auth.ParseToken(tokenStr, claims)
 		if err != nil || !tok.Valid {
 			log.Println("[auth] invalid token:", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
+		if _, ok := claims["pur"]; ok {
+			log.Println("[auth] purpose token used as access token")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
 		if exp, ok := claims["exp"].(float64); ok && time.Now().Unix() > int64(exp) {
 			log.Println("[auth] expired token")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
@@ -55,7 +69,65 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
+		sid, _ := claims["sid"].(string)
+		if sid == "" {
+			log.Println("[auth] no sid in claims:", claims)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		if err := sessions.Validate(sid, uid, c.ClientIP()); err != nil {
+			log.Println("[auth] session check failed:", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+			return
+		}
+		if fromCookie && !csrfSafeMethod(c.Request.Method) && !validCSRF(c, sid) {
+			log.Printf("[auth] CSRF check failed (path=%s %s)", c.Request.Method, c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
+			return
+		}
 		c.Set("userID", uid)
+		c.Set("sessionID", sid)
 		c.Next()
 	}
+}
+
+// authenticateAccessToken handles personal access tokens. They carry no
+// session, so "sessionID" stays unset and RequireScope limits what they reach.
+func authenticateAccessToken(c *gin.Context, tokens repository.AccessTokenStore, raw string) {
+	t, err := tokens.Authenticate(auth.HashToken(raw))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println("[auth] access token lookup failed:", err)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+	c.Set("userID", t.UserID)
+	c.Set("accessToken", t)
+	c.Next()
+}
+
+// cookieToken returns the access token from the auth cookie when cookie mode
+// is on. An explicit Authorization header still wins so API clients keep
+// working, and such requests need no CSRF token because browsers never
+// attach that header on their own.
+func cookieToken(c *gin.Context) (string, bool) {
+	if !config.TokenInCookie || c.GetHeader("Authorization") != "" {
+		return "", false
+	}
+	v, err := c.Cookie(config.Cookie.AccessName)
+	if err != nil || v == "" {
+		return "", false
+	}
+	return v, true
+}
+
+func csrfSafeMethod(m string) bool {
+	return m == http.MethodGet || m == http.MethodHead || m == http.MethodOptions
+}
+
+func validCSRF(c *gin.Context, sid string) bool {
+	header := c.GetHeader(config.Cookie.CSRFHeader)
+	cookie, _ := c.Cookie(config.Cookie.CSRFName)
+	return header != "" && header == cookie && auth.ValidCSRFToken(sid, header)
 }
\ No newline at end of file
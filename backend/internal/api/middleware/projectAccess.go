@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+// ProjectMember resolves the project from the :id route parameter and only
+// lets members of that project through. Non-members get the same 404 as a
+// missing project so project IDs cannot be probed.
+func ProjectMember(projects repository.ProjectStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+			return
+		}
+		authorizeMember(c, projects, projectID, "Project not found")
+	}
+}
+
+// TaskMember resolves the project owning the task in the :id route parameter
+// and applies the same membership check as ProjectMember.
+func TaskMember(projects repository.ProjectStore, tasks repository.TaskStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+			return
+		}
+		projectID, err := tasks.GetProjectID(taskID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+				return
+			}
+			log.Println("[authz] resolve task project:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authorize request"})
+			return
+		}
+		authorizeMember(c, projects, projectID, "Task not found")
+	}
+}
+
+// RequireCapability must run after ProjectMember or TaskMember and rejects
+// members whose role lacks the capability with 403.
+func RequireCapability(capability model.Capability) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := model.Role(c.GetString("projectRole"))
+		if !role.Can(capability) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func authorizeMember(c *gin.Context, projects repository.ProjectStore, projectID int, notFound string) {
+	uid := c.GetInt("userID")
+	access, err := projects.GetMemberAccess(projectID, uid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": notFound})
+			return
+		}
+		log.Println("[authz] lookup member role:", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authorize request"})
+		return
+	}
+	if (access.Require2FA || config.MFA.RequireForAll) && !access.HasMFA {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Two-factor authentication is required for this project",
+			"code":  "mfa_enrollment_required",
+		})
+		return
+	}
+	c.Set("projectID", projectID)
+	c.Set("projectRole", string(access.Role))
+	c.Set("hasMFA", access.HasMFA)
+	c.Next()
+}
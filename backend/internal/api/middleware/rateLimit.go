@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/config"
+	"planify/backend/internal/ratelimit"
+)
+
+// RateLimitHeaders lists the response headers set by RateLimit, for CORS.
+var RateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"}
+
+// RateLimit applies the token bucket configured for group. Requests are
+// keyed by the authenticated user when AuthMiddleware ran first and by
+// client IP otherwise. If the store fails the request is let through.
+func RateLimit(store ratelimit.BucketStore, group string) gin.HandlerFunc {
+	cfg, ok := config.RateLimit.Rules[group]
+	if !config.RateLimit.Enabled || !ok || cfg.Requests <= 0 || cfg.Burst <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	rule := ratelimit.RuleFromConfig(cfg)
+	policy := strconv.Itoa(cfg.Burst) + ";w=" + strconv.Itoa(ceilSeconds(time.Duration(float64(cfg.Burst)/rule.Rate*float64(time.Second))))
+
+	return func(c *gin.Context) {
+		key := group + ":ip:" + c.ClientIP()
+		if uid := c.GetInt("userID"); uid != 0 {
+			key = group + ":u:" + strconv.Itoa(uid)
+		}
+		res, err := store.Take(c.Request.Context(), key, rule)
+		if err != nil {
+			log.Printf("[ratelimit] %s: %v", group, err)
+			c.Next()
+			return
+		}
+		h := c.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		h.Set("RateLimit-Policy", policy)
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, slow down"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
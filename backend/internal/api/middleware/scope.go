@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/model"
+)
+
+// RequireScope limits personal access tokens to routes their scopes cover.
+// Session-authenticated requests are not affected.
+func RequireScope(scope model.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get("accessToken")
+		if !ok || v.(*model.AccessToken).HasScope(scope) {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Access token is missing the required scope",
+			"scope": scope,
+		})
+	}
+}
+
+// SessionOnly rejects personal access tokens outright. Used for account
+// security routes such as password, 2FA, sessions and token management.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("accessToken"); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This action requires signing in"})
+			return
+		}
+		c.Next()
+	}
+}
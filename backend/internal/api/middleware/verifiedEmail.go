@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"planify/backend/internal/repository"
+)
+
+// RequireVerifiedEmail lets unverified accounts read but blocks every
+// state-changing request until the address has been confirmed.
+func RequireVerifiedEmail(users repository.UserStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		verified, err := users.IsEmailVerified(c.GetInt("userID"))
+		if err != nil {
+			log.Println("[auth] lookup email verification:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authorize request"})
+			return
+		}
+		if !verified {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Please verify your email address first"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrUnknownUser lets a Chain move on to the next backend.
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, login, password string) (*model.User, error)
+}
+
+// Chain tries each authenticator in order. A backend that does not know the
+// user passes to the next one; a backend that knows the user but rejects the
+// password ends the chain.
+type Chain []Authenticator
+
+func (ch Chain) Name() string { return "chain" }
+
+func (ch Chain) Authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	for _, a := range ch {
+		u, err := a.Authenticate(ctx, login, password)
+		if errors.Is(err, ErrUnknownUser) {
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+			log.Printf("[auth] %s backend: %v", a.Name(), err)
+		}
+		return u, err
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// NewChain builds the authenticators named in config.AuthBackends.
+func NewChain(users repository.UserStore, projects repository.ProjectStore) (Chain, error) {
+	var ch Chain
+	for _, name := range config.AuthBackends {
+		switch name {
+		case "local":
+			ch = append(ch, &PasswordAuthenticator{Users: users})
+		case "ldap":
+			ch = append(ch, &LDAPAuthenticator{Config: config.LDAP, Users: users, Projects: projects})
+		default:
+			return nil, fmt.Errorf("auth: unknown backend %q", name)
+		}
+	}
+	if len(ch) == 0 {
+		return nil, errors.New("auth: no authentication backends configured")
+	}
+	return ch, nil
+}
+
+// PasswordAuthenticator checks the password stored in users and upgrades
+// plaintext or outdated hashes after a successful match.
+type PasswordAuthenticator struct {
+	Users repository.UserStore
+}
+
+func (a *PasswordAuthenticator) Name() string { return "local" }
+
+func (a *PasswordAuthenticator) Authenticate(_ context.Context, login, password string) (*model.User, error) {
+	u, err := a.Users.GetUserByEmail(login)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, err
+	}
+	// SSO-only accounts have no password and belong to other backends.
+	if u.Password == "" {
+		return nil, ErrUnknownUser
+	}
+	ok, needsRehash, err := VerifyPassword(u.Password, password)
+	if err != nil {
+		log.Printf("[auth] verify password for user %d: %v", u.ID, err)
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if needsRehash {
+		if hash, err := HashPassword(password); err != nil {
+			log.Printf("[auth] rehash password for user %d: %v", u.ID, err)
+		} else if err := a.Users.UpdatePasswordHash(u.ID, hash); err != nil {
+			log.Printf("[auth] store rehashed password for user %d: %v", u.ID, err)
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"planify/backend/internal/config"
+)
+
+// CSRFToken derives the double-submit token for a session. Binding it to the
+// session ID means a cookie planted by a sibling subdomain is useless without
+// also knowing the server secret.
+func CSRFToken(sessionID string) string {
+	mac := hmac.New(sha256.New, config.JwtKey)
+	mac.Write([]byte("csrf:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func ValidCSRFToken(sessionID, token string) bool {
+	return hmac.Equal([]byte(CSRFToken(sessionID)), []byte(token))
+}
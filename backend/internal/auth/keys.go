@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"planify/backend/internal/config"
+)
+
+type signingKey struct {
+	id          string
+	method      jwt.SigningMethod
+	signKey     interface{}
+	verifyKey   interface{}
+	verifyUntil time.Time
+}
+
+// KeyRing holds every key that may verify Planify tokens and the single
+// active key used to sign new ones.
+type KeyRing struct {
+	issuer   string
+	audience string
+	active   *signingKey
+	keys     map[string]*signingKey
+}
+
+var (
+	ringMu sync.RWMutex
+	ring   *KeyRing
+)
+
+// LoadKeys builds the key ring from config.JWT and installs it for the
+// package-level signing and parsing helpers.
+func LoadKeys(cfg config.JWTConfig) error {
+	kr, err := NewKeyRing(cfg)
+	if err != nil {
+		return err
+	}
+	ringMu.Lock()
+	ring = kr
+	ringMu.Unlock()
+	return nil
+}
+
+func Keys() *KeyRing {
+	ringMu.RLock()
+	kr := ring
+	ringMu.RUnlock()
+	if kr != nil {
+		return kr
+	}
+	if err := LoadKeys(config.JWT); err != nil {
+		panic(err)
+	}
+	return Keys()
+}
+
+func NewKeyRing(cfg config.JWTConfig) (*KeyRing, error) {
+	kr := &KeyRing{issuer: cfg.Issuer, audience: cfg.Audience, keys: map[string]*signingKey{}}
+	for _, kc := range cfg.Keys {
+		if kc.ID == "" {
+			return nil, errors.New("jwt: key without id")
+		}
+		if _, dup := kr.keys[kc.ID]; dup {
+			return nil, fmt.Errorf("jwt: duplicate key id %q", kc.ID)
+		}
+		k, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q: %w", kc.ID, err)
+		}
+		kr.keys[kc.ID] = k
+	}
+	active, ok := kr.keys[cfg.ActiveKeyID]
+	if !ok {
+		return nil, fmt.Errorf("jwt: active key %q is not configured", cfg.ActiveKeyID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("jwt: active key %q has no private key", cfg.ActiveKeyID)
+	}
+	kr.active = active
+	return kr, nil
+}
+
+func loadKey(kc config.SigningKey) (*signingKey, error) {
+	k := &signingKey{id: kc.ID, verifyUntil: kc.VerifyUntil}
+	switch kc.Algorithm {
+	case "HS256":
+		if kc.Secret == "" {
+			kc.Secret = string(config.JwtKey)
+		}
+		if len(kc.Secret) < 16 {
+			return nil, errors.New("HS256 secret must be at least 16 bytes")
+		}
+		k.method = jwt.SigningMethodHS256
+		k.signKey = []byte(kc.Secret)
+		k.verifyKey = k.signKey
+		return k, nil
+	case "RS256":
+		k.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+
+	priv, err := pemSource(kc.PrivateKeyPEM, kc.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := pemSource(kc.PublicKeyPEM, kc.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if priv == nil && pub == nil {
+		return nil, errors.New("missing key material")
+	}
+
+	if k.method == jwt.SigningMethodRS256 {
+		if priv != nil {
+			pk, err := jwt.ParseRSAPrivateKeyFromPEM(priv)
+			if err != nil {
+				return nil, err
+			}
+			k.signKey, k.verifyKey = pk, &pk.PublicKey
+		} else {
+			if k.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(pub); err != nil {
+				return nil, err
+			}
+		}
+		return k, nil
+	}
+	if priv != nil {
+		pk, err := jwt.ParseEdPrivateKeyFromPEM(priv)
+		if err != nil {
+			return nil, err
+		}
+		edk := pk.(ed25519.PrivateKey)
+		k.signKey, k.verifyKey = edk, edk.Public()
+	} else {
+		if k.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(pub); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func pemSource(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// Sign stamps iss, aud and the active kid onto claims and signs them.
+func (kr *KeyRing) Sign(claims jwt.MapClaims) (string, error) {
+	claims["iss"] = kr.issuer
+	claims["aud"] = kr.audience
+	tok := jwt.NewWithClaims(kr.active.method, claims)
+	tok.Header["kid"] = kr.active.id
+	return tok.SignedString(kr.active.signKey)
+}
+
+// Parse verifies a token against the ring. Only the algorithms present in
+// the ring are accepted, and a token's alg must match the alg of the key
+// named by its kid.
+func (kr *KeyRing) Parse(tokenStr string, claims jwt.MapClaims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, kr.keyFunc,
+		jwt.WithValidMethods(kr.algorithms()),
+		jwt.WithIssuer(kr.issuer),
+		jwt.WithAudience(kr.audience),
+		jwt.WithExpirationRequired(),
+	)
+}
+
+func (kr *KeyRing) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := kr.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("alg %s does not match key %q", t.Method.Alg(), kid)
+	}
+	if !k.verifyUntil.IsZero() && time.Now().After(k.verifyUntil) {
+		return nil, fmt.Errorf("key %q is retired", kid)
+	}
+	return k.verifyKey, nil
+}
+
+func (kr *KeyRing) algorithms() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, k := range kr.keys {
+		if alg := k.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			out = append(out, alg)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// JWKS returns the public half of every asymmetric key that still verifies
+// tokens. HMAC secrets are never published.
+func (kr *KeyRing) JWKS() map[string]interface{} {
+	ids := make([]string, 0, len(kr.keys))
+	for id := range kr.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	b64 := base64.RawURLEncoding
+	keys := []map[string]interface{}{}
+	now := time.Now()
+	for _, id := range ids {
+		k := kr.keys[id]
+		if !k.verifyUntil.IsZero() && now.After(k.verifyUntil) {
+			continue
+		}
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "RSA", "use": "sig", "alg": k.method.Alg(), "kid": id,
+				"n": b64.EncodeToString(pub.N.Bytes()),
+				"e": b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "OKP", "crv": "Ed25519", "use": "sig", "alg": k.method.Alg(), "kid": id,
+				"x": b64.EncodeToString(pub),
+			})
+		}
+	}
+	return map[string]interface{}{"keys": keys}
+}
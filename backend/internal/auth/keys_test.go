@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"planify/backend/internal/config"
+)
+
+func rsaPEM(t *testing.T) (priv, pub string, key *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	pub = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return priv, pub, key
+}
+
+func edPEM(t *testing.T) (priv string, key ed25519.PrivateKey) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), key
+}
+
+func newRing(t *testing.T, cfg config.JWTConfig) *KeyRing {
+	t.Helper()
+	if cfg.Issuer == "" {
+		cfg.Issuer, cfg.Audience = "planify", "planify-api"
+	}
+	kr, err := NewKeyRing(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"uid": 7, "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeyRingSignsAndParses(t *testing.T) {
+	rsaPriv, _, _ := rsaPEM(t)
+	edPriv, _ := edPEM(t)
+	for _, tc := range []struct {
+		alg string
+		key config.SigningKey
+	}{
+		{"HS256", config.SigningKey{ID: "h", Algorithm: "HS256", Secret: "0123456789abcdef"}},
+		{"RS256", config.SigningKey{ID: "r", Algorithm: "RS256", PrivateKeyPEM: rsaPriv}},
+		{"EdDSA", config.SigningKey{ID: "e", Algorithm: "EdDSA", PrivateKeyPEM: edPriv}},
+	} {
+		t.Run(tc.alg, func(t *testing.T) {
+			kr := newRing(t, config.JWTConfig{ActiveKeyID: tc.key.ID, Keys: []config.SigningKey{tc.key}})
+			signed, err := kr.Sign(claims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := jwt.MapClaims{}
+			tok, err := kr.Parse(signed, got)
+			if err != nil || !tok.Valid {
+				t.Fatalf("parse: %v", err)
+			}
+			if tok.Method.Alg() != tc.alg || tok.Header["kid"] != tc.key.ID {
+				t.Fatalf("alg %s kid %v", tok.Method.Alg(), tok.Header["kid"])
+			}
+			if got["iss"] != "planify" || got["aud"] != "planify-api" || got["uid"] != float64(7) {
+				t.Fatalf("claims %v", got)
+			}
+		})
+	}
+}
+
+func TestKeyRingRejectsBadConfig(t *testing.T) {
+	_, rsaPub, _ := rsaPEM(t)
+	for name, cfg := range map[string]config.JWTConfig{
+		"missing id":      {ActiveKeyID: "", Keys: []config.SigningKey{{Algorithm: "HS256", Secret: "0123456789abcdef"}}},
+		"duplicate id":    {ActiveKeyID: "a", Keys: []config.SigningKey{{ID: "a", Algorithm: "HS256", Secret: "0123456789abcdef"}, {ID: "a", Algorithm: "HS256", Secret: "0123456789abcdef"}}},
+		"short secret":    {ActiveKeyID: "a", Keys: []config.SigningKey{{ID: "a", Algorithm: "HS256", Secret: "short"}}},
+		"unknown alg":     {ActiveKeyID: "a", Keys: []config.SigningKey{{ID: "a", Algorithm: "ES256"}}},
+		"no material":     {ActiveKeyID: "a", Keys: []config.SigningKey{{ID: "a", Algorithm: "RS256"}}},
+		"active missing":  {ActiveKeyID: "b", Keys: []config.SigningKey{{ID: "a", Algorithm: "HS256", Secret: "0123456789abcdef"}}},
+		"active pub only": {ActiveKeyID: "a", Keys: []config.SigningKey{{ID: "a", Algorithm: "RS256", PublicKeyPEM: rsaPub}}},
+	} {
+		if _, err := NewKeyRing(cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// After a rotation the old key keeps verifying until VerifyUntil, then its
+// tokens are refused and it drops out of the JWKS.
+func TestKeyRingRotationGraceWindow(t *testing.T) {
+	_, oldPub, oldKey := rsaPEM(t)
+	edPriv, _ := edPEM(t)
+
+	old := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": "planify", "aud": "planify-api", "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	old.Header["kid"] = "2025"
+	oldToken, err := old.SignedString(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := func(verifyUntil time.Time) *KeyRing {
+		return newRing(t, config.JWTConfig{ActiveKeyID: "2026", Keys: []config.SigningKey{
+			{ID: "2025", Algorithm: "RS256", PublicKeyPEM: oldPub, VerifyUntil: verifyUntil},
+			{ID: "2026", Algorithm: "EdDSA", PrivateKeyPEM: edPriv},
+		}})
+	}
+
+	kr := rotated(time.Now().Add(time.Hour))
+	if _, err := kr.Parse(oldToken, jwt.MapClaims{}); err != nil {
+		t.Fatalf("old token inside the grace window: %v", err)
+	}
+	signed, err := kr.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := kr.Parse(signed, jwt.MapClaims{}); err != nil || tok.Header["kid"] != "2026" {
+		t.Fatalf("new token: %v", err)
+	}
+	if kids := jwksKids(kr); kids != "2025,2026" {
+		t.Fatalf("jwks kids %s", kids)
+	}
+
+	kr = rotated(time.Now().Add(-time.Second))
+	if _, err := kr.Parse(oldToken, jwt.MapClaims{}); err == nil || !strings.Contains(err.Error(), "retired") {
+		t.Fatalf("old token after the grace window: %v", err)
+	}
+	if kids := jwksKids(kr); kids != "2026" {
+		t.Fatalf("jwks kids after retirement %s", kids)
+	}
+}
+
+func jwksKids(kr *KeyRing) string {
+	var kids []string
+	for _, k := range kr.JWKS()["keys"].([]map[string]interface{}) {
+		kids = append(kids, k["kid"].(string))
+	}
+	return strings.Join(kids, ",")
+}
+
+func TestKeyRingRejects(t *testing.T) {
+	rsaPriv, rsaPub, rsaKey := rsaPEM(t)
+	kr := newRing(t, config.JWTConfig{ActiveKeyID: "r", Keys: []config.SigningKey{
+		{ID: "r", Algorithm: "RS256", PrivateKeyPEM: rsaPriv},
+		{ID: "h", Algorithm: "HS256", Secret: "0123456789abcdef"},
+	}})
+	base := func() jwt.MapClaims {
+		return jwt.MapClaims{"iss": "planify", "aud": "planify-api", "exp": time.Now().Add(time.Minute).Unix()}
+	}
+	sign := func(method jwt.SigningMethod, kid string, c jwt.MapClaims, key interface{}) string {
+		tok := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	with := func(k string, v interface{}) jwt.MapClaims {
+		c := base()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+	_, otherKey := edPEM(t)
+	otherRSA, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	for name, tok := range map[string]string{
+		// The classic confusion attack: the RSA public key used as an HMAC
+		// secret under the RSA key's kid.
+		"HS256 under an RS256 kid": sign(jwt.SigningMethodHS256, "r", base(), []byte(rsaPub)),
+		"RS256 under an HS256 kid": sign(jwt.SigningMethodRS256, "h", base(), rsaKey),
+		"alg not in the ring":      sign(jwt.SigningMethodEdDSA, "r", base(), otherKey),
+		"HS384 with the secret":    sign(jwt.SigningMethodHS384, "h", base(), []byte("0123456789abcdef")),
+		"alg none":                 sign(jwt.SigningMethodNone, "r", base(), jwt.UnsafeAllowNoneSignatureType),
+		"unknown kid":              sign(jwt.SigningMethodRS256, "x", base(), rsaKey),
+		"missing kid":              sign(jwt.SigningMethodRS256, "", base(), rsaKey),
+		"wrong signer":             sign(jwt.SigningMethodRS256, "r", base(), otherRSA),
+		"wrong issuer":             sign(jwt.SigningMethodRS256, "r", with("iss", "someone-else"), rsaKey),
+		"wrong audience":           sign(jwt.SigningMethodRS256, "r", with("aud", "other-api"), rsaKey),
+		"no audience":              sign(jwt.SigningMethodRS256, "r", with("aud", nil), rsaKey),
+		"no expiry":                sign(jwt.SigningMethodRS256, "r", with("exp", nil), rsaKey),
+		"expired":                  sign(jwt.SigningMethodRS256, "r", with("exp", time.Now().Add(-time.Minute).Unix()), rsaKey),
+	} {
+		if _, err := kr.Parse(tok, jwt.MapClaims{}); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	if _, err := kr.Parse(sign(jwt.SigningMethodRS256, "r", base(), rsaKey), jwt.MapClaims{}); err != nil {
+		t.Fatalf("control token: %v", err)
+	}
+}
+
+func TestJWKSPublishesPublicKeysOnly(t *testing.T) {
+	rsaPriv, _, rsaKey := rsaPEM(t)
+	edPriv, edKey := edPEM(t)
+	kr := newRing(t, config.JWTConfig{ActiveKeyID: "r", Keys: []config.SigningKey{
+		{ID: "r", Algorithm: "RS256", PrivateKeyPEM: rsaPriv},
+		{ID: "e", Algorithm: "EdDSA", PrivateKeyPEM: edPriv},
+		{ID: "h", Algorithm: "HS256", Secret: "0123456789abcdef"},
+	}})
+	keys := kr.JWKS()["keys"].([]map[string]interface{})
+	if len(keys) != 2 {
+		t.Fatalf("published %d keys, want 2 (no HMAC secret): %v", len(keys), keys)
+	}
+	b64 := base64.RawURLEncoding
+	ed, rs := keys[0], keys[1]
+	if ed["kid"] != "e" || ed["kty"] != "OKP" || ed["crv"] != "Ed25519" || ed["alg"] != "EdDSA" || ed["use"] != "sig" ||
+		ed["x"] != b64.EncodeToString(edKey.Public().(ed25519.PublicKey)) {
+		t.Fatalf("ed25519 jwk %v", ed)
+	}
+	if rs["kid"] != "r" || rs["kty"] != "RSA" || rs["alg"] != "RS256" || rs["use"] != "sig" ||
+		rs["n"] != b64.EncodeToString(rsaKey.N.Bytes()) ||
+		rs["e"] != b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()) {
+		t.Fatalf("rsa jwk %v", rs)
+	}
+	for _, k := range keys {
+		for _, private := range []string{"d", "p", "q", "k"} {
+			if _, ok := k[private]; ok {
+				t.Fatalf("jwk %v leaks %q", k["kid"], private)
+			}
+		}
+	}
+}
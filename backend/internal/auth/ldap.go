@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+const ldapProvider = "ldap"
+
+// LDAPAuthenticator binds as the service account, finds the user entry, then
+// re-binds as that entry with the supplied password. On success the entry's
+// name and email are copied into users and mapped groups become project
+// memberships.
+type LDAPAuthenticator struct {
+	Config   config.LDAPConfig
+	Users    repository.UserStore
+	Projects repository.ProjectStore
+}
+
+type directoryEntry struct {
+	DN     string
+	Email  string
+	Name   string
+	Groups []string
+}
+
+func (a *LDAPAuthenticator) Name() string { return ldapProvider }
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	// An empty password turns a bind into an unauthenticated bind, which
+	// many servers accept.
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	entry, err := a.lookup(login, password)
+	if err != nil {
+		return nil, err
+	}
+	u, err := a.syncUser(entry)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.Config.GroupRoles) > 0 {
+		if err := a.Projects.SyncDirectoryRoles(u.ID, a.projectRoles(entry.Groups)); err != nil {
+			return nil, fmt.Errorf("sync ldap roles: %w", err)
+		}
+	}
+	return u, nil
+}
+
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	cfg := a.Config
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(cfg.URL, ldap.DialWithTLSConfig(tlsCfg))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Timeout > 0 {
+		conn.SetTimeout(cfg.Timeout)
+	}
+	if cfg.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsCfg); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (a *LDAPAuthenticator) lookup(login, password string) (*directoryEntry, error) {
+	cfg := a.Config
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if cfg.BindDN != "" {
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("service bind: %w", err)
+		}
+	}
+	filter := strings.ReplaceAll(cfg.UserFilter, "{login}", ldap.EscapeFilter(login))
+	attrs := []string{cfg.EmailAttr, cfg.NameAttr}
+	if cfg.GroupFilter == "" && cfg.GroupAttr != "" {
+		attrs = append(attrs, cfg.GroupAttr)
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		filter, attrs, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("user search: %w", err)
+	}
+	if len(res.Entries) == 0 {
+		return nil, ErrUnknownUser
+	}
+	if len(res.Entries) > 1 {
+		return nil, fmt.Errorf("user filter matched %d entries for %q", len(res.Entries), login)
+	}
+	e := res.Entries[0]
+
+	if err := conn.Bind(e.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("user bind: %w", err)
+	}
+	entry := &directoryEntry{
+		DN:    e.DN,
+		Email: strings.ToLower(e.GetAttributeValue(cfg.EmailAttr)),
+		Name:  e.GetAttributeValue(cfg.NameAttr),
+	}
+	if entry.Email == "" {
+		return nil, fmt.Errorf("entry %s has no %s attribute", e.DN, cfg.EmailAttr)
+	}
+	if entry.Name == "" {
+		entry.Name = entry.Email
+	}
+
+	if cfg.GroupFilter == "" {
+		entry.Groups = e.GetAttributeValues(cfg.GroupAttr)
+		return entry, nil
+	}
+	// Re-bind as the service account: the user may not be allowed to read
+	// group entries.
+	if cfg.BindDN != "" {
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("service bind: %w", err)
+		}
+	}
+	base := cfg.GroupBaseDN
+	if base == "" {
+		base = cfg.BaseDN
+	}
+	gres, err := conn.Search(ldap.NewSearchRequest(
+		base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		strings.ReplaceAll(cfg.GroupFilter, "{dn}", ldap.EscapeFilter(e.DN)), []string{"dn"}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("group search: %w", err)
+	}
+	for _, g := range gres.Entries {
+		entry.Groups = append(entry.Groups, g.DN)
+	}
+	return entry, nil
+}
+
+// syncUser finds the account linked to the directory entry (falling back to
+// the email address) and refreshes its name and email from the directory.
+func (a *LDAPAuthenticator) syncUser(e *directoryEntry) (*model.User, error) {
+	u, err := a.Users.GetByIdentity(ldapProvider, e.DN)
+	if errors.Is(err, sql.ErrNoRows) {
+		u, err = a.Users.GetUserByEmail(e.Email)
+		if errors.Is(err, sql.ErrNoRows) {
+			u, err = a.Users.CreateExternal(e.Name, e.Email)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := a.Users.LinkIdentity(u.ID, ldapProvider, e.DN, e.Email); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if u.Name == e.Name && u.Email == e.Email && u.EmailVerified {
+		return u, nil
+	}
+	return a.Users.SyncDirectoryProfile(u.ID, e.Name, e.Email)
+}
+
+// projectRoles resolves the highest mapped role per project. Every project
+// named in GroupRoles is present in the result; a nil role means the user is
+// in none of that project's groups.
+func (a *LDAPAuthenticator) projectRoles(groups []string) map[int]*model.Role {
+	member := map[string]bool{}
+	for _, g := range groups {
+		member[strings.ToLower(g)] = true
+	}
+	rank := map[model.Role]int{model.RoleViewer: 1, model.RoleMember: 2, model.RoleAdmin: 3}
+	out := map[int]*model.Role{}
+	for _, gr := range a.Config.GroupRoles {
+		if _, seen := out[gr.ProjectID]; !seen {
+			out[gr.ProjectID] = nil
+		}
+		role := model.Role(gr.Role)
+		if !member[strings.ToLower(gr.GroupDN)] || rank[role] == 0 {
+			continue
+		}
+		if cur := out[gr.ProjectID]; cur == nil || rank[role] > rank[*cur] {
+			out[gr.ProjectID] = &role
+		}
+	}
+	return out
+}
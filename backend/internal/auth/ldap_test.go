@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+
+	"planify/backend/internal/config"
+	"planify/backend/internal/model"
+	"planify/backend/internal/repository"
+)
+
+const (
+	ldapBase       = "dc=example,dc=org"
+	ldapService    = "cn=planify,ou=services,dc=example,dc=org"
+	ldapServicePw  = "service-secret"
+	ldapAda        = "uid=ada,ou=people,dc=example,dc=org"
+	ldapGrace      = "uid=grace,ou=people,dc=example,dc=org"
+	ldapDevs       = "cn=devs,ou=groups,dc=example,dc=org"
+	ldapLeads      = "cn=leads,ou=groups,dc=example,dc=org"
+	ldapGroupsBase = "ou=groups,dc=example,dc=org"
+)
+
+// directory is an in-process LDAP server that understands simple binds and
+// searches with and/or/not, equality and presence filters. Like a typical
+// deployment, only the service account may search.
+type directory struct {
+	mu        sync.Mutex
+	entries   map[string]map[string][]string
+	passwords map[string]string
+}
+
+func newDirectory(t *testing.T) (*directory, string) {
+	t.Helper()
+	d := &directory{
+		entries: map[string]map[string][]string{
+			ldapAda: {
+				"objectclass": {"person"}, "uid": {"ada"}, "mail": {"Ada@Example.org"},
+				"cn": {"Ada Lovelace"}, "memberof": {ldapDevs},
+			},
+			ldapGrace: {
+				"objectclass": {"person"}, "uid": {"grace"}, "mail": {"grace@example.org"}, "cn": {"Grace Hopper"},
+			},
+			ldapDevs:  {"objectclass": {"groupOfNames"}, "member": {ldapAda}},
+			ldapLeads: {"objectclass": {"groupOfNames"}, "member": {ldapGrace}},
+		},
+		passwords: map[string]string{ldapService: ldapServicePw, ldapAda: "ada-pass", ldapGrace: "grace-pass"},
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return d, "ldap://" + ln.Addr().String()
+}
+
+func (d *directory) set(dn, attr string, values ...string) {
+	d.mu.Lock()
+	d.entries[dn][attr] = values
+	d.mu.Unlock()
+}
+
+func (d *directory) serve(conn net.Conn) {
+	defer conn.Close()
+	bound := ""
+	for {
+		req, err := ber.ReadPacket(conn)
+		if err != nil || len(req.Children) < 2 {
+			return
+		}
+		msgID := req.Children[0].Value.(int64)
+		op := req.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, _ := op.Children[1].Value.(string)
+			pw := op.Children[2].Data.String()
+			d.mu.Lock()
+			ok := pw != "" && d.passwords[strings.ToLower(dn)] == pw
+			d.mu.Unlock()
+			code := ldap.LDAPResultInvalidCredentials
+			if ok {
+				bound, code = strings.ToLower(dn), ldap.LDAPResultSuccess
+			}
+			conn.Write(ldapResult(msgID, ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			if bound != ldapService {
+				conn.Write(ldapResult(msgID, ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights))
+				continue
+			}
+			base := strings.ToLower(op.Children[0].Value.(string))
+			var attrs []string
+			for _, a := range op.Children[7].Children {
+				attrs = append(attrs, a.Value.(string))
+			}
+			for _, entry := range d.search(base, op.Children[6], attrs) {
+				conn.Write(entry(msgID))
+			}
+			conn.Write(ldapResult(msgID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			conn.Write(ldapResult(msgID, ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform))
+		}
+	}
+}
+
+func (d *directory) search(base string, filter *ber.Packet, attrs []string) []func(int64) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dns := make([]string, 0, len(d.entries))
+	for dn := range d.entries {
+		dns = append(dns, dn)
+	}
+	sort.Strings(dns)
+	var out []func(int64) []byte
+	for _, dn := range dns {
+		e := d.entries[dn]
+		if !strings.HasSuffix(dn, base) || !matches(filter, e) {
+			continue
+		}
+		res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+		res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, ""))
+		list := ber.NewSequence("")
+		for _, a := range attrs {
+			values := e[strings.ToLower(a)]
+			if len(values) == 0 {
+				continue
+			}
+			attr := ber.NewSequence("")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a, ""))
+			vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+			for _, v := range values {
+				vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+			}
+			attr.AppendChild(vals)
+			list.AppendChild(attr)
+		}
+		res.AppendChild(list)
+		out = append(out, func(msgID int64) []byte { return envelope(msgID, res) })
+	}
+	return out
+}
+
+func matches(f *ber.Packet, e map[string][]string) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !matches(c, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if matches(c, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matches(f.Children[0], e)
+	case ldap.FilterEqualityMatch:
+		attr, want := strings.ToLower(f.Children[0].Value.(string)), f.Children[1].Value.(string)
+		for _, v := range e[attr] {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(e[strings.ToLower(f.Data.String())]) > 0
+	}
+	return false
+}
+
+func ldapResult(msgID int64, tag ber.Tag, code int) []byte {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return envelope(msgID, res)
+}
+
+func envelope(msgID int64, op *ber.Packet) []byte {
+	p := ber.NewSequence("")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, ""))
+	p.AppendChild(op)
+	return p.Bytes()
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	dir, url := newDirectory(t)
+	stores, _ := repository.NewMemoryStores()
+	owner, err := stores.Users.Create("Owner", "owner@example.org", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, _ := stores.Projects.Create(repository.CreateProjectPayload{Name: "Web", OwnerID: owner.ID})
+	ops, _ := stores.Projects.Create(repository.CreateProjectPayload{Name: "Ops", OwnerID: owner.ID})
+
+	cfg := config.LDAP
+	cfg.URL = url
+	cfg.BindDN, cfg.BindPassword = ldapService, ldapServicePw
+	cfg.BaseDN = ldapBase
+	cfg.GroupRoles = []config.LDAPGroupRole{
+		{GroupDN: ldapDevs, ProjectID: web.ID, Role: "member"},
+		{GroupDN: ldapLeads, ProjectID: web.ID, Role: "admin"},
+		{GroupDN: ldapDevs, ProjectID: ops.ID, Role: "viewer"},
+	}
+	a := &LDAPAuthenticator{Config: cfg, Users: stores.Users, Projects: stores.Projects}
+	ctx := context.Background()
+	roles := func(userID int) string {
+		var out []string
+		for _, p := range []*model.Project{web, ops} {
+			role, err := stores.Projects.GetMemberRole(p.ID, userID)
+			if err != nil {
+				role = "-"
+			}
+			out = append(out, string(role))
+		}
+		return strings.Join(out, ",")
+	}
+
+	ada, err := a.Authenticate(ctx, "ada", "ada-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ada.Name != "Ada Lovelace" || ada.Email != "ada@example.org" || !ada.EmailVerified {
+		t.Fatalf("provisioned %+v", ada)
+	}
+	if u, err := stores.Users.GetByIdentity("ldap", ldapAda); err != nil || u.ID != ada.ID {
+		t.Fatalf("identity not linked: %v", err)
+	}
+	if got := roles(ada.ID); got != "member,viewer" {
+		t.Fatalf("roles after first login %s", got)
+	}
+	if u, err := a.Authenticate(ctx, "ADA@example.org", "ada-pass"); err != nil || u.ID != ada.ID {
+		t.Fatalf("login by mail: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		login, password string
+		want            error
+	}{
+		"wrong password": {"ada", "nope", ErrInvalidCredentials},
+		"empty password": {"ada", "", ErrInvalidCredentials},
+		"unknown login":  {"nobody", "ada-pass", ErrUnknownUser},
+		"filter escape":  {"*", "ada-pass", ErrUnknownUser},
+	} {
+		if _, err := a.Authenticate(ctx, tc.login, tc.password); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// The directory is the source of truth for the profile and for mapped
+	// memberships; the DN link keeps the account when the email changes.
+	dir.set(ldapAda, "cn", "Ada King")
+	dir.set(ldapAda, "mail", "ada.king@example.org")
+	dir.set(ldapAda, "memberof", ldapLeads)
+	u, err := a.Authenticate(ctx, "ada", "ada-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != ada.ID || u.Name != "Ada King" || u.Email != "ada.king@example.org" {
+		t.Fatalf("synced %+v", u)
+	}
+	if got := roles(ada.ID); got != "admin,-" {
+		t.Fatalf("roles after group change %s", got)
+	}
+
+	// Group lookup by search needs the service account again after the
+	// user bind; the directory refuses searches from anyone else.
+	a.Config.GroupFilter = "(&(objectClass=groupOfNames)(member={dn}))"
+	a.Config.GroupBaseDN = ldapGroupsBase
+	if _, err := a.Authenticate(ctx, "ada", "ada-pass"); err != nil {
+		t.Fatal(err)
+	}
+	if got := roles(ada.ID); got != "member,viewer" {
+		t.Fatalf("roles from group search %s", got)
+	}
+
+	// An existing local account with the same email is linked, not duplicated.
+	grace, err := stores.Users.Create("Grace", "grace@example.org", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err = a.Authenticate(ctx, "grace", "grace-pass")
+	if err != nil || u.ID != grace.ID || u.Name != "Grace Hopper" {
+		t.Fatalf("linked %+v: %v", u, err)
+	}
+	if got := roles(grace.ID); got != "admin,-" {
+		t.Fatalf("grace roles %s", got)
+	}
+
+	// Owners keep their role whatever the directory says.
+	if err := stores.Projects.SyncDirectoryRoles(owner.ID, a.projectRoles(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got := roles(owner.ID); got != "owner,owner" {
+		t.Fatalf("owner roles %s", got)
+	}
+
+	// Memberships added by hand are not the directory's to change.
+	if err := stores.Projects.AddMember(ops.ID, grace.ID, model.RoleMember); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Projects.SyncDirectoryRoles(grace.ID, a.projectRoles([]string{ldapDevs})); err != nil {
+		t.Fatal(err)
+	}
+	if got := roles(grace.ID); got != "member,member" {
+		t.Fatalf("grace roles with a manual membership %s", got)
+	}
+	if err := stores.Projects.SyncDirectoryRoles(grace.ID, a.projectRoles(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got := roles(grace.ID); got != "-,member" {
+		t.Fatalf("grace roles after leaving every group %s", got)
+	}
+
+	a.Config.BindPassword = "wrong"
+	if _, err := a.Authenticate(ctx, "ada", "ada-pass"); err == nil || errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrUnknownUser) {
+		t.Fatalf("bad service credentials: %v", err)
+	}
+}
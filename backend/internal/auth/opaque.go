@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewOpaqueToken returns a random URL-safe token and the SHA-256 hex digest
+// that should be stored in its place.
+func NewOpaqueToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// NewPersonalToken returns a prefixed opaque token, its hash and a short
+// display prefix that identifies it in listings without revealing it.
+func NewPersonalToken(prefix string) (token, hash, display string, err error) {
+	raw, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	token = prefix + raw
+	return token, HashToken(token), token[:len(prefix)+6], nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
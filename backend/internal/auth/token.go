@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"planify/backend/internal/config"
+)
+
+const PurposeVerifyEmail = "verify_email"
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// SignPurposeToken issues a short-lived token that is only accepted by
+// ParsePurposeToken for the same purpose, so an email link can never be
+// replayed as an access token or vice versa.
+func SignPurposeToken(purpose string, userID int, extra jwt.MapClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"uid": userID,
+		"pur": purpose,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	return Keys().Sign(claims)
+}
+
+func ParsePurposeToken(tokenStr, purpose string) (int, jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	tok, err := Keys().Parse(tokenStr, claims)
+	if err != nil || !tok.Valid {
+		return 0, nil, ErrInvalidToken
+	}
+	if p, _ := claims["pur"].(string); p != purpose {
+		return 0, nil, ErrInvalidToken
+	}
+	uid, _ := claims["uid"].(float64)
+	if uid == 0 {
+		return 0, nil, ErrInvalidToken
+	}
+	return int(uid), claims, nil
+}
+
+// SignAccessToken issues the short-lived bearer token for a session.
+func SignAccessToken(userID int, email, sessionID string) (string, time.Duration, error) {
+	now := time.Now()
+	ttl := config.AccessTokenTTL()
+	claims := jwt.MapClaims{
+		"uid":   userID,
+		"email": email,
+		"sid":   sessionID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(ttl).Unix(),
+	}
+	signed, err := Keys().Sign(claims)
+	return signed, ttl, err
+}
+
+// ParseToken verifies any Planify token against the active key ring.
+func ParseToken(tokenStr string, claims jwt.MapClaims) (*jwt.Token, error) {
+	return Keys().Parse(tokenStr, claims)
+}
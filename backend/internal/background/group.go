@@ -0,0 +1,69 @@
+package background
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Group runs background work that has to finish, or at least notice
+// cancellation, before the process exits.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in its own goroutine. fn should return soon after ctx is done.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[jobs] %s panicked: %v", name, r)
+			}
+		}()
+		fn(g.ctx)
+	}()
+}
+
+// Every runs fn once per interval until the group stops.
+func (g *Group) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	g.Go(name, func(ctx context.Context) {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := fn(ctx); err != nil {
+					log.Printf("[jobs] %s: %v", name, err)
+				}
+			}
+		}
+	})
+}
+
+// Stop cancels the group and waits for running work until ctx expires.
+func (g *Group) Stop(ctx context.Context) error {
+	g.cancel()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,7 +2,393 @@ package config
 
 import "time"
 
-var JwtKey = []byte("my_super_secret_key")
+// DefaultSecret is the development secret. Load refuses it outside dev.
+const DefaultSecret = "my_super_secret_key"
+
+// Env is "dev", "staging" or "production". It defaults to production so a
+// deployment that forgets to configure it still refuses DefaultSecret.
+var Env = "production"
+
+// JwtKey signs CSRF tokens and is the secret of HS256 keys that have none of
+// their own.
+var JwtKey = []byte(DefaultSecret)
+
+// The TLS certificate is re-read on SIGHUP. ShutdownTimeout bounds the
+// whole drain on SIGTERM: in-flight requests, background jobs and the pool.
+type ServerConfig struct {
+	Addr              string
+	TLSCertFile       string
+	TLSKeyFile        string
+	CORSOrigins       []string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+}
+
+var Server = ServerConfig{
+	Addr:              ":8080",
+	CORSOrigins:       []string{"http://localhost:5173"},
+	ReadTimeout:       2 * time.Minute,
+	ReadHeaderTimeout: 10 * time.Second,
+	WriteTimeout:      2 * time.Minute,
+	IdleTimeout:       2 * time.Minute,
+	ShutdownTimeout:   30 * time.Second,
+}
+
+// AutoMigrate applies pending migrations on boot. Instances take a database
+// lock first, so several can start at once. For the sqlite driver DSN is a
+// file path, or ":memory:" for a throwaway database, and BusyTimeout is how
+// long a write waits for another writer.
+type DatabaseConfig struct {
+	Driver          string
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	AutoMigrate     bool
+	BusyTimeout     time.Duration
+}
+
+var Database = DatabaseConfig{
+	Driver:          "mysql",
+	DSN:             "root:@tcp(127.0.0.1:3306)/planify?parseTime=true",
+	MaxOpenConns:    25,
+	MaxIdleConns:    10,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+	BusyTimeout:     5 * time.Second,
+}
+
+// Tasks are ordered within a column by string ranks that grow as tasks are
+// squeezed between each other. Every RebalanceInterval, columns holding a
+// rank longer than MaxRankLength are given short ranks again. An interval of
+// 0 turns the job off.
+type BoardConfig struct {
+	RebalanceInterval time.Duration
+	MaxRankLength     int
+}
+
+var Board = BoardConfig{
+	RebalanceInterval: 10 * time.Minute,
+	MaxRankLength:     12,
+}
+
+// Sizes are in bytes.
+type UploadConfig struct {
+	Dir               string
+	MaxAttachmentSize int64
+	MaxAvatarSize     int64
+}
+
+var Uploads = UploadConfig{
+	Dir:               "uploads",
+	MaxAttachmentSize: 25 << 20,
+	MaxAvatarSize:     5 << 20,
+}
+
+// SigningKey is one entry of the JWT key ring. HS256 keys use Secret, or
+// JwtKey when it is empty; RS256 and EdDSA keys take a PEM private key inline
+// or from a file, or only a public key when the entry is kept around for
+// verification. A key stops verifying tokens after VerifyUntil, which gives
+// rotated keys a grace window.
+type SigningKey struct {
+	ID             string
+	Algorithm      string
+	Secret         string
+	PrivateKeyPEM  string
+	PrivateKeyFile string
+	PublicKeyPEM   string
+	PublicKeyFile  string
+	VerifyUntil    time.Time
+}
+
+type JWTConfig struct {
+	Issuer      string
+	Audience    string
+	ActiveKeyID string
+	Keys        []SigningKey
+}
+
+var JWT = JWTConfig{
+	Issuer:      "planify",
+	Audience:    "planify-api",
+	ActiveKeyID: "default",
+	Keys: []SigningKey{
+		{ID: "default", Algorithm: "HS256"},
+	},
+}
 var TokenInCookie = false
 
-func AccessTokenTTL() time.Duration { return 48 * time.Hour }
\ No newline at end of file
+// Cookie controls how tokens are delivered when TokenInCookie is on. SameSite
+// is one of "lax", "strict" or "none"; "none" requires Secure.
+type CookieConfig struct {
+	AccessName  string
+	RefreshName string
+	CSRFName    string
+	CSRFHeader  string
+	Domain      string
+	Secure      bool
+	SameSite    string
+}
+
+var Cookie = CookieConfig{
+	AccessName:  "planify_token",
+	RefreshName: "planify_refresh",
+	CSRFName:    "planify_csrf",
+	CSRFHeader:  "X-CSRF-Token",
+	Secure:      true,
+	SameSite:    "lax",
+}
+
+// RefreshTTL bounds both a single refresh token and how long a session may
+// sit idle before it has to log in again.
+type TokenConfig struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+var Tokens = TokenConfig{
+	AccessTTL:  15 * time.Minute,
+	RefreshTTL: 30 * 24 * time.Hour,
+}
+
+func AccessTokenTTL() time.Duration { return Tokens.AccessTTL }
+
+func RefreshTokenTTL() time.Duration { return Tokens.RefreshTTL }
+
+type AccessTokenConfig struct {
+	Prefix     string
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	MaxPerUser int
+}
+
+// AccessTokens configures personal access tokens. The prefix makes leaked
+// tokens easy to spot with secret scanners.
+var AccessTokens = AccessTokenConfig{
+	Prefix:     "pfy_",
+	DefaultTTL: 90 * 24 * time.Hour,
+	MaxTTL:     365 * 24 * time.Hour,
+	MaxPerUser: 50,
+}
+
+type PasswordHashConfig struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Argon2id parameters. Changing them makes existing hashes get upgraded on the
+// owner's next successful login.
+var PasswordHash = PasswordHashConfig{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+type PasswordPolicyConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+var PasswordPolicy = PasswordPolicyConfig{
+	MinLength:    10,
+	MaxLength:    128,
+	RequireUpper: true,
+	RequireLower: true,
+	RequireDigit: true,
+}
+
+// PublicURL is the frontend origin used to build links in outgoing email.
+var PublicURL = "http://localhost:5173"
+
+type SignupConfig struct {
+	Enabled         bool
+	AllowedDomains  []string
+	VerificationTTL time.Duration
+}
+
+var Signup = SignupConfig{
+	Enabled:         true,
+	VerificationTTL: 24 * time.Hour,
+}
+
+type MailConfig struct {
+	Driver   string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	StartTLS bool
+	Timeout  time.Duration
+}
+
+var Mail = MailConfig{
+	Driver:  "log",
+	Host:    "127.0.0.1",
+	Port:    1025,
+	From:    "Planify <no-reply@planify.local>",
+	Timeout: 10 * time.Second,
+}
+
+type PasswordResetConfig struct {
+	TokenTTL       time.Duration
+	RateLimitIP    int
+	RateLimitEmail int
+	RateWindow     time.Duration
+}
+
+var PasswordReset = PasswordResetConfig{
+	TokenTTL:       time.Hour,
+	RateLimitIP:    10,
+	RateLimitEmail: 3,
+	RateWindow:     time.Hour,
+}
+
+type LockoutConfig struct {
+	Store            string
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	AccountThreshold int
+	IPThreshold      int
+	LockDuration     time.Duration
+	Window           time.Duration
+}
+
+// Lockout throttles /api/login. After FreeAttempts failures each further
+// attempt waits twice as long as the last (BaseDelay up to MaxDelay); at a
+// threshold the account or IP is locked for LockDuration. Counters reset
+// after Window without failures. Store is "memory" or "sql".
+var Lockout = LockoutConfig{
+	Store:            "memory",
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         5 * time.Minute,
+	AccountThreshold: 10,
+	IPThreshold:      100,
+	LockDuration:     15 * time.Minute,
+	Window:           time.Hour,
+}
+
+type RateLimitRule struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// RateLimit sets the token buckets applied per route group. Authenticated
+// groups are keyed by user, anonymous ones by client IP. A group without a
+// rule is not limited. Store is "memory" or "redis" (RedisURL, e.g.
+// redis://localhost:6379/0).
+type RateLimitConfig struct {
+	Enabled  bool
+	Store    string
+	RedisURL string
+	Rules    map[string]RateLimitRule
+}
+
+var RateLimit = RateLimitConfig{
+	Enabled: true,
+	Store:   "memory",
+	Rules: map[string]RateLimitRule{
+		"public":      {Requests: 60, Per: time.Minute, Burst: 20},
+		"api":         {Requests: 600, Per: time.Minute, Burst: 100},
+		"task-writes": {Requests: 120, Per: time.Minute, Burst: 30},
+		"uploads":     {Requests: 20, Per: time.Minute, Burst: 5},
+	},
+}
+
+// RequireForAll turns two-factor authentication on for every project; single
+// projects can also opt in through their security settings.
+type MFAConfig struct {
+	Issuer        string
+	RequireForAll bool
+	LoginTokenTTL time.Duration
+	RecoveryCodes int
+	MaxAttempts   int
+}
+
+var MFA = MFAConfig{
+	Issuer:        "Planify",
+	LoginTokenTTL: 5 * time.Minute,
+	RecoveryCodes: 10,
+	MaxAttempts:   5,
+}
+
+type OIDCProvider struct {
+	ID           string
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDC lists the single sign-on providers. Users are matched by provider
+// subject first and then by verified email; AutoProvision creates a Planify
+// account when neither matches.
+type OIDCConfig struct {
+	Providers         []OIDCProvider
+	PostLoginRedirect string
+	StateTTL          time.Duration
+	AutoProvision     bool
+}
+
+// PostLoginRedirect defaults to PublicURL + "/#/sso/callback".
+var OIDC = OIDCConfig{
+	StateTTL:      10 * time.Minute,
+	AutoProvision: true,
+}
+
+// AuthBackends lists the password authenticators tried by /api/login, in
+// order. Known values are "local" and "ldap".
+var AuthBackends = []string{"local"}
+
+type LDAPGroupRole struct {
+	GroupDN   string
+	ProjectID int
+	Role      string
+}
+
+// LDAP configures directory sign-in. In filters, {login} is replaced with the
+// escaped login name and {dn} with the user's DN. When GroupFilter is empty
+// groups are read from GroupAttr on the user entry (memberOf).
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	EmailAttr          string
+	NameAttr           string
+	GroupAttr          string
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupRoles         []LDAPGroupRole
+	Timeout            time.Duration
+}
+
+var LDAP = LDAPConfig{
+	URL:        "ldap://127.0.0.1:389",
+	UserFilter: "(&(objectClass=person)(|(uid={login})(mail={login})))",
+	EmailAttr:  "mail",
+	NameAttr:   "cn",
+	GroupAttr:  "memberOf",
+	Timeout:    10 * time.Second,
+}
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyName(t *testing.T) {
+	for field, want := range map[string]string{
+		"Addr":          "addr",
+		"TLSCertFile":   "tls_cert_file",
+		"MaxOpenConns":  "max_open_conns",
+		"DSN":           "dsn",
+		"CORSOrigins":   "cors_origins",
+		"AccessTTL":     "access_ttl",
+		"ActiveKeyID":   "active_key_id",
+		"RedisURL":      "redis_url",
+		"PublicURL":     "public_url",
+		"TokenInCookie": "token_in_cookie",
+		"OIDC":          "oidc",
+		"MFA":           "mfa",
+	} {
+		if got := keyName(field); got != want {
+			t.Errorf("keyName(%s) = %s, want %s", field, got, want)
+		}
+	}
+}
+
+func TestApplyMap(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		doc   map[string]any
+		check func(*Config) bool
+		err   string
+	}{
+		{
+			name:  "nested keys",
+			doc:   map[string]any{"server": map[string]any{"addr": ":9000", "tls_cert_file": "c.pem"}},
+			check: func(c *Config) bool { return c.Server.Addr == ":9000" && c.Server.TLSCertFile == "c.pem" },
+		},
+		{
+			name:  "keys are case-insensitive",
+			doc:   map[string]any{"Database": map[string]any{"Max_Open_Conns": 3}},
+			check: func(c *Config) bool { return c.Database.MaxOpenConns == 3 },
+		},
+		{
+			name:  "untouched keys keep their defaults",
+			doc:   map[string]any{"server": map[string]any{"addr": ":9000"}},
+			check: func(c *Config) bool { return c.Server.ShutdownTimeout == 30*time.Second },
+		},
+		{
+			name:  "duration",
+			doc:   map[string]any{"tokens": map[string]any{"access_ttl": "5m"}},
+			check: func(c *Config) bool { return c.Tokens.AccessTTL == 5*time.Minute },
+		},
+		{
+			name: "time",
+			doc:  map[string]any{"jwt": map[string]any{"keys": []any{map[string]any{"id": "old", "verify_until": "2026-01-02T03:04:05Z"}}}},
+			check: func(c *Config) bool {
+				return c.JWT.Keys[0].VerifyUntil.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+			},
+		},
+		{
+			name: "a list replaces the default",
+			doc:  map[string]any{"server": map[string]any{"cors_origins": []any{"https://a.example", "https://b.example"}}},
+			check: func(c *Config) bool {
+				return strings.Join(c.Server.CORSOrigins, ",") == "https://a.example,https://b.example"
+			},
+		},
+		{
+			name: "a map replaces the default",
+			doc:  map[string]any{"rate_limit": map[string]any{"rules": map[string]any{"api": map[string]any{"requests": 5, "per": "1s", "burst": 2}}}},
+			check: func(c *Config) bool {
+				r, ok := c.RateLimit.Rules["api"]
+				return len(c.RateLimit.Rules) == 1 && ok && r == RateLimitRule{Requests: 5, Per: time.Second, Burst: 2}
+			},
+		},
+		{
+			name:  "whole floats fit ints",
+			doc:   map[string]any{"uploads": map[string]any{"max_avatar_size": float64(1024)}},
+			check: func(c *Config) bool { return c.Uploads.MaxAvatarSize == 1024 },
+		},
+		{name: "unknown top-level key", doc: map[string]any{"sever": map[string]any{}}, err: "unknown key sever"},
+		{name: "unknown nested key", doc: map[string]any{"server": map[string]any{"adr": ":1"}}, err: "unknown key server.adr"},
+		{name: "unknown key in a list", doc: map[string]any{"jwt": map[string]any{"keys": []any{map[string]any{"kid": "x"}}}}, err: "unknown key jwt.keys[0].kid"},
+		{name: "bad duration", doc: map[string]any{"tokens": map[string]any{"access_ttl": 900}}, err: "tokens.access_ttl: expected a duration"},
+		{name: "string for a number", doc: map[string]any{"database": map[string]any{"max_open_conns": "3"}}, err: "database.max_open_conns: expected a number"},
+		{name: "fraction for an int", doc: map[string]any{"database": map[string]any{"max_open_conns": 2.5}}, err: "expected a whole number"},
+		{name: "scalar for a table", doc: map[string]any{"server": ":8080"}, err: "server: expected a table"},
+		{name: "scalar for a list", doc: map[string]any{"auth_backends": "local"}, err: "auth_backends: expected a list"},
+		{name: "number for a bool", doc: map[string]any{"token_in_cookie": 1}, err: "token_in_cookie: expected true or false"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Defaults()
+			err := applyMap(cfg, tc.doc)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got %v, want an error containing %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.check(cfg) {
+				t.Fatalf("unexpected result %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"PLANIFY_SERVER_ADDR":              ":9999",
+		"PLANIFY_DATABASE_MAX_OPEN_CONNS":  "7",
+		"PLANIFY_TOKENS_ACCESS_TTL":        "2m",
+		"PLANIFY_TOKEN_IN_COOKIE":          "true",
+		"PLANIFY_AUTH_BACKENDS":            "ldap, local,",
+		"PLANIFY_JWT_ACTIVE_KEY_ID":        "next",
+		"PLANIFY_PASSWORD_HASH_ITERATIONS": "4",
+	}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	cfg := Defaults()
+	if err := applyEnv(cfg, "PLANIFY", lookup); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":9999" || cfg.Database.MaxOpenConns != 7 || cfg.Tokens.AccessTTL != 2*time.Minute ||
+		!cfg.TokenInCookie || strings.Join(cfg.AuthBackends, ",") != "ldap,local" || cfg.JWT.ActiveKeyID != "next" ||
+		cfg.PasswordHash.Iterations != 4 {
+		t.Fatalf("env not applied: %+v", cfg)
+	}
+
+	for name, value := range map[string]string{
+		"PLANIFY_TOKENS_ACCESS_TTL":       "15",
+		"PLANIFY_DATABASE_MAX_OPEN_CONNS": "many",
+		"PLANIFY_TOKEN_IN_COOKIE":         "yes please",
+		"PLANIFY_JWT_KEYS":                "default",
+	} {
+		err := applyEnv(Defaults(), "PLANIFY", func(k string) (string, bool) { return value, k == name })
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s=%s: got %v", name, value, err)
+		}
+	}
+}
+
+// restoreSettings puts the package settings back after a test that calls
+// Load, which applies what it read.
+func restoreSettings(t *testing.T) {
+	saved := Defaults()
+	t.Cleanup(func() { Apply(saved) })
+}
+
+func TestLoadLayersFileAndEnv(t *testing.T) {
+	restoreSettings(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"planify.yaml": `
+env: production
+secret: 0123456789abcdef0123456789abcdef
+server:
+  addr: ":7000"
+  shutdown_timeout: 5s
+database:
+  driver: sqlite
+  dsn: planify.db
+`,
+		"planify.toml": `
+env = "production"
+secret = "0123456789abcdef0123456789abcdef"
+[server]
+addr = ":7000"
+shutdown_timeout = "5s"
+[database]
+driver = "sqlite"
+dsn = "planify.db"
+`,
+	}
+	for name, body := range files {
+		t.Run(filepath.Ext(name), func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PLANIFY_SERVER_ADDR", ":7001")
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Addr != ":7001" {
+				t.Fatalf("server.addr %q, want the environment to win", cfg.Server.Addr)
+			}
+			if cfg.Server.ShutdownTimeout != 5*time.Second || cfg.Database.Driver != "sqlite" || cfg.Env != "production" {
+				t.Fatalf("file not applied: %+v", cfg)
+			}
+			if Server.Addr != ":7001" || string(JwtKey) != cfg.Secret {
+				t.Fatal("Load did not apply the result")
+			}
+		})
+	}
+
+	for name, tc := range map[string]struct{ file, body, err string }{
+		"unknown key":        {"typo.yaml", "server:\n  adr: \":1\"\n", "unknown key server.adr"},
+		"unsupported format": {"planify.json", "{}", "unsupported format"},
+		"malformed":          {"broken.toml", "[server\n", "broken.toml"},
+		"missing file":       {"", "", "no such file"},
+	} {
+		path := filepath.Join(dir, "missing.yaml")
+		if tc.file != "" {
+			path = filepath.Join(dir, tc.file)
+			if err := os.WriteFile(path, []byte(tc.body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got %v, want an error containing %q", name, err, tc.err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	production := func(c *Config) {
+		c.Env = "production"
+		c.Secret = strings.Repeat("s", 32)
+	}
+	for _, tc := range []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{name: "defaults refuse the development secret", modify: func(c *Config) { c.Env = Defaults().Env },
+			want: "secret is the built-in development value"},
+		{name: "defaults are valid in dev", modify: func(*Config) {}},
+		{name: "production with a real secret", modify: production},
+		{name: "default secret outside dev", modify: func(c *Config) { production(c); c.Secret = DefaultSecret },
+			want: "secret is the built-in development value"},
+		{name: "default secret in staging", modify: func(c *Config) { c.Env = "staging" },
+			want: "secret is the built-in development value"},
+		{name: "short secret outside dev", modify: func(c *Config) { production(c); c.Secret = strings.Repeat("s", 20) },
+			want: "secret must be at least 32 characters outside dev"},
+		{name: "jwt key with the default secret", modify: func(c *Config) {
+			production(c)
+			c.JWT.Keys = []SigningKey{{ID: "default", Algorithm: "HS256", Secret: DefaultSecret}}
+		}, want: `jwt key "default" uses the built-in development secret`},
+		{name: "unknown env", modify: func(c *Config) { c.Env = "prod" }, want: `env must be one of dev, staging, production, got "prod"`},
+		{name: "relative public url", modify: func(c *Config) { c.PublicURL = "/app" }, want: "public_url must be an absolute http(s) URL"},
+		{name: "half a TLS pair", modify: func(c *Config) { c.Server.TLSCertFile = "cert.pem" }, want: "must be set together"},
+		{name: "unknown driver", modify: func(c *Config) { c.Database.Driver = "oracle" }, want: "database.driver must be one of"},
+		{name: "idle above open", modify: func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 2, 3 }, want: "max_idle_conns"},
+		{name: "access ttl not shorter", modify: func(c *Config) { c.Tokens.AccessTTL = c.Tokens.RefreshTTL }, want: "access_ttl must be shorter"},
+		{name: "same_site none without secure", modify: func(c *Config) { c.Cookie.SameSite, c.Cookie.Secure = "none", false }, want: "requires cookie.secure"},
+		{name: "active key missing", modify: func(c *Config) { c.JWT.ActiveKeyID = "next" }, want: `jwt.active_key_id "next" is not in jwt.keys`},
+		{name: "smtp without host", modify: func(c *Config) { c.Mail.Driver, c.Mail.Host = "smtp", "" }, want: "required for the smtp driver"},
+		{name: "redis without url", modify: func(c *Config) { c.RateLimit.Store, c.RateLimit.RedisURL = "redis", "" }, want: "rate_limit.redis_url is required"},
+		{name: "bad rate rule", modify: func(c *Config) { c.RateLimit.Rules = map[string]RateLimitRule{"api": {Requests: 1}} }, want: "rate_limit.rules.api"},
+		{name: "unknown backend", modify: func(c *Config) { c.AuthBackends = []string{"kerberos"} }, want: "auth_backends must be one of"},
+		{name: "no backends", modify: func(c *Config) { c.AuthBackends = nil }, want: "auth_backends must not be empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.Env = "dev"
+			tc.modify(cfg)
+			err := cfg.Validate()
+			if tc.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("got %v, want a ValidationError", err)
+			}
+			for _, p := range ve.Problems {
+				if strings.Contains(p, tc.want) {
+					return
+				}
+			}
+			t.Fatalf("problems %q do not mention %q", ve.Problems, tc.want)
+		})
+	}
+
+	// Every problem is reported at once.
+	cfg := Defaults()
+	cfg.Env = "production"
+	cfg.Database.DSN = ""
+	cfg.Uploads.Dir = ""
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Problems) != 3 {
+		t.Fatalf("got %v, want three problems", err)
+	}
+}
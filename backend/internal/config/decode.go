@@ -0,0 +1,274 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// keyName turns a Go field name into its config key: TLSCertFile becomes
+// tls_cert_file and MaxOpenConns max_open_conns.
+func keyName(field string) string {
+	r := []rune(field)
+	var b strings.Builder
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := r[i-1]
+			nextLower := i+1 < len(r) && unicode.IsLower(r[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
+
+// applyMap overlays a decoded YAML/TOML document onto the struct pointed to
+// by dst. Unknown keys are errors so typos do not go unnoticed.
+func applyMap(dst any, doc map[string]any) error {
+	return setStruct(reflect.ValueOf(dst).Elem(), doc, "")
+}
+
+func setStruct(v reflect.Value, doc map[string]any, path string) error {
+	fields := map[string]int{}
+	for i := 0; i < v.NumField(); i++ {
+		fields[keyName(v.Type().Field(i).Name)] = i
+	}
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		i, ok := fields[strings.ToLower(k)]
+		if !ok {
+			return fmt.Errorf("unknown key %s", path+k)
+		}
+		if err := setValue(v.Field(i), doc[k], path+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, raw any, path string) error {
+	switch {
+	case v.Type() == durationType:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s: %w", path, errNotDuration)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, errNotDuration)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == timeType:
+		switch t := raw.(type) {
+		case time.Time:
+			v.Set(reflect.ValueOf(t))
+		case string:
+			parsed, err := time.Parse(time.RFC3339, t)
+			if err != nil {
+				return fmt.Errorf("%s: expected an RFC 3339 time: %w", path, err)
+			}
+			v.Set(reflect.ValueOf(parsed))
+		default:
+			return fmt.Errorf("%s: expected an RFC 3339 time", path)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a table", path)
+		}
+		return setStruct(v, m, path+".")
+	case reflect.Slice:
+		items, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a list", path)
+		}
+		out := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(out.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(out)
+		return nil
+	case reflect.Map:
+		m, ok := raw.(map[string]any)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: expected a table", path)
+		}
+		out := reflect.MakeMapWithSize(v.Type(), len(m))
+		for k, item := range m {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, item, path+"."+k); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(k), elem)
+		}
+		v.Set(out)
+		return nil
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string", path)
+		}
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("%s: expected true or false", path)
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return setNumber(v, raw, path)
+	}
+	return fmt.Errorf("%s: unsupported setting type %s", path, v.Type())
+}
+
+func setNumber(v reflect.Value, raw any, path string) error {
+	var f float64
+	switch n := raw.(type) {
+	case int:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case uint64:
+		f = float64(n)
+	case float64:
+		f = n
+	default:
+		return fmt.Errorf("%s: expected a number", path)
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(f)
+		return nil
+	}
+	if f != math.Trunc(f) {
+		return fmt.Errorf("%s: expected a whole number", path)
+	}
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f < 0 || v.OverflowUint(uint64(f)) {
+			return fmt.Errorf("%s: %v is out of range", path, raw)
+		}
+		v.SetUint(uint64(f))
+	default:
+		if v.OverflowInt(int64(f)) {
+			return fmt.Errorf("%s: %v is out of range", path, raw)
+		}
+		v.SetInt(int64(f))
+	}
+	return nil
+}
+
+// applyEnv sets every scalar and string-list field that has a matching
+// environment variable, e.g. PLANIFY_SERVER_ADDR for Server.Addr.
+func applyEnv(dst any, prefix string, lookup func(string) (string, bool)) error {
+	return envStruct(reflect.ValueOf(dst).Elem(), prefix, lookup)
+}
+
+func envStruct(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		name := prefix + "_" + strings.ToUpper(keyName(v.Type().Field(i).Name))
+		if f.Kind() == reflect.Struct && f.Type() != timeType {
+			if err := envStruct(f, name, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setFromString(f, s, name); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	return nil
+}
+
+func setFromString(v reflect.Value, s, name string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, errNotDuration)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("%s: expected an RFC 3339 time: %w", name, err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false", name)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: expected a whole number", name)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: expected a whole number", name)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: expected a number", name)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%s: can only be set in the config file", name)
+		}
+		out := []string{}
+		for _, part := range strings.Split(s, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		v.Set(reflect.ValueOf(out))
+	default:
+		return fmt.Errorf("%s: can only be set in the config file", name)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config gathers every setting so it can be read from a file and the
+// environment in one pass. Load copies the result into the package-level
+// variables the rest of the code reads.
+//
+// File keys are the snake_case field names, nested by section
+// (database.max_open_conns). Environment variables use the same path in upper
+// case with a PLANIFY_ prefix (PLANIFY_DATABASE_MAX_OPEN_CONNS); lists are
+// comma separated. Lists of objects and maps can only be set in the file, and
+// lists and maps given there replace the defaults rather than merging.
+type Config struct {
+	Env       string
+	Secret    string
+	PublicURL string
+
+	Server         ServerConfig
+	Database       DatabaseConfig
+	Uploads        UploadConfig
+	Board          BoardConfig
+	Tokens         TokenConfig
+	TokenInCookie  bool
+	Cookie         CookieConfig
+	JWT            JWTConfig
+	AccessTokens   AccessTokenConfig
+	PasswordHash   PasswordHashConfig
+	PasswordPolicy PasswordPolicyConfig
+	Signup         SignupConfig
+	Mail           MailConfig
+	PasswordReset  PasswordResetConfig
+	Lockout        LockoutConfig
+	RateLimit      RateLimitConfig
+	MFA            MFAConfig
+	OIDC           OIDCConfig
+	AuthBackends   []string
+	LDAP           LDAPConfig
+}
+
+// Defaults returns the built-in settings.
+func Defaults() *Config {
+	return &Config{
+		Env:            Env,
+		Secret:         string(JwtKey),
+		PublicURL:      PublicURL,
+		Server:         Server,
+		Database:       Database,
+		Uploads:        Uploads,
+		Board:          Board,
+		Tokens:         Tokens,
+		TokenInCookie:  TokenInCookie,
+		Cookie:         Cookie,
+		JWT:            JWT,
+		AccessTokens:   AccessTokens,
+		PasswordHash:   PasswordHash,
+		PasswordPolicy: PasswordPolicy,
+		Signup:         Signup,
+		Mail:           Mail,
+		PasswordReset:  PasswordReset,
+		Lockout:        Lockout,
+		RateLimit:      RateLimit,
+		MFA:            MFA,
+		OIDC:           OIDC,
+		AuthBackends:   AuthBackends,
+		LDAP:           LDAP,
+	}
+}
+
+// Load reads the defaults, then the file at path (YAML or TOML, chosen by
+// extension; empty means none), then PLANIFY_* environment variables. The
+// result is validated and applied.
+func Load(path string) (*Config, error) {
+	cfg := Defaults()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(cfg, "PLANIFY", os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	Apply(cfg)
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	doc := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &doc)
+	case ".toml":
+		err = toml.Unmarshal(raw, &doc)
+	default:
+		return fmt.Errorf("config: %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := applyMap(c, doc); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) Dev() bool { return c.Env == "dev" }
+
+// ValidationError lists every problem found so they can be fixed in one go.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (c *Config) Validate() error {
+	var p []string
+	add := func(format string, args ...any) { p = append(p, fmt.Sprintf(format, args...)) }
+	oneOf := func(field, v string, allowed ...string) {
+		for _, a := range allowed {
+			if v == a {
+				return
+			}
+		}
+		add("%s must be one of %s, got %q", field, strings.Join(allowed, ", "), v)
+	}
+
+	oneOf("env", c.Env, "dev", "staging", "production")
+	if !c.Dev() {
+		if c.Secret == DefaultSecret {
+			add("secret is the built-in development value; set PLANIFY_SECRET")
+		} else if len(c.Secret) < 32 {
+			add("secret must be at least 32 characters outside dev")
+		}
+		for _, k := range c.JWT.Keys {
+			if k.Secret == DefaultSecret {
+				add("jwt key %q uses the built-in development secret", k.ID)
+			}
+		}
+	}
+	if len(c.Secret) < 16 {
+		add("secret must be at least 16 characters")
+	}
+	checkURL := func(field, v string) {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("%s must be an absolute http(s) URL, got %q", field, v)
+		}
+	}
+	checkURL("public_url", c.PublicURL)
+
+	if c.Server.Addr == "" {
+		add("server.addr is required")
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		add("server.tls_cert_file and server.tls_key_file must be set together")
+	}
+	for _, f := range []string{c.Server.TLSCertFile, c.Server.TLSKeyFile} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			add("server TLS file: %v", err)
+		}
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		add("server timeouts must not be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("server.shutdown_timeout must be positive")
+	}
+	for _, o := range c.Server.CORSOrigins {
+		checkURL("server.cors_origins", o)
+	}
+
+	oneOf("database.driver", c.Database.Driver, "mysql", "postgres", "sqlite")
+	if c.Database.DSN == "" {
+		add("database.dsn is required")
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		add("database pool sizes must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		add("database.max_idle_conns must not exceed database.max_open_conns")
+	}
+	if c.Database.BusyTimeout < 0 {
+		add("database.busy_timeout must not be negative")
+	}
+
+	if c.Uploads.Dir == "" {
+		add("uploads.dir is required")
+	}
+	if c.Uploads.MaxAttachmentSize <= 0 || c.Uploads.MaxAvatarSize <= 0 {
+		add("uploads size limits must be positive")
+	}
+	if c.Board.RebalanceInterval < 0 {
+		add("board.rebalance_interval must not be negative")
+	}
+	if c.Board.MaxRankLength < 2 || c.Board.MaxRankLength > 128 {
+		add("board.max_rank_length must be between 2 and 128")
+	}
+
+	if c.Tokens.AccessTTL <= 0 || c.Tokens.RefreshTTL <= 0 {
+		add("tokens.access_ttl and tokens.refresh_ttl must be positive")
+	} else if c.Tokens.AccessTTL >= c.Tokens.RefreshTTL {
+		add("tokens.access_ttl must be shorter than tokens.refresh_ttl")
+	}
+	oneOf("cookie.same_site", c.Cookie.SameSite, "lax", "strict", "none")
+	if c.Cookie.SameSite == "none" && !c.Cookie.Secure {
+		add("cookie.same_site none requires cookie.secure")
+	}
+	found := false
+	for _, k := range c.JWT.Keys {
+		found = found || k.ID == c.JWT.ActiveKeyID
+	}
+	if !found {
+		add("jwt.active_key_id %q is not in jwt.keys", c.JWT.ActiveKeyID)
+	}
+
+	oneOf("mail.driver", c.Mail.Driver, "log", "smtp")
+	if c.Mail.Driver == "smtp" && (c.Mail.Host == "" || c.Mail.Port == 0 || c.Mail.From == "") {
+		add("mail.host, mail.port and mail.from are required for the smtp driver")
+	}
+	oneOf("lockout.store", c.Lockout.Store, "memory", "sql")
+	oneOf("rate_limit.store", c.RateLimit.Store, "memory", "redis")
+	if c.RateLimit.Store == "redis" && c.RateLimit.RedisURL == "" {
+		add("rate_limit.redis_url is required for the redis store")
+	}
+	for name, r := range c.RateLimit.Rules {
+		if r.Requests <= 0 || r.Per <= 0 || r.Burst <= 0 {
+			add("rate_limit.rules.%s needs positive requests, per and burst", name)
+		}
+	}
+	for _, b := range c.AuthBackends {
+		oneOf("auth_backends", b, "local", "ldap")
+	}
+	if len(c.AuthBackends) == 0 {
+		add("auth_backends must not be empty")
+	}
+
+	if len(p) > 0 {
+		return &ValidationError{Problems: p}
+	}
+	return nil
+}
+
+// Apply makes cfg the active configuration.
+func Apply(cfg *Config) {
+	Env = cfg.Env
+	JwtKey = []byte(cfg.Secret)
+	PublicURL = cfg.PublicURL
+	Server = cfg.Server
+	Database = cfg.Database
+	Uploads = cfg.Uploads
+	Board = cfg.Board
+	Tokens = cfg.Tokens
+	TokenInCookie = cfg.TokenInCookie
+	Cookie = cfg.Cookie
+	JWT = cfg.JWT
+	AccessTokens = cfg.AccessTokens
+	PasswordHash = cfg.PasswordHash
+	PasswordPolicy = cfg.PasswordPolicy
+	Signup = cfg.Signup
+	Mail = cfg.Mail
+	PasswordReset = cfg.PasswordReset
+	Lockout = cfg.Lockout
+	RateLimit = cfg.RateLimit
+	MFA = cfg.MFA
+	OIDC = cfg.OIDC
+	AuthBackends = cfg.AuthBackends
+	LDAP = cfg.LDAP
+}
+
+var errNotDuration = errors.New(`expected a duration such as "90s", "15m" or "720h"`)
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"planify/backend/internal/config"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// DB is a *sql.DB that rewrites queries for its dialect. Repositories call
+// it exactly like *sql.DB.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+func New(db *sql.DB, dialect Dialect) *DB {
+	return &DB{DB: db, Dialect: dialect}
+}
+
+// Open connects with the pool settings from cfg and pings the server.
+func Open(cfg config.DatabaseConfig) (*DB, error) {
+	dialect := Dialect(cfg.Driver)
+	dsn := cfg.DSN
+	switch dialect {
+	case MySQL:
+		c, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		// Report matched rather than changed rows, like the other drivers,
+		// so an UPDATE that writes the current values still counts.
+		c.ClientFoundRows = true
+		dsn = c.FormatDSN()
+	case Postgres:
+	case SQLite:
+		dsn = sqliteDSN(cfg)
+	default:
+		return nil, fmt.Errorf("database: unsupported driver %q", cfg.Driver)
+	}
+	db, err := sql.Open(dialect.driverName(), dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if dialect == SQLite && isMemory(cfg.DSN) {
+		// The database lives as long as one connection to it does.
+		db.SetMaxIdleConns(max(cfg.MaxIdleConns, 1))
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return New(db, dialect), nil
+}
+
+// sqliteDSN adds the connection settings every SQLite connection needs:
+// WAL so readers do not block the writer, a busy timeout so writers queue
+// instead of failing, enforced foreign keys for the ON DELETE rules, and
+// transactions that take the write lock up front in place of FOR UPDATE.
+//
+// ":memory:" becomes a named memdb database: unlike plain :memory:, every
+// connection in the pool sees the same data.
+func sqliteDSN(cfg config.DatabaseConfig) string {
+	dsn := cfg.DSN
+	if dsn == ":memory:" {
+		dsn = fmt.Sprintf("file:/planify-%d?vfs=memdb", memoryDBs.Add(1))
+	}
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout.Milliseconds()))
+	q.Add("_pragma", "foreign_keys(1)")
+	if !isMemory(cfg.DSN) {
+		q.Add("_pragma", "journal_mode(WAL)")
+		q.Add("_pragma", "synchronous(NORMAL)")
+	}
+	q.Set("_txlock", "immediate")
+	q.Set("_time_format", "sqlite")
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + q.Encode()
+}
+
+var memoryDBs atomic.Int64
+
+func isMemory(dsn string) bool {
+	return dsn == ":memory:" || strings.Contains(dsn, "vfs=memdb") || strings.Contains(dsn, "mode=memory")
+}
+
+func (db *DB) Rebind(query string) string {
+	return db.Dialect.Rebind(query)
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.DB.Exec(db.Rebind(query), args...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.Rebind(query), args...)
+}
+
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.DB.Query(db.Rebind(query), args...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.Rebind(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.DB.QueryRow(db.Rebind(query), args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.Rebind(query), args...)
+}
+
+func (db *DB) Prepare(query string) (*sql.Stmt, error) {
+	return db.DB.Prepare(db.Rebind(query))
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, Dialect: db.Dialect}, nil
+}
+
+// InsertID runs an INSERT and returns the generated id column.
+func (db *DB) InsertID(query string, args ...any) (int64, error) {
+	return insertID(db.Dialect, db.DB, query, args)
+}
+
+// Tx is the transaction counterpart of DB.
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.Tx.Exec(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRow(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.Tx.Prepare(tx.Dialect.Rebind(query))
+}
+
+func (tx *Tx) InsertID(query string, args ...any) (int64, error) {
+	return insertID(tx.Dialect, tx.Tx, query, args)
+}
+
+type execQueryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// insertID uses LastInsertId on MySQL and RETURNING id on Postgres, whose
+// driver does not implement LastInsertId.
+func insertID(d Dialect, q execQueryer, query string, args []any) (int64, error) {
+	if d == Postgres {
+		var id int64
+		err := q.QueryRow(d.Rebind(query)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	res, err := q.Exec(d.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect names the SQL flavour a DB speaks. Queries in the repositories
+// are written for MySQL with ? placeholders and rewritten per dialect.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+var forUpdate = regexp.MustCompile(`(?i)\s+FOR\s+UPDATE\b`)
+
+// driverName is the database/sql driver registered for the dialect.
+func (d Dialect) driverName() string {
+	if d == Postgres {
+		return "pgx"
+	}
+	return string(d)
+}
+
+// Rebind adapts a query written for MySQL: Postgres gets $1, $2, ...
+// placeholders (question marks inside quoted strings are left alone), and
+// SQLite, which has no row locks, drops FOR UPDATE. Its transactions take
+// the write lock when they begin instead.
+func (d Dialect) Rebind(query string) string {
+	if d == SQLite {
+		return forUpdate.ReplaceAllString(query, "")
+	}
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// InsertIgnore turns an "INSERT INTO ..." statement into one that silently
+// skips rows that would violate a unique or primary key.
+func (d Dialect) InsertIgnore(query string) string {
+	switch d {
+	case Postgres:
+		return query + " ON CONFLICT DO NOTHING"
+	case SQLite:
+		return strings.Replace(query, "INSERT INTO", "INSERT OR IGNORE INTO", 1)
+	}
+	return strings.Replace(query, "INSERT INTO", "INSERT IGNORE INTO", 1)
+}
+
+// TransactionalDDL reports whether schema changes can be rolled back.
+func (d Dialect) TransactionalDDL() bool {
+	return d == Postgres || d == SQLite
+}
+
+// IsUniqueViolation reports whether err is a duplicate-key error from any
+// of the supported drivers.
+func IsUniqueViolation(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1062
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// IsForeignKeyViolation reports whether err is a missing-parent error from
+// any of the supported drivers.
+func IsForeignKeyViolation(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1452
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23503"
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*State
+	sweep   time.Time
+}
+
+// NewMemoryStore keeps state in process. Entries idle for longer than ttl
+// are dropped.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, entries: make(map[string]*State)}
+}
+
+func (m *MemoryStore) Get(key string) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.entries[key]; ok {
+		return *s, nil
+	}
+	return State{}, nil
+}
+
+func (m *MemoryStore) AddFailure(key string, now time.Time, window time.Duration) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.After(m.sweep) {
+		for k, s := range m.entries {
+			if now.Sub(s.LastFailure) > m.ttl && now.After(s.LockedUntil) {
+				delete(m.entries, k)
+			}
+		}
+		m.sweep = now.Add(m.ttl)
+	}
+
+	s, ok := m.entries[key]
+	if !ok {
+		s = &State{}
+		m.entries[key] = s
+	}
+	if now.Sub(s.LastFailure) > window {
+		s.Failures = 0
+	}
+	s.Failures++
+	s.LastFailure = now
+	return *s, nil
+}
+
+func (m *MemoryStore) Lock(key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.entries[key]; ok {
+		s.LockedUntil = until
+	} else {
+		m.entries[key] = &State{LockedUntil: until}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Clear(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
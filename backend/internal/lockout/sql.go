@@ -0,0 +1,77 @@
+package lockout
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"planify/backend/internal/database"
+)
+
+// SQLStore keeps state in the login_failures table so every node sees the
+// same counters.
+type SQLStore struct {
+	DB *database.DB
+}
+
+func (s *SQLStore) Get(key string) (State, error) {
+	var st State
+	var locked sql.NullTime
+	err := s.DB.QueryRow(
+		`SELECT failures, last_failure_at, locked_until FROM login_failures WHERE lock_key = ?`, key,
+	).Scan(&st.Failures, &st.LastFailure, &locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, nil
+	}
+	st.LockedUntil = locked.Time
+	return st, err
+}
+
+func (s *SQLStore) AddFailure(key string, now time.Time, window time.Duration) (State, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return State{}, err
+	}
+	defer tx.Rollback()
+
+	var st State
+	var locked sql.NullTime
+	err = tx.QueryRow(
+		`SELECT failures, last_failure_at, locked_until FROM login_failures WHERE lock_key = ? FOR UPDATE`, key,
+	).Scan(&st.Failures, &st.LastFailure, &locked)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		st = State{Failures: 1, LastFailure: now}
+		if _, err := tx.Exec(
+			`INSERT INTO login_failures (lock_key, failures, last_failure_at) VALUES (?, 1, ?)`, key, now.UTC(),
+		); err != nil {
+			return State{}, err
+		}
+	case err != nil:
+		return State{}, err
+	default:
+		st.LockedUntil = locked.Time
+		if now.Sub(st.LastFailure) > window {
+			st.Failures = 0
+		}
+		st.Failures++
+		st.LastFailure = now
+		if _, err := tx.Exec(
+			`UPDATE login_failures SET failures = ?, last_failure_at = ? WHERE lock_key = ?`,
+			st.Failures, now.UTC(), key,
+		); err != nil {
+			return State{}, err
+		}
+	}
+	return st, tx.Commit()
+}
+
+func (s *SQLStore) Lock(key string, until time.Time) error {
+	_, err := s.DB.Exec(`UPDATE login_failures SET locked_until = ? WHERE lock_key = ?`, until.UTC(), key)
+	return err
+}
+
+func (s *SQLStore) Clear(key string) error {
+	_, err := s.DB.Exec(`DELETE FROM login_failures WHERE lock_key = ?`, key)
+	return err
+}
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"planify/backend/internal/config"
+)
+
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New picks a Mailer for the configured driver. The "log" driver only prints
+// messages and is meant for local development.
+func New(cfg config.MailConfig) Mailer {
+	switch strings.ToLower(cfg.Driver) {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     cfg.Host,
+			Port:     cfg.Port,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			From:     cfg.From,
+			StartTLS: cfg.StartTLS,
+			Timeout:  cfg.Timeout,
+		}
+	default:
+		return LogMailer{}
+	}
+}
+
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("[mail] to=%s subject=%q\n%s", strings.Join(msg.To, ","), msg.Subject, msg.Text)
+	return nil
+}
@@ -0,0 +1,147 @@
+package mail
+
+import (
+	"context"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// received is one message accepted by sink.
+type received struct {
+	from string
+	to   []string
+	data string
+}
+
+// sink is a minimal SMTP server that accepts every message, much like
+// MailHog. It does not offer STARTTLS or AUTH.
+func sink(t *testing.T) (host string, port int, got <-chan received) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan received, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, out)
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, out
+}
+
+func serveSMTP(conn net.Conn, out chan<- received) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ready")
+	var msg received
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-sink")
+			tp.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			msg = received{from: bracketed(arg)}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, bracketed(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			msg.data = strings.Join(lines, "\n")
+			out <- msg
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// bracketed returns the address between the angle brackets of a MAIL or
+// RCPT argument, dropping parameters such as BODY=8BITMIME.
+func bracketed(arg string) string {
+	_, addr, _ := strings.Cut(arg, "<")
+	addr, _, _ = strings.Cut(addr, ">")
+	return addr
+}
+
+func TestSMTPMailerDeliversToSink(t *testing.T) {
+	host, port, got := sink(t)
+	m := &SMTPMailer{Host: host, Port: port, From: "Planify <no-reply@planify.local>", Timeout: 5 * time.Second}
+	link := "http://localhost:5173/#/verify-email?token=abc%2Bdef%3D"
+	err := m.Send(context.Background(), Message{
+		To:      []string{"ada@example.com"},
+		Subject: "Confirm your Planify account ✓",
+		Text:    "Hi Ada,\n\nConfirm your email address:\n" + link + "\n.\nThanks",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg received
+	select {
+	case msg = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sink received nothing")
+	}
+	if msg.from != "no-reply@planify.local" || len(msg.to) != 1 || msg.to[0] != "ada@example.com" {
+		t.Fatalf("envelope from %q to %q", msg.from, msg.to)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(msg.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := parsed.Header
+	from, err := mail.ParseAddress(h.Get("From"))
+	if err != nil || from.Address != "no-reply@planify.local" || from.Name != "Planify" {
+		t.Fatalf("From header %q: %v", h.Get("From"), err)
+	}
+	if h.Get("To") != "ada@example.com" {
+		t.Fatalf("To header %q", h.Get("To"))
+	}
+	if s, err := new(mime.WordDecoder).DecodeHeader(h.Get("Subject")); err != nil || s != "Confirm your Planify account ✓" {
+		t.Fatalf("Subject header %q decodes to %q: %v", h.Get("Subject"), s, err)
+	}
+	if !strings.HasSuffix(h.Get("Message-ID"), "@planify.local>") || h.Get("Date") == "" {
+		t.Fatalf("Message-ID %q, Date %q", h.Get("Message-ID"), h.Get("Date"))
+	}
+	if h.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("Content-Type %q", h.Get("Content-Type"))
+	}
+	body := msg.data[strings.Index(msg.data, "\n\n")+2:]
+	if !strings.Contains(body, "\n"+link+"\n.\n") {
+		t.Fatalf("verification link or dot line mangled in body %q", body)
+	}
+}
+
+func TestSMTPMailerRequiresStartTLS(t *testing.T) {
+	host, port, _ := sink(t)
+	m := &SMTPMailer{Host: host, Port: port, From: "no-reply@planify.local", StartTLS: true, Timeout: 5 * time.Second}
+	err := m.Send(context.Background(), Message{To: []string{"ada@example.com"}, Subject: "Hi", Text: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("got %v, want a STARTTLS error", err)
+	}
+}
@@ -0,0 +1,408 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"planify/backend/internal/database"
+)
+
+//go:embed migrations
+var embedded embed.FS
+
+// SourceDir is where `migrate create` writes new files, relative to the
+// backend module root.
+const SourceDir = "internal/migrate/migrations"
+
+// lockID keys the Postgres advisory lock; any constant shared by every
+// instance works.
+const lockID int64 = 0x706c616e696679
+
+var errLockTimeout = errors.New("migrate: timed out waiting for another instance to finish migrating")
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the embedded migrations for one SQL dialect and records
+// them in schema_migrations.
+type Migrator struct {
+	DB         *database.DB
+	Migrations []Migration
+	// LockTimeout bounds how long Up and Down wait for another instance that
+	// is migrating the same database.
+	LockTimeout time.Duration
+}
+
+func New(db *database.DB) (*Migrator, error) {
+	ms, err := Load(embedded, "migrations/"+string(db.Dialect))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: ms, LockTimeout: time.Minute}, nil
+}
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql pairs from dir.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: no migrations for %s: %w", filepath.Base(dir), err)
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by both %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: %04d_%s has no up migration", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]time.Time{}
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := done[mig.Version]; ok {
+			s.AppliedAt = &at
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, mig.Version, mig.Name,
+			); err != nil {
+				return fmt.Errorf("migrate: %04d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down reverts the most recent steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0 && n < steps; i-- {
+			mig := m.Migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migrate: %04d_%s cannot be reverted", mig.Version, mig.Name)
+			}
+			if err := m.run(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = ?`, mig.Version,
+			); err != nil {
+				return fmt.Errorf("migrate: %04d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Baseline records every migration up to and including version as applied
+// without running it. It adopts a database whose schema was created before
+// migrations existed, so Up starts after the baseline.
+func (m *Migrator) Baseline(ctx context.Context, version int64) (int, error) {
+	if len(m.Migrations) == 0 || version < m.Migrations[0].Version {
+		return 0, fmt.Errorf("migrate: no migration at or below version %d", version)
+	}
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, "",
+				`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, mig.Version, mig.Name,
+			); err != nil {
+				return fmt.Errorf("migrate: baseline %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// run executes a migration one statement at a time, then the bookkeeping
+// statement: the MySQL driver rejects multi-statement queries unless the
+// DSN opts in. Postgres runs it all in one transaction; MySQL commits DDL
+// implicitly, so a failed migration may leave earlier statements applied.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	stmts := splitStatements(script)
+	record = m.DB.Rebind(record)
+	if !m.DB.Dialect.TransactionalDDL() {
+		return execScript(ctx, conn, stmts, record, args)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := execScript(ctx, tx, stmts, record, args); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func execScript(ctx context.Context, e execer, stmts []string, record string, args []any) error {
+	for _, stmt := range stmts {
+		if _, err := e.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	_, err := e.ExecContext(ctx, record, args...)
+	return err
+}
+
+// lock takes the migration lock on conn: GET_LOCK on MySQL, a session
+// advisory lock on Postgres. Both are released when unlock runs or the
+// connection closes. SQLite has no such lock; a SQLite file is served by a
+// single process, and each migration's transaction holds the write lock.
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) (unlock func(), err error) {
+	switch m.DB.Dialect {
+	case database.SQLite:
+		return func() {}, nil
+	case database.Postgres:
+		deadline := time.Now().Add(m.LockTimeout)
+		for {
+			var got bool
+			if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockID).Scan(&got); err != nil {
+				return nil, err
+			}
+			if got {
+				return func() { conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID) }, nil
+			}
+			if time.Now().After(deadline) {
+				return nil, errLockTimeout
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	}
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx,
+		`SELECT GET_LOCK('planify_migrate', ?)`, int(m.LockTimeout/time.Second),
+	).Scan(&got); err != nil {
+		return nil, err
+	}
+	if got.Int64 != 1 {
+		return nil, errLockTimeout
+	}
+	return func() { conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK('planify_migrate')`) }, nil
+}
+
+// locked runs fn while holding a database-wide advisory lock so that only
+// one instance migrates at a time.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// splitStatements splits a script on semicolons that end a line, dropping
+// blank lines and -- comments.
+func splitStatements(script string) []string {
+	var out []string
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			out = append(out, strings.TrimSuffix(strings.TrimSpace(cur.String()), ";"))
+			cur.Reset()
+		}
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Create writes an empty up/down pair into every dialect directory under
+// root, numbered after the newest migration in any of them, and returns the
+// paths. Dialects share version numbers so a change lands in all of them.
+func Create(root, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, errors.New("migrate: name is required")
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	var dirs []string
+	next := int64(1)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		existing, err := Load(os.DirFS(dir), ".")
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 && existing[len(existing)-1].Version >= next {
+			next = existing[len(existing)-1].Version + 1
+		}
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("migrate: no dialect directories in %s", root)
+	}
+	var paths []string
+	for _, dir := range dirs {
+		base := filepath.Join(dir, fmt.Sprintf("%04d_%s", next, name))
+		for _, p := range []string{base + ".up.sql", base + ".down.sql"} {
+			if err := os.WriteFile(p, []byte("-- "+filepath.Base(p)+"\n"), 0o644); err != nil {
+				return nil, err
+			}
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"planify/backend/internal/config"
+	"planify/backend/internal/database"
+)
+
+// TestBaselineAdoptsLegacySchema builds the schema by hand the way
+// deployments did before migrations, then adopts it with Baseline.
+func TestBaselineAdoptsLegacySchema(t *testing.T) {
+	cfg := config.Defaults().Database
+	cfg.Driver = string(database.SQLite)
+	cfg.DSN = filepath.Join(t.TempDir(), "legacy.db")
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, stmt := range splitStatements(m.Migrations[0].Up) {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO users (name, email, password) VALUES ('Ada', 'ada@example.com', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "0001_core_schema") {
+		t.Fatalf("up on an unadopted database: %v", err)
+	}
+	if _, err := m.Baseline(ctx, 0); err == nil {
+		t.Fatal("baseline accepted version 0")
+	}
+	if n, err := m.Baseline(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("baseline: %d, %v", n, err)
+	}
+	if n, err := m.Baseline(ctx, 1); err != nil || n != 0 {
+		t.Fatalf("second baseline: %d, %v", n, err)
+	}
+	n, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(m.Migrations)-1 {
+		t.Fatalf("applied %d migrations, want %d", n, len(m.Migrations)-1)
+	}
+
+	var verified, admin bool
+	if err := db.QueryRow(
+		`SELECT email_verified_at IS NOT NULL, is_admin FROM users WHERE email = 'ada@example.com'`,
+	).Scan(&verified, &admin); err != nil {
+		t.Fatal(err)
+	}
+	if !verified || admin {
+		t.Fatalf("existing user verified=%v admin=%v", verified, admin)
+	}
+	if _, err := db.Exec(`INSERT INTO project_members (project_id, user_id, role, source) SELECT 1, 1, 'member', 'ldap' WHERE 0`); err != nil {
+		t.Fatalf("project_members.source missing: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE projects SET require_2fa = TRUE WHERE 0`); err != nil {
+		t.Fatalf("projects.require_2fa missing: %v", err)
+	}
+}
+
+func TestCreateNumbersEveryDialect(t *testing.T) {
+	root := t.TempDir()
+	for dialect, files := range map[string][]string{
+		"mysql":    {"0001_core.up.sql", "0001_core.down.sql", "0002_more.up.sql"},
+		"postgres": {"0001_core.up.sql", "0003_newest.up.sql"},
+		"sqlite":   {},
+	} {
+		dir := filepath.Join(root, dialect)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			if err := os.WriteFile(filepath.Join(dir, f), []byte("SELECT 1;\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	paths, err := Create(root, "Add Task Labels!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range paths {
+		rel, _ := filepath.Rel(root, p)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := "mysql/0004_add_task_labels.up.sql mysql/0004_add_task_labels.down.sql " +
+		"postgres/0004_add_task_labels.up.sql postgres/0004_add_task_labels.down.sql " +
+		"sqlite/0004_add_task_labels.up.sql sqlite/0004_add_task_labels.down.sql"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("created %v", got)
+	}
+
+	if _, err := Create(root, " -- "); err == nil {
+		t.Fatal("created a migration without a name")
+	}
+	if _, err := Create(filepath.Join(root, "missing"), "x"); err == nil {
+		t.Fatal("created migrations in a missing directory")
+	}
+}
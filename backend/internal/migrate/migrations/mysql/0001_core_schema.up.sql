@@ -0,0 +1,105 @@
+CREATE TABLE users (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    password   VARCHAR(255) NOT NULL DEFAULT '',
+    avatar     VARCHAR(512) NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_users_email (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE projects (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    due_date    DATE NULL,
+    owner_id    INT NULL,
+    created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_projects_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE project_members (
+    project_id INT NOT NULL,
+    user_id    INT NOT NULL,
+    role       VARCHAR(20) NOT NULL DEFAULT 'member',
+    PRIMARY KEY (project_id, user_id),
+    KEY idx_project_members_user (user_id),
+    CONSTRAINT fk_project_members_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_project_members_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE statuses (
+    id       INT AUTO_INCREMENT PRIMARY KEY,
+    title    VARCHAR(100) NOT NULL,
+    position INT NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO statuses (id, title, position) VALUES
+    (1, 'To Do', 0),
+    (2, 'In Progress', 1),
+    (3, 'Done', 2);
+
+CREATE TABLE tasks (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    project_id  INT NOT NULL,
+    status_id   INT NOT NULL,
+    title       VARCHAR(255) NOT NULL,
+    description TEXT NULL,
+    priority    VARCHAR(20) NULL,
+    position    INT NOT NULL DEFAULT 0,
+    created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_tasks_project (project_id),
+    KEY idx_tasks_status_position (status_id, position),
+    CONSTRAINT fk_tasks_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_tasks_status FOREIGN KEY (status_id) REFERENCES statuses (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE task_assignees (
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    PRIMARY KEY (task_id, user_id),
+    KEY idx_task_assignees_user (user_id),
+    CONSTRAINT fk_task_assignees_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_assignees_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE task_collaborators (
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    PRIMARY KEY (task_id, user_id),
+    KEY idx_task_collaborators_user (user_id),
+    CONSTRAINT fk_task_collaborators_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_collaborators_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE attachments (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    task_id     INT NOT NULL,
+    file_name   VARCHAR(255) NOT NULL,
+    stored_name VARCHAR(255) NOT NULL,
+    size        BIGINT NOT NULL DEFAULT 0,
+    created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_attachments_task (task_id),
+    CONSTRAINT fk_attachments_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE task_comments (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    task_id    INT NOT NULL,
+    user_id    INT NULL,
+    text       TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_task_comments_task (task_id),
+    KEY idx_task_comments_user (user_id),
+    CONSTRAINT fk_task_comments_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_comments_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE user_settings (
+    user_id                INT PRIMARY KEY,
+    notifications_assign   BOOLEAN NOT NULL DEFAULT TRUE,
+    notifications_due_date BOOLEAN NOT NULL DEFAULT TRUE,
+    notifications_comments BOOLEAN NOT NULL DEFAULT TRUE,
+    appearance_theme       VARCHAR(20) NOT NULL DEFAULT 'Automatic',
+    CONSTRAINT fk_user_settings_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,36 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE personal_access_tokens (
+    id           INT AUTO_INCREMENT PRIMARY KEY,
+    user_id      INT NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash   CHAR(64) NOT NULL,
+    scopes       VARCHAR(255) NOT NULL,
+    created_at   DATETIME NOT NULL,
+    last_used_at DATETIME NULL,
+    expires_at   DATETIME NOT NULL,
+    revoked_at   DATETIME NULL,
+    UNIQUE KEY uq_personal_access_tokens_hash (token_hash),
+    KEY idx_personal_access_tokens_user (user_id),
+    CONSTRAINT fk_personal_access_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE login_failures (
+    lock_key        VARCHAR(320) PRIMARY KEY,
+    failures        INT NOT NULL DEFAULT 0,
+    last_failure_at DATETIME NOT NULL,
+    locked_until    DATETIME NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE auth_events (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id    INT NULL,
+    event      VARCHAR(32) NOT NULL,
+    login      VARCHAR(255) NOT NULL DEFAULT '',
+    ip         VARCHAR(45) NOT NULL DEFAULT '',
+    detail     VARCHAR(512) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    KEY idx_auth_events_event (event, id),
+    CONSTRAINT fk_auth_events_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
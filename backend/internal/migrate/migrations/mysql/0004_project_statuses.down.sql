@@ -0,0 +1,24 @@
+INSERT INTO statuses (id, title, position, category) VALUES
+    (1, 'To Do', 0, 'todo'),
+    (2, 'In Progress', 1, 'in_progress'),
+    (3, 'Done', 2, 'done');
+
+UPDATE tasks SET status_id = CASE (SELECT category FROM statuses WHERE id = tasks.status_id)
+    WHEN 'done' THEN 3
+    WHEN 'in_progress' THEN 2
+    ELSE 1
+END;
+
+DELETE FROM statuses WHERE project_id IS NOT NULL;
+
+ALTER TABLE statuses DROP FOREIGN KEY fk_statuses_project;
+
+DROP INDEX idx_statuses_project ON statuses;
+
+ALTER TABLE statuses DROP COLUMN archived_at;
+
+ALTER TABLE statuses DROP COLUMN color;
+
+ALTER TABLE statuses DROP COLUMN category;
+
+ALTER TABLE statuses DROP COLUMN project_id;
@@ -0,0 +1,12 @@
+CREATE TABLE status_transitions (
+    id              INT AUTO_INCREMENT PRIMARY KEY,
+    project_id      INT NOT NULL,
+    from_status_id  INT NULL,
+    to_status_id    INT NOT NULL,
+    roles           VARCHAR(255) NOT NULL DEFAULT '',
+    required_fields VARCHAR(255) NOT NULL DEFAULT '',
+    KEY idx_status_transitions_project (project_id),
+    CONSTRAINT fk_status_transitions_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_status_transitions_from FOREIGN KEY (from_status_id) REFERENCES statuses (id) ON DELETE CASCADE,
+    CONSTRAINT fk_status_transitions_to FOREIGN KEY (to_status_id) REFERENCES statuses (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
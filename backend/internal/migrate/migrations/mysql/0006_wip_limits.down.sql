@@ -0,0 +1,5 @@
+ALTER TABLE statuses DROP COLUMN wip_per_assignee;
+
+ALTER TABLE statuses DROP COLUMN wip_mode;
+
+ALTER TABLE statuses DROP COLUMN wip_limit;
@@ -0,0 +1,5 @@
+ALTER TABLE statuses ADD COLUMN wip_limit INT NULL;
+
+ALTER TABLE statuses ADD COLUMN wip_mode VARCHAR(10) NOT NULL DEFAULT 'soft';
+
+ALTER TABLE statuses ADD COLUMN wip_per_assignee BOOLEAN NOT NULL DEFAULT FALSE;
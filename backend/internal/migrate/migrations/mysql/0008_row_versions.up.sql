@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE projects ADD COLUMN version INT NOT NULL DEFAULT 1;
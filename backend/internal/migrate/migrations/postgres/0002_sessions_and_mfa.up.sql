@@ -0,0 +1,80 @@
+-- Columns added to the core tables since 0001. A database adopted with
+-- `migrate baseline` gets them here; its existing accounts count as verified.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL;
+
+UPDATE users SET email_verified_at = created_at;
+
+ALTER TABLE projects ADD COLUMN require_2fa BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE project_members ADD COLUMN source VARCHAR(20) NULL;
+
+CREATE TABLE password_resets (
+    id         INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id    INT NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_password_resets_token UNIQUE (token_hash),
+    CONSTRAINT fk_password_resets_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_resets_user ON password_resets (user_id);
+
+CREATE TABLE sessions (
+    id           VARCHAR(64) PRIMARY KEY,
+    user_id      INT NOT NULL,
+    user_agent   VARCHAR(512) NOT NULL DEFAULT '',
+    ip           VARCHAR(45) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP NULL,
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sessions_user ON sessions (user_id);
+
+CREATE TABLE refresh_tokens (
+    id         INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_refresh_tokens_token UNIQUE (token_hash),
+    CONSTRAINT fk_refresh_tokens_session FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_refresh_tokens_session ON refresh_tokens (session_id);
+
+CREATE TABLE user_mfa (
+    user_id        INT PRIMARY KEY,
+    secret         VARCHAR(64) NOT NULL,
+    enabled_at     TIMESTAMP NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT fk_user_mfa_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_recovery_codes (
+    id        INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id   INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at   TIMESTAMP NULL,
+    CONSTRAINT fk_user_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_recovery_codes_user ON user_recovery_codes (user_id, code_hash);
+
+CREATE TABLE user_identities (
+    id         INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id    INT NOT NULL,
+    provider   VARCHAR(64) NOT NULL,
+    subject    VARCHAR(512) NOT NULL,
+    email      VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_user_identities_subject UNIQUE (provider, subject),
+    CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_identities_user ON user_identities (user_id);
@@ -0,0 +1,4 @@
+DROP TABLE auth_events;
+DROP TABLE login_failures;
+DROP TABLE personal_access_tokens;
+ALTER TABLE users DROP COLUMN is_admin;
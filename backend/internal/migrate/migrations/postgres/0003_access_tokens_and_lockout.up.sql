@@ -0,0 +1,38 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE personal_access_tokens (
+    id           INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id      INT NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash   CHAR(64) NOT NULL,
+    scopes       VARCHAR(255) NOT NULL,
+    created_at   TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL,
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP NULL,
+    CONSTRAINT uq_personal_access_tokens_hash UNIQUE (token_hash),
+    CONSTRAINT fk_personal_access_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_personal_access_tokens_user ON personal_access_tokens (user_id);
+
+CREATE TABLE login_failures (
+    lock_key        VARCHAR(320) PRIMARY KEY,
+    failures        INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until    TIMESTAMP NULL
+);
+
+CREATE TABLE auth_events (
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id    INT NULL,
+    event      VARCHAR(32) NOT NULL,
+    login      VARCHAR(255) NOT NULL DEFAULT '',
+    ip         VARCHAR(45) NOT NULL DEFAULT '',
+    detail     VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_auth_events_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_auth_events_event ON auth_events (event, id);
@@ -0,0 +1,13 @@
+CREATE TABLE status_transitions (
+    id              INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    project_id      INT NOT NULL,
+    from_status_id  INT NULL,
+    to_status_id    INT NOT NULL,
+    roles           VARCHAR(255) NOT NULL DEFAULT '',
+    required_fields VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT fk_status_transitions_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_status_transitions_from FOREIGN KEY (from_status_id) REFERENCES statuses (id) ON DELETE CASCADE,
+    CONSTRAINT fk_status_transitions_to FOREIGN KEY (to_status_id) REFERENCES statuses (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_status_transitions_project ON status_transitions (project_id);
@@ -0,0 +1,16 @@
+ALTER TABLE tasks ADD COLUMN position INT NOT NULL DEFAULT 0;
+
+UPDATE tasks SET position = r.pos
+FROM (
+    SELECT a.id, COUNT(b.id) AS pos
+    FROM tasks a
+    LEFT JOIN tasks b ON b.status_id = a.status_id AND b.rank_key < a.rank_key
+    GROUP BY a.id
+) r
+WHERE r.id = tasks.id;
+
+CREATE INDEX idx_tasks_status_position ON tasks (status_id, position);
+
+DROP INDEX idx_tasks_status_rank;
+
+ALTER TABLE tasks DROP COLUMN rank_key;
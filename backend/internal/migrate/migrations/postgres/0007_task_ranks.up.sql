@@ -0,0 +1,12 @@
+-- Tasks are ordered by string ranks compared byte by byte. Existing tasks
+-- keep their order: the rank is the old position, then the id as a
+-- tiebreaker, both zero-padded. The rebalance job shortens them later.
+ALTER TABLE tasks ADD COLUMN rank_key VARCHAR(255) COLLATE "C" NOT NULL DEFAULT 'i';
+
+UPDATE tasks SET rank_key = LPAD(GREATEST(position, 0)::text, 10, '0') || LPAD(id::text, 10, '0') || 'i';
+
+CREATE INDEX idx_tasks_status_rank ON tasks (status_id, rank_key);
+
+DROP INDEX idx_tasks_status_position;
+
+ALTER TABLE tasks DROP COLUMN position;
@@ -0,0 +1,10 @@
+DROP TABLE user_settings;
+DROP TABLE task_comments;
+DROP TABLE attachments;
+DROP TABLE task_collaborators;
+DROP TABLE task_assignees;
+DROP TABLE tasks;
+DROP TABLE statuses;
+DROP TABLE project_members;
+DROP TABLE projects;
+DROP TABLE users;
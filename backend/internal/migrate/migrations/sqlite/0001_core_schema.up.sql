@@ -0,0 +1,113 @@
+CREATE TABLE users (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    password   VARCHAR(255) NOT NULL DEFAULT '',
+    avatar     VARCHAR(512) NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_users_email UNIQUE (email)
+);
+
+CREATE TABLE projects (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    due_date    DATE NULL,
+    owner_id    INT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_projects_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE TABLE project_members (
+    project_id INT NOT NULL,
+    user_id    INT NOT NULL,
+    role       VARCHAR(20) NOT NULL DEFAULT 'member',
+    PRIMARY KEY (project_id, user_id),
+    CONSTRAINT fk_project_members_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_project_members_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_project_members_user ON project_members (user_id);
+
+CREATE TABLE statuses (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    title    VARCHAR(100) NOT NULL,
+    position INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO statuses (id, title, position) VALUES
+    (1, 'To Do', 0),
+    (2, 'In Progress', 1),
+    (3, 'Done', 2);
+
+CREATE TABLE tasks (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id  INT NOT NULL,
+    status_id   INT NOT NULL,
+    title       VARCHAR(255) NOT NULL,
+    description TEXT NULL,
+    priority    VARCHAR(20) NULL,
+    position    INT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_tasks_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_tasks_status FOREIGN KEY (status_id) REFERENCES statuses (id)
+);
+
+CREATE INDEX idx_tasks_project ON tasks (project_id);
+
+CREATE INDEX idx_tasks_status_position ON tasks (status_id, position);
+
+CREATE TABLE task_assignees (
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    PRIMARY KEY (task_id, user_id),
+    CONSTRAINT fk_task_assignees_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_assignees_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_task_assignees_user ON task_assignees (user_id);
+
+CREATE TABLE task_collaborators (
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    PRIMARY KEY (task_id, user_id),
+    CONSTRAINT fk_task_collaborators_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_collaborators_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_task_collaborators_user ON task_collaborators (user_id);
+
+CREATE TABLE attachments (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id     INT NOT NULL,
+    file_name   VARCHAR(255) NOT NULL,
+    stored_name VARCHAR(255) NOT NULL,
+    size        BIGINT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_attachments_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_attachments_task ON attachments (task_id);
+
+CREATE TABLE task_comments (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id    INT NOT NULL,
+    user_id    INT NULL,
+    text       TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_task_comments_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_comments_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_task_comments_task ON task_comments (task_id);
+
+CREATE INDEX idx_task_comments_user ON task_comments (user_id);
+
+CREATE TABLE user_settings (
+    user_id                INT PRIMARY KEY,
+    notifications_assign   BOOLEAN NOT NULL DEFAULT TRUE,
+    notifications_due_date BOOLEAN NOT NULL DEFAULT TRUE,
+    notifications_comments BOOLEAN NOT NULL DEFAULT TRUE,
+    appearance_theme       VARCHAR(20) NOT NULL DEFAULT 'Automatic',
+    CONSTRAINT fk_user_settings_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
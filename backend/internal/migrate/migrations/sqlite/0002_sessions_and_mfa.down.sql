@@ -0,0 +1,9 @@
+DROP TABLE user_identities;
+DROP TABLE user_recovery_codes;
+DROP TABLE user_mfa;
+DROP TABLE refresh_tokens;
+DROP TABLE sessions;
+DROP TABLE password_resets;
+ALTER TABLE project_members DROP COLUMN source;
+ALTER TABLE projects DROP COLUMN require_2fa;
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,33 @@
+-- SQLite cannot drop a column that carries a foreign key, so the table is
+-- rebuilt. Deferring foreign keys lets tasks point at the global rows while
+-- they are copied across.
+PRAGMA defer_foreign_keys = ON;
+
+INSERT INTO statuses (id, title, position, category) VALUES
+    (1, 'To Do', 0, 'todo'),
+    (2, 'In Progress', 1, 'in_progress'),
+    (3, 'Done', 2, 'done');
+
+UPDATE tasks SET status_id = CASE (SELECT category FROM statuses WHERE id = tasks.status_id)
+    WHEN 'done' THEN 3
+    WHEN 'in_progress' THEN 2
+    ELSE 1
+END;
+
+DELETE FROM statuses WHERE project_id IS NOT NULL;
+
+CREATE TABLE statuses_down AS SELECT id, title, position FROM statuses;
+
+DROP INDEX idx_statuses_project;
+
+DROP TABLE statuses;
+
+CREATE TABLE statuses (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    title    VARCHAR(100) NOT NULL,
+    position INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO statuses (id, title, position) SELECT id, title, position FROM statuses_down;
+
+DROP TABLE statuses_down;
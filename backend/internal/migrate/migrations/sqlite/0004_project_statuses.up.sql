@@ -0,0 +1,31 @@
+ALTER TABLE statuses ADD COLUMN project_id INT NULL REFERENCES projects (id) ON DELETE CASCADE;
+
+ALTER TABLE statuses ADD COLUMN category VARCHAR(20) NOT NULL DEFAULT 'todo';
+
+ALTER TABLE statuses ADD COLUMN color VARCHAR(20) NULL;
+
+ALTER TABLE statuses ADD COLUMN archived_at TIMESTAMP NULL;
+
+CREATE INDEX idx_statuses_project ON statuses (project_id, position);
+
+UPDATE statuses SET category = 'in_progress' WHERE title = 'In Progress';
+
+UPDATE statuses SET category = 'done' WHERE title = 'Done';
+
+-- Every existing project gets its own copy of the global columns and its
+-- tasks are moved onto them before the global rows go away.
+INSERT INTO statuses (project_id, title, category, position)
+SELECT p.id, s.title, s.category, s.position
+FROM projects p
+CROSS JOIN statuses s
+WHERE s.project_id IS NULL;
+
+UPDATE tasks SET status_id = (
+    SELECT MIN(n.id)
+    FROM statuses o
+    JOIN statuses n ON n.title = o.title AND n.position = o.position
+    WHERE o.id = tasks.status_id AND n.project_id = tasks.project_id
+)
+WHERE status_id IN (SELECT id FROM statuses WHERE project_id IS NULL);
+
+DELETE FROM statuses WHERE project_id IS NULL;
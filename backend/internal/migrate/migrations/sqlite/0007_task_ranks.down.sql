@@ -0,0 +1,12 @@
+ALTER TABLE tasks ADD COLUMN position INT NOT NULL DEFAULT 0;
+
+UPDATE tasks SET position = (
+    SELECT COUNT(*) FROM tasks b
+    WHERE b.status_id = tasks.status_id AND b.rank_key < tasks.rank_key
+);
+
+CREATE INDEX idx_tasks_status_position ON tasks (status_id, position);
+
+DROP INDEX idx_tasks_status_rank;
+
+ALTER TABLE tasks DROP COLUMN rank_key;
@@ -0,0 +1,3 @@
+ALTER TABLE projects DROP COLUMN version;
+
+ALTER TABLE tasks DROP COLUMN version;
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type Scope string
+
+const (
+	ScopeProjectsRead  Scope = "projects:read"
+	ScopeProjectsAdmin Scope = "projects:admin"
+	ScopeTasksRead     Scope = "tasks:read"
+	ScopeTasksWrite    Scope = "tasks:write"
+	ScopeProfileRead   Scope = "profile:read"
+	ScopeProfileWrite  Scope = "profile:write"
+)
+
+var Scopes = []Scope{
+	ScopeProjectsRead, ScopeProjectsAdmin,
+	ScopeTasksRead, ScopeTasksWrite,
+	ScopeProfileRead, ScopeProfileWrite,
+}
+
+func (s Scope) Valid() bool {
+	for _, known := range Scopes {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessToken is a personal access token as shown to its owner. The secret
+// itself is only returned once, from the create call.
+type AccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+}
+
+func (t *AccessToken) HasScope(s Scope) bool {
+	for _, have := range t.Scopes {
+		if have == s {
+			return true
+		}
+	}
+	return false
+}
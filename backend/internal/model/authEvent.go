@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+const (
+	AuthEventLockout = "lockout"
+	AuthEventUnlock  = "unlock"
+)
+
+type AuthEvent struct {
+	ID        int       `json:"id"`
+	UserID    *int      `json:"userId"`
+	Event     string    `json:"event"`
+	Login     string    `json:"login"`
+	IP        string    `json:"ip"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"createdAt"`
+}
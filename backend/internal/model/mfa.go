@@ -0,0 +1,8 @@
+package model
+
+type MFA struct {
+	UserID       int
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
@@ -0,0 +1,68 @@
+package model
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+type Capability string
+
+const (
+	CapEditTasks         Capability = "edit_tasks"
+	CapComment           Capability = "comment"
+	CapChangeDueDate     Capability = "change_due_date"
+	CapManageMembers     Capability = "manage_members"
+	CapManageSettings    Capability = "manage_settings"
+	CapManageWorkflow    Capability = "manage_workflow"
+	CapTransferOwnership Capability = "transfer_ownership"
+	CapDeleteProject     Capability = "delete_project"
+)
+
+var roleCapabilities = map[Role][]Capability{
+	RoleOwner: {
+		CapEditTasks, CapComment, CapChangeDueDate, CapManageMembers,
+		CapManageSettings, CapManageWorkflow, CapTransferOwnership, CapDeleteProject,
+	},
+	RoleAdmin:  {CapEditTasks, CapComment, CapChangeDueDate, CapManageMembers, CapManageSettings, CapManageWorkflow},
+	RoleMember: {CapEditTasks, CapComment},
+	RoleViewer: {},
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleCapabilities[r]
+	return ok
+}
+
+func (r Role) Capabilities() []Capability {
+	return append([]Capability{}, roleCapabilities[r]...)
+}
+
+func (r Role) Can(c Capability) bool {
+	for _, have := range roleCapabilities[r] {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+type ProjectMember struct {
+	ID           int          `json:"id"`
+	Name         string       `json:"name"`
+	Email        string       `json:"email"`
+	Avatar       string       `json:"avatar"`
+	Role         Role         `json:"role"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// MemberAccess is what the authorization middleware needs to know about a
+// caller's membership in one project.
+type MemberAccess struct {
+	Role       Role
+	Require2FA bool
+	HasMFA     bool
+}
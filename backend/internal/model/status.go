@@ -0,0 +1,111 @@
+package model
+
+import "fmt"
+
+// StatusCategory groups a project's columns into the three buckets reports
+// rely on, whatever the columns are called.
+type StatusCategory string
+
+const (
+	CategoryTodo       StatusCategory = "todo"
+	CategoryInProgress StatusCategory = "in_progress"
+	CategoryDone       StatusCategory = "done"
+)
+
+func (c StatusCategory) Valid() bool {
+	switch c {
+	case CategoryTodo, CategoryInProgress, CategoryDone:
+		return true
+	}
+	return false
+}
+
+// WIPMode decides what happens when a move takes a column past its
+// work-in-progress limit: soft mode allows it with a warning, hard mode
+// refuses it.
+type WIPMode string
+
+const (
+	WIPSoft WIPMode = "soft"
+	WIPHard WIPMode = "hard"
+)
+
+func (m WIPMode) Valid() bool {
+	return m == WIPSoft || m == WIPHard
+}
+
+type Status struct {
+	ID        int            `json:"id"`
+	ProjectID int            `json:"projectId"`
+	Title     string         `json:"title"`
+	Category  StatusCategory `json:"category"`
+	Color     *string        `json:"color"`
+	Position  int            `json:"position"`
+	Archived  bool           `json:"archived"`
+	// WIPLimit caps the tasks in the column, or the tasks per assignee when
+	// WIPPerAssignee is set. Nil means no limit.
+	WIPLimit       *int    `json:"wipLimit"`
+	WIPMode        WIPMode `json:"wipMode"`
+	WIPPerAssignee bool    `json:"wipPerAssignee"`
+}
+
+// WIPViolation reports a column, or one assignee's share of it, holding more
+// tasks than its limit. AssigneeID is set for per-assignee limits.
+type WIPViolation struct {
+	StatusID   int     `json:"statusId"`
+	AssigneeID *int    `json:"assigneeId,omitempty"`
+	Limit      int     `json:"limit"`
+	Count      int     `json:"count"`
+	Mode       WIPMode `json:"mode"`
+	Message    string  `json:"message"`
+}
+
+// AssigneeCount is how many of a column's tasks one user is assigned to.
+type AssigneeCount struct {
+	UserID int `json:"userId"`
+	Count  int `json:"count"`
+}
+
+// CheckWIP lists the limits the column breaks with count tasks in it, or
+// with the given per-assignee counts when the limit is per assignee.
+func CheckWIP(s Status, count int, perAssignee []AssigneeCount) []WIPViolation {
+	if s.WIPLimit == nil {
+		return nil
+	}
+	limit := *s.WIPLimit
+	if !s.WIPPerAssignee {
+		if count <= limit {
+			return nil
+		}
+		return []WIPViolation{{
+			StatusID: s.ID,
+			Limit:    limit,
+			Count:    count,
+			Mode:     s.WIPMode,
+			Message:  fmt.Sprintf("%s is over its limit of %d tasks", s.Title, limit),
+		}}
+	}
+	var out []WIPViolation
+	for _, a := range perAssignee {
+		if a.Count <= limit {
+			continue
+		}
+		uid := a.UserID
+		out = append(out, WIPViolation{
+			StatusID:   s.ID,
+			AssigneeID: &uid,
+			Limit:      limit,
+			Count:      a.Count,
+			Mode:       s.WIPMode,
+			Message:    fmt.Sprintf("An assignee has more than %d tasks in %s", limit, s.Title),
+		})
+	}
+	return out
+}
+
+// DefaultStatuses are the columns every new project starts with.
+var DefaultStatuses = []Status{
+	{Title: "To Do", Category: CategoryTodo},
+	{Title: "In Progress", Category: CategoryInProgress},
+	{Title: "Done", Category: CategoryDone},
+}
@@ -1,15 +1,15 @@
 package model
 
 type UserTask struct {
-	ID               int      `json:"id"`
-	Title            string   `json:"title"`
-	ProjectID        int      `json:"projectId"`
-	ProjectName      string   `json:"projectName"`
-	StatusName       string   `json:"statusName"`
-	DueDate          *string  `json:"dueDate"`
-	Priority         *string  `json:"priority"`
-	CommentsCount    int      `json:"commentsCount"`
-	AttachmentsCount int      `json:"attachmentsCount"`
+	ID               int     `json:"id"`
+	Title            string  `json:"title"`
+	ProjectID        int     `json:"projectId"`
+	ProjectName      string  `json:"projectName"`
+	StatusName       string  `json:"statusName"`
+	DueDate          *string `json:"dueDate"`
+	Priority         *string `json:"priority"`
+	CommentsCount    int     `json:"commentsCount"`
+	AttachmentsCount int     `json:"attachmentsCount"`
 }
 
 type Attachment struct {
@@ -27,24 +27,26 @@ type TaskCommentAuthor struct {
 }
 
 type TaskComment struct {
-	ID        int               `json:"id"`
-	Text      string            `json:"text"`
-	CreatedAt string            `json:"createdAt"`
+	ID        int                `json:"id"`
+	Text      string             `json:"text"`
+	CreatedAt string             `json:"createdAt"`
 	Author    *TaskCommentAuthor `json:"author"`
 }
 
 type TaskDetail struct {
-	ID            int           `json:"id"`
-	Title         string        `json:"title"`
-	Description   *string       `json:"description"`
-	ProjectID     int           `json:"projectId"`
-	ProjectName   string        `json:"projectName"`
-	StatusID      int           `json:"statusId"`
-	StatusName    string        `json:"statusName"`
-	DueDate       *string       `json:"dueDate"`
-	Priority      *string       `json:"priority"`
-	Assignees     []User        `json:"assignees"`
-	Collaborators []User        `json:"collaborators"`
-	Attachments   []Attachment  `json:"attachments"`
-	Comments      []TaskComment `json:"comments"`
-}
\ No newline at end of file
+	ID             int            `json:"id"`
+	Title          string         `json:"title"`
+	Description    *string        `json:"description"`
+	ProjectID      int            `json:"projectId"`
+	ProjectName    string         `json:"projectName"`
+	StatusID       int            `json:"statusId"`
+	StatusName     string         `json:"statusName"`
+	StatusCategory StatusCategory `json:"statusCategory"`
+	DueDate        *string        `json:"dueDate"`
+	Priority       *string        `json:"priority"`
+	Assignees      []User         `json:"assignees"`
+	Collaborators  []User         `json:"collaborators"`
+	Attachments    []Attachment   `json:"attachments"`
+	Comments       []TaskComment  `json:"comments"`
+	Version        int            `json:"version"`
+}
@@ -0,0 +1,132 @@
+package model
+
+// TaskField names something a transition can require before a task moves.
+// FieldComment is met by a comment sent with the move itself.
+type TaskField string
+
+const (
+	FieldAssignee    TaskField = "assignee"
+	FieldDescription TaskField = "description"
+	FieldPriority    TaskField = "priority"
+	FieldComment     TaskField = "comment"
+)
+
+var TaskFields = []TaskField{FieldAssignee, FieldDescription, FieldPriority, FieldComment}
+
+var fieldMessages = map[TaskField]string{
+	FieldAssignee:    "Assign someone to the task first",
+	FieldDescription: "Add a description to the task first",
+	FieldPriority:    "Set a priority on the task first",
+	FieldComment:     "Include a comment with this move",
+}
+
+func (f TaskField) Valid() bool {
+	for _, known := range TaskFields {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition allows tasks to move from one status to another. A nil
+// FromStatusID matches every status. Empty Roles lets anyone who can edit
+// tasks make the move.
+type Transition struct {
+	ID             int         `json:"id"`
+	ProjectID      int         `json:"projectId"`
+	FromStatusID   *int        `json:"fromStatusId"`
+	ToStatusID     int         `json:"toStatusId"`
+	Roles          []Role      `json:"roles"`
+	RequiredFields []TaskField `json:"requiredFields"`
+}
+
+// UnmetCondition is one reason a move was refused. Condition is
+// "transition", "role" or "field".
+type UnmetCondition struct {
+	Condition string    `json:"condition"`
+	Field     TaskField `json:"field,omitempty"`
+	Roles     []Role    `json:"roles,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// TaskFacts is what the transition check needs to know about the task being
+// moved and the move request.
+type TaskFacts struct {
+	Assignees      int
+	HasDescription bool
+	HasPriority    bool
+	HasComment     bool
+}
+
+func (f TaskFacts) has(field TaskField) bool {
+	switch field {
+	case FieldAssignee:
+		return f.Assignees > 0
+	case FieldDescription:
+		return f.HasDescription
+	case FieldPriority:
+		return f.HasPriority
+	case FieldComment:
+		return f.HasComment
+	}
+	return false
+}
+
+// CheckTransition lists what stops a task moving from one status to another
+// under a project's rules. A project without rules allows every move, and
+// moves within a column are never restricted. A rule for the exact source
+// status wins over a rule for any status.
+func CheckTransition(rules []Transition, from, to int, role Role, facts TaskFacts) []UnmetCondition {
+	if from == to || len(rules) == 0 {
+		return nil
+	}
+	var rule *Transition
+	for i := range rules {
+		r := &rules[i]
+		if r.ToStatusID != to {
+			continue
+		}
+		if r.FromStatusID != nil && *r.FromStatusID == from {
+			rule = r
+			break
+		}
+		if r.FromStatusID == nil && rule == nil {
+			rule = r
+		}
+	}
+	if rule == nil {
+		return []UnmetCondition{{
+			Condition: "transition",
+			Message:   "The workflow does not allow moving a task between these statuses",
+		}}
+	}
+
+	var unmet []UnmetCondition
+	if len(rule.Roles) > 0 {
+		allowed := false
+		for _, r := range rule.Roles {
+			if r == role {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			unmet = append(unmet, UnmetCondition{
+				Condition: "role",
+				Roles:     append([]Role{}, rule.Roles...),
+				Message:   "Your project role cannot make this move",
+			})
+		}
+	}
+	for _, f := range rule.RequiredFields {
+		if !facts.has(f) {
+			unmet = append(unmet, UnmetCondition{
+				Condition: "field",
+				Field:     f,
+				Message:   fieldMessages[f],
+			})
+		}
+	}
+	return unmet
+}
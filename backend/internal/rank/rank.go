@@ -0,0 +1,116 @@
+// Package rank orders items with strings that compare byte by byte, so an
+// item can always be placed between two others by writing only its own rank.
+// Ranks are base-36 fractions: "i" sorts like 0.5 and "0i" like 0.014. They
+// use the digits 0-9 and a-z and never end in '0', which leaves room below
+// every rank.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var ErrOrder = errors.New("rank: lower bound must sort before upper bound")
+
+// Between returns a rank that sorts after a and before b. An empty a means
+// no lower bound and an empty b no upper bound.
+func Between(a, b string) (string, error) {
+	if !valid(a) || !valid(b) || (b != "" && a >= b) {
+		return "", ErrOrder
+	}
+	if b == "" && a != "" {
+		return after(a), nil
+	}
+	return midpoint(a, b), nil
+}
+
+// after returns the shortest rank above a: a's first digit other than 'z'
+// plus one. Appending this way adds a digit every 35 ranks, where halving
+// the gap to the end would add one every few.
+func after(a string) string {
+	for i := 0; i < len(a); i++ {
+		if d := strings.IndexByte(digits, a[i]); d < len(digits)-1 {
+			return a[:i] + string(digits[d+1])
+		}
+	}
+	return a + string(digits[1])
+}
+
+func valid(r string) bool {
+	if strings.HasSuffix(r, "0") {
+		return false
+	}
+	for i := 0; i < len(r); i++ {
+		if strings.IndexByte(digits, r[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func midpoint(a, b string) string {
+	if b != "" {
+		// Keep the shared prefix, treating a as padded with zeros.
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := len(digits)
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(digits[(lo+hi+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[lo]) + midpoint(rest, "")
+}
+
+func digitAt(r string, i int) byte {
+	if i < len(r) {
+		return r[i]
+	}
+	return digits[0]
+}
+
+// Spread returns n short ranks in ascending order, evenly spaced with room
+// between neighbours, for rewriting a whole list.
+func Spread(n int) []string {
+	width, space := 1, int64(len(digits))
+	for space < int64(n+1)*int64(len(digits)) {
+		width++
+		space *= int64(len(digits))
+	}
+	step := space / int64(n+1)
+	out := make([]string, n)
+	buf := make([]byte, width)
+	for i := range out {
+		v := step * int64(i+1)
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = digits[v%int64(len(digits))]
+			v /= int64(len(digits))
+		}
+		out[i] = strings.TrimRight(string(buf), "0")
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package rank
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	for _, tc := range []struct {
+		a, b, want string
+	}{
+		{"", "", "i"},
+		{"", "i", "9"},
+		{"", "1", "0i"},
+		{"", "01", "00i"},
+		{"a", "b", "ai"},
+		{"a", "a1", "a0i"},
+		{"zy", "zz", "zyi"},
+		{"i", "", "j"},
+		{"y1", "", "z"},
+		{"z", "", "z1"},
+		{"zz", "", "zz1"},
+	} {
+		got, err := Between(tc.a, tc.b)
+		if err != nil || got != tc.want {
+			t.Errorf("Between(%q, %q) = %q, %v; want %q", tc.a, tc.b, got, err, tc.want)
+		}
+	}
+
+	for _, tc := range [][2]string{{"b", "a"}, {"a", "a"}, {"a0", ""}, {"", "b0"}, {"A", ""}, {"a-", "b"}} {
+		if got, err := Between(tc[0], tc[1]); !errors.Is(err, ErrOrder) {
+			t.Errorf("Between(%q, %q) = %q, %v; want ErrOrder", tc[0], tc[1], got, err)
+		}
+	}
+}
+
+// TestBetweenOrders checks a < Between(a, b) < b for random bounds.
+func TestBetweenOrders(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomRank := func() string {
+		b := make([]byte, 1+rng.Intn(6))
+		for i := range b {
+			b[i] = digits[rng.Intn(len(digits))]
+		}
+		b[len(b)-1] = digits[1+rng.Intn(len(digits)-1)]
+		return string(b)
+	}
+	for i := 0; i < 10000; i++ {
+		a, b := randomRank(), randomRank()
+		switch {
+		case a == b:
+			continue
+		case a > b:
+			a, b = b, a
+		}
+		if i%10 == 0 {
+			b = ""
+		}
+		if i%10 == 5 {
+			a = ""
+		}
+		got, err := Between(a, b)
+		if err != nil || !valid(got) || got <= a || (b != "" && got >= b) {
+			t.Fatalf("Between(%q, %q) = %q, %v", a, b, got, err)
+		}
+	}
+}
+
+func TestAppendStaysShort(t *testing.T) {
+	last := ""
+	for i := 0; i < 100; i++ {
+		next, err := Between(last, "")
+		if err != nil || next <= last {
+			t.Fatalf("append after %q = %q, %v", last, next, err)
+		}
+		last = next
+	}
+	if len(last) > 4 {
+		t.Fatalf("100 appends grew the rank to %q", last)
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, tc := range []struct {
+		n, maxLen int
+	}{
+		{0, 0},
+		{1, 1},
+		{35, 2},
+		{36, 2},
+		{1000, 3},
+		{50000, 5},
+	} {
+		got := Spread(tc.n)
+		if len(got) != tc.n {
+			t.Fatalf("Spread(%d) returned %d ranks", tc.n, len(got))
+		}
+		for i, r := range got {
+			if r == "" || !valid(r) || len(r) > tc.maxLen {
+				t.Fatalf("Spread(%d)[%d] = %q, want a valid rank of at most %d digits", tc.n, i, r, tc.maxLen)
+			}
+			if i > 0 {
+				if _, err := Between(got[i-1], r); err != nil {
+					t.Fatalf("Spread(%d) has no room between %q and %q", tc.n, got[i-1], r)
+				}
+			}
+		}
+	}
+}
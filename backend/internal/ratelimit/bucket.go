@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"planify/backend/internal/config"
+)
+
+// Rule is a token bucket: Burst tokens at most, refilled at Rate per second.
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+func RuleFromConfig(r config.RateLimitRule) Rule {
+	return Rule{Rate: float64(r.Requests) / r.Per.Seconds(), Burst: r.Burst}
+}
+
+// Result describes the bucket after a request was counted against it.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request would be allowed
+}
+
+// BucketStore takes one token from the bucket for key.
+type BucketStore interface {
+	Take(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+func NewBucketStore(cfg config.RateLimitConfig) (BucketStore, error) {
+	switch cfg.Store {
+	case "", "memory":
+		return NewMemoryBuckets(), nil
+	case "redis":
+		return NewRedisBuckets(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown store %q", cfg.Store)
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type MemoryBuckets struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweep   time.Time
+}
+
+func NewMemoryBuckets() *MemoryBuckets {
+	return &MemoryBuckets{buckets: make(map[string]*bucket)}
+}
+
+func (m *MemoryBuckets) Take(_ context.Context, key string, rule Rule) (Result, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Idle buckets are full again after Burst/Rate, at which point they are
+	// indistinguishable from missing ones.
+	if now.After(m.sweep) {
+		for k, b := range m.buckets {
+			if now.Sub(b.last) > 10*time.Minute {
+				delete(m.buckets, k)
+			}
+		}
+		m.sweep = now.Add(time.Minute)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rule.Rate)
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(rule, b.tokens, allowed), nil
+}
+
+func result(rule Rule, tokens float64, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     rule.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(rule.Burst) - tokens) / rule.Rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / rule.Rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from a bucket stored as a hash in one round
+// trip, so concurrent instances cannot both spend the last token.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisBuckets shares buckets between instances through Redis or any server
+// speaking its protocol and supporting EVAL.
+type RedisBuckets struct {
+	Client redis.UniversalClient
+	Prefix string
+}
+
+func NewRedisBuckets(url string) (*RedisBuckets, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisBuckets{Client: redis.NewClient(opts), Prefix: "planify:rl:"}, nil
+}
+
+func (r *RedisBuckets) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	now := float64(time.Now().UnixMicro()) / 1e6
+	res, err := takeScript.Run(ctx, r.Client, []string{r.Prefix + key},
+		rule.Rate, rule.Burst, strconv.FormatFloat(now, 'f', 6, 64),
+	).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := res[0].(int64)
+	s, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(rule, tokens, allowed == 1), nil
+}
+
+func (r *RedisBuckets) Close() error {
+	return r.Client.Close()
+}
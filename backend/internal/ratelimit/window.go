@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Window is an in-process fixed-window counter keyed by an arbitrary string
+// such as a client IP or an email address.
+type Window struct {
+	Limit  int
+	Period time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*windowEntry
+	sweep   time.Time
+}
+
+type windowEntry struct {
+	count int
+	reset time.Time
+}
+
+func NewWindow(limit int, period time.Duration) *Window {
+	return &Window{Limit: limit, Period: period, entries: make(map[string]*windowEntry)}
+}
+
+// Allow records a hit for key and reports whether it is still within the limit.
+func (w *Window) Allow(key string) bool {
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if now.After(w.sweep) {
+		for k, e := range w.entries {
+			if now.After(e.reset) {
+				delete(w.entries, k)
+			}
+		}
+		w.sweep = now.Add(w.Period)
+	}
+
+	e, ok := w.entries[key]
+	if !ok || now.After(e.reset) {
+		e = &windowEntry{reset: now.Add(w.Period)}
+		w.entries[key] = e
+	}
+	e.count++
+	return e.count <= w.Limit
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/model"
+)
+
+var ErrTokenLimit = errors.New("too many access tokens")
+
+type AccessTokenRepository struct {
+	DB *database.DB
+}
+
+func (r *AccessTokenRepository) Create(t *model.AccessToken, tokenHash string, limit int) error {
+	var n int
+	err := r.DB.QueryRow(
+		`SELECT COUNT(*) FROM personal_access_tokens WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?`,
+		t.UserID, time.Now().UTC(),
+	).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && n >= limit {
+		return ErrTokenLimit
+	}
+	t.CreatedAt = time.Now().UTC()
+	id, err := r.DB.InsertID(`
+		INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.UserID, t.Name, t.Prefix, tokenHash, joinScopes(t.Scopes), t.CreatedAt, t.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	t.ID = int(id)
+	return nil
+}
+
+func (r *AccessTokenRepository) List(userID int) ([]model.AccessToken, error) {
+	rows, err := r.DB.Query(`
+		SELECT id, name, token_prefix, scopes, created_at, last_used_at, expires_at
+		FROM personal_access_tokens
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY created_at DESC`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.AccessToken{}
+	for rows.Next() {
+		t := model.AccessToken{UserID: userID}
+		var scopes string
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Name, &t.Prefix, &scopes, &t.CreatedAt, &lastUsed, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		t.Scopes = splitScopes(scopes)
+		if lastUsed.Valid {
+			t.LastUsedAt = &lastUsed.Time
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// Authenticate resolves a token hash to a live token and records its use at
+// most once a minute.
+func (r *AccessTokenRepository) Authenticate(tokenHash string) (*model.AccessToken, error) {
+	var t model.AccessToken
+	var scopes string
+	var lastUsed sql.NullTime
+	now := time.Now().UTC()
+	err := r.DB.QueryRow(`
+		SELECT id, user_id, name, token_prefix, scopes, created_at, last_used_at, expires_at
+		FROM personal_access_tokens
+		WHERE token_hash = ? AND revoked_at IS NULL AND expires_at > ?`, tokenHash, now,
+	).Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &t.CreatedAt, &lastUsed, &t.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	t.Scopes = splitScopes(scopes)
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+	if !lastUsed.Valid || now.Sub(lastUsed.Time) > time.Minute {
+		if _, err := r.DB.Exec(`UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?`, now, t.ID); err != nil {
+			return nil, err
+		}
+		t.LastUsedAt = &now
+	}
+	return &t, nil
+}
+
+func (r *AccessTokenRepository) Revoke(id, userID int) error {
+	res, err := r.DB.Exec(
+		`UPDATE personal_access_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), id, userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func joinScopes(scopes []model.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, " ")
+}
+
+func splitScopes(s string) []model.Scope {
+	out := []model.Scope{}
+	for _, f := range strings.Fields(s) {
+		out = append(out, model.Scope(f))
+	}
+	return out
+}
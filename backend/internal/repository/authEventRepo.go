@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/model"
+)
+
+type AuthEventRepository struct {
+	DB *database.DB
+}
+
+func (r *AuthEventRepository) Record(e model.AuthEvent) error {
+	_, err := r.DB.Exec(
+		`INSERT INTO auth_events (user_id, event, login, ip, detail, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		e.UserID, e.Event, e.Login, e.IP, e.Detail, time.Now().UTC(),
+	)
+	return err
+}
+
+// List returns the newest events first, optionally filtered by event name.
+func (r *AuthEventRepository) List(event string, limit int) ([]model.AuthEvent, error) {
+	query := `SELECT id, user_id, event, login, ip, detail, created_at FROM auth_events`
+	args := []any{}
+	if event != "" {
+		query += ` WHERE event = ?`
+		args = append(args, event)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.AuthEvent{}
+	for rows.Next() {
+		var e model.AuthEvent
+		var uid sql.NullInt64
+		if err := rows.Scan(&e.ID, &uid, &e.Event, &e.Login, &e.IP, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if uid.Valid {
+			id := int(uid.Int64)
+			e.UserID = &id
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
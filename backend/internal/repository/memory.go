@@ -0,0 +1,1283 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"planify/backend/internal/model"
+	"planify/backend/internal/rank"
+)
+
+// errConstraint stands in for the foreign key and unique violations the SQL
+// repositories get back from the driver.
+var errConstraint = errors.New("repository: constraint violation")
+
+// Memory keeps every table in maps behind one mutex. It mirrors the SQL
+// repositories closely enough for handler tests: the same ordering, the same
+// errors and the same position and membership rules. Nothing is persisted.
+type Memory struct {
+	mu  sync.Mutex
+	seq int
+
+	users      map[int]*memUser
+	identities map[[2]string]int
+	settings   map[int]model.UserSettings
+
+	projects map[int]*memProject
+	members  map[[2]int]*memMember
+	statuses map[int]*memStatus
+	rules    map[int]*model.Transition
+
+	tasks       map[int]*memTask
+	comments    map[int]*memComment
+	attachments map[int]*memAttachment
+
+	sessions map[string]*memSession
+	refresh  map[string]*memRefresh
+	mfa      map[int]*memMFA
+	recovery map[int]map[string]bool
+	resets   map[string]*memReset
+	tokens   map[int]*memToken
+	events   []model.AuthEvent
+}
+
+type memUser struct {
+	model.User
+	admin      bool
+	verifiedAt *time.Time
+}
+
+type memProject struct {
+	model.Project
+	dueDate    *string
+	require2FA bool
+	version    int
+}
+
+type memMember struct {
+	role   model.Role
+	source string
+}
+
+type memStatus struct {
+	id        int
+	projectID int
+	title     string
+	category  model.StatusCategory
+	color     *string
+	position  int
+	archived  *time.Time
+
+	wipLimit       *int
+	wipMode        model.WIPMode
+	wipPerAssignee bool
+}
+
+type memTask struct {
+	id            int
+	projectID     int
+	statusID      int
+	title         string
+	description   *string
+	priority      *string
+	rank          string
+	version       int
+	assignees     []int
+	collaborators []int
+}
+
+type memComment struct {
+	id        int
+	taskID    int
+	userID    *int
+	text      string
+	createdAt time.Time
+}
+
+type memAttachment struct {
+	id         int
+	taskID     int
+	fileName   string
+	storedName string
+	size       int64
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		users:       map[int]*memUser{},
+		identities:  map[[2]string]int{},
+		settings:    map[int]model.UserSettings{},
+		projects:    map[int]*memProject{},
+		members:     map[[2]int]*memMember{},
+		statuses:    map[int]*memStatus{},
+		rules:       map[int]*model.Transition{},
+		tasks:       map[int]*memTask{},
+		comments:    map[int]*memComment{},
+		attachments: map[int]*memAttachment{},
+		sessions:    map[string]*memSession{},
+		refresh:     map[string]*memRefresh{},
+		mfa:         map[int]*memMFA{},
+		recovery:    map[int]map[string]bool{},
+		resets:      map[string]*memReset{},
+		tokens:      map[int]*memToken{},
+	}
+}
+
+// NewMemoryStores returns stores backed by a fresh Memory.
+func NewMemoryStores() (*Stores, *Memory) {
+	m := NewMemory()
+	return m.Stores(), m
+}
+
+func (m *Memory) Stores() *Stores {
+	return &Stores{
+		Projects:       memoryProjects{m},
+		Tasks:          memoryTasks{m},
+		Statuses:       memoryStatuses{m},
+		Transitions:    memoryTransitions{m},
+		Users:          memoryUsers{m},
+		Sessions:       memorySessions{m},
+		MFA:            memoryMFA{m},
+		PasswordResets: memoryResets{m},
+		AccessTokens:   memoryTokens{m},
+		AuthEvents:     memoryEvents{m},
+	}
+}
+
+// SetAdmin flips users.is_admin, which has no repository method because it is
+// only ever set by hand in the database.
+func (m *Memory) SetAdmin(userID int, admin bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	u.admin = admin
+	return nil
+}
+
+func (m *Memory) nextID() int {
+	m.seq++
+	return m.seq
+}
+
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func (m *Memory) status(id int) *memStatus {
+	if s, ok := m.statuses[id]; ok {
+		return s
+	}
+	return &memStatus{}
+}
+
+func (m *Memory) userByEmail(email string) *memUser {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+func (m *Memory) publicUser(id int) model.User {
+	u := m.users[id]
+	return model.User{ID: u.ID, Name: u.Name, Email: u.Email, Avatar: u.Avatar}
+}
+
+func (m *Memory) userIDs(ids []int) []model.User {
+	var out []model.User
+	for _, id := range ids {
+		if _, ok := m.users[id]; ok {
+			out = append(out, m.publicUser(id))
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](in map[int]V) []int {
+	keys := make([]int, 0, len(in))
+	for k := range in {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func contains(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryProjects struct{ m *Memory }
+
+func (r memoryProjects) GetAll(userID int) ([]model.Project, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []model.Project
+	for _, id := range sortedKeys(m.projects) {
+		if _, ok := m.members[[2]int{id, userID}]; ok {
+			p := m.projects[id].Project
+			p.OwnerID = nil
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (r memoryProjects) GetByID(id int) (map[string]interface{}, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.projects[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	data := map[string]interface{}{
+		"id":          id,
+		"name":        p.Name,
+		"description": p.Description,
+		"require2fa":  p.require2FA,
+		"version":     p.version,
+		"createdAt":   p.CreatedAt,
+		"due_date":    nil,
+	}
+	if p.dueDate != nil {
+		data["due_date"] = *p.dueDate
+	}
+
+	var team []model.User
+	for _, uid := range sortedKeys(m.users) {
+		if _, ok := m.members[[2]int{id, uid}]; ok {
+			u := m.users[uid]
+			team = append(team, model.User{ID: u.ID, Name: u.Name, Email: u.Email})
+		}
+	}
+	data["team"] = team
+
+	var columns []map[string]interface{}
+	for _, s := range m.projectStatuses(id, false) {
+		var inColumn []*memTask
+		var list []map[string]interface{}
+		for _, t := range m.columnTasks(s.id, 0) {
+			inColumn = append(inColumn, t)
+			desc := ""
+			if t.description != nil {
+				desc = *t.description
+			}
+			list = append(list, map[string]interface{}{
+				"id":          t.id,
+				"title":       t.title,
+				"description": desc,
+				"rank":        t.rank,
+				"position":    len(list),
+				"version":     t.version,
+				"assignees":   []model.User{},
+			})
+		}
+		columns = append(columns, boardColumn(s.model(), list, assigneeCounts(inColumn, 0)))
+	}
+	data["columns"] = columns
+	return data, nil
+}
+
+// columnTasks returns a column's tasks ordered by rank, then ID, leaving out
+// skipID.
+func (m *Memory) columnTasks(statusID, skipID int) []*memTask {
+	var out []*memTask
+	for _, id := range sortedKeys(m.tasks) {
+		if t := m.tasks[id]; t.statusID == statusID && t.id != skipID {
+			out = append(out, t)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].rank < out[j].rank })
+	return out
+}
+
+// placeTask mirrors the SQL placeTask.
+func (m *Memory) placeTask(taskID int, p UpdateTaskPayload) (string, error) {
+	ranked := func() []rankedTask {
+		var out []rankedTask
+		for _, t := range m.columnTasks(p.StatusID, taskID) {
+			out = append(out, rankedTask{t.id, t.rank})
+		}
+		return out
+	}
+	lo, hi, err := placement(ranked(), p)
+	if err != nil {
+		return "", err
+	}
+	if r, ok := newRank(lo, hi); ok {
+		return r, nil
+	}
+	m.rebalance(p.StatusID, taskID)
+	if lo, hi, err = placement(ranked(), p); err != nil {
+		return "", err
+	}
+	return rank.Between(lo, hi)
+}
+
+func (m *Memory) rebalance(statusID, skipID int) {
+	column := m.columnTasks(statusID, skipID)
+	for i, r := range rank.Spread(len(column)) {
+		column[i].rank = r
+	}
+}
+
+func (r memoryProjects) GetMemberRole(projectID, userID int) (model.Role, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pm, ok := m.members[[2]int{projectID, userID}]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return pm.role, nil
+}
+
+func (r memoryProjects) GetMemberAccess(projectID, userID int) (*model.MemberAccess, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pm, ok := m.members[[2]int{projectID, userID}]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	mfa := m.mfa[userID]
+	return &model.MemberAccess{
+		Role:       pm.role,
+		Require2FA: m.projects[projectID].require2FA,
+		HasMFA:     mfa != nil && mfa.enabledAt != nil,
+	}, nil
+}
+
+func (r memoryProjects) SetRequire2FA(projectID int, required bool, version int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.projects[projectID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := bumpMemVersion(&p.version, version); err != nil {
+		return err
+	}
+	p.require2FA = required
+	return nil
+}
+
+func (r memoryProjects) UpdateDueDate(projectID int, payload UpdateDueDatePayload) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.projects[projectID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := bumpMemVersion(&p.version, payload.Version); err != nil {
+		return err
+	}
+	p.dueDate = nil
+	if payload.DueDate != nil && strings.TrimSpace(*payload.DueDate) != "" {
+		v := *payload.DueDate
+		p.dueDate = &v
+	}
+	return nil
+}
+
+func (r memoryProjects) Create(payload CreateProjectPayload) (*model.Project, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[payload.OwnerID]; !ok {
+		return nil, errConstraint
+	}
+	for _, uid := range payload.TeamIDs {
+		if _, ok := m.users[uid]; !ok {
+			return nil, ErrUnknownMember
+		}
+	}
+	owner := payload.OwnerID
+	p := &memProject{Project: model.Project{
+		ID:          m.nextID(),
+		Name:        payload.Name,
+		Description: payload.Description,
+		CreatedAt:   memoryNow(),
+		OwnerID:     &owner,
+	}, version: 1}
+	if payload.DueDate != nil {
+		v := *payload.DueDate
+		p.dueDate = &v
+	}
+	m.projects[p.ID] = p
+	m.members[[2]int{p.ID, owner}] = &memMember{role: model.RoleOwner}
+	for pos, def := range model.DefaultStatuses {
+		s := &memStatus{id: m.nextID(), projectID: p.ID, title: def.Title, category: def.Category, position: pos, wipMode: model.WIPSoft}
+		m.statuses[s.id] = s
+	}
+	for _, uid := range payload.TeamIDs {
+		if _, ok := m.members[[2]int{p.ID, uid}]; !ok {
+			m.members[[2]int{p.ID, uid}] = &memMember{role: model.RoleMember}
+		}
+	}
+	return &model.Project{ID: p.ID, Name: p.Name, Description: p.Description, OwnerID: &owner}, nil
+}
+
+func (r memoryProjects) ListMembers(projectID int) ([]model.ProjectMember, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []model.ProjectMember{}
+	for _, uid := range sortedKeys(m.users) {
+		pm, ok := m.members[[2]int{projectID, uid}]
+		if !ok {
+			continue
+		}
+		u := m.users[uid]
+		out = append(out, model.ProjectMember{
+			ID:           u.ID,
+			Name:         u.Name,
+			Email:        u.Email,
+			Avatar:       defaultAvatar(u.Avatar),
+			Role:         pm.role,
+			Capabilities: pm.role.Capabilities(),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r memoryProjects) AddMember(projectID, userID int, role model.Role) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]int{projectID, userID}
+	if m.users[userID] == nil {
+		return sql.ErrNoRows
+	}
+	if _, ok := m.members[key]; ok {
+		return ErrAlreadyMember
+	}
+	if m.projects[projectID] == nil {
+		return errConstraint
+	}
+	m.members[key] = &memMember{role: role}
+	return nil
+}
+
+func (r memoryProjects) UpdateMemberRole(projectID, userID int, role model.Role) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pm, ok := m.members[[2]int{projectID, userID}]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	pm.role = role
+	return nil
+}
+
+func (r memoryProjects) RemoveMember(projectID, userID int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]int{projectID, userID}
+	if _, ok := m.members[key]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.members, key)
+	return nil
+}
+
+func (r memoryProjects) TransferOwnership(projectID, fromUserID, toUserID int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	to, ok := m.members[[2]int{projectID, toUserID}]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	to.role = model.RoleOwner
+	if from, ok := m.members[[2]int{projectID, fromUserID}]; ok {
+		from.role = model.RoleAdmin
+	}
+	if p, ok := m.projects[projectID]; ok {
+		p.OwnerID = &toUserID
+	}
+	return nil
+}
+
+func (r memoryProjects) Delete(projectID, version int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.projects[projectID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := bumpMemVersion(&p.version, version); err != nil {
+		return err
+	}
+	for id, t := range m.tasks {
+		if t.projectID != projectID {
+			continue
+		}
+		for cid, c := range m.comments {
+			if c.taskID == id {
+				delete(m.comments, cid)
+			}
+		}
+		for aid, a := range m.attachments {
+			if a.taskID == id {
+				delete(m.attachments, aid)
+			}
+		}
+		delete(m.tasks, id)
+	}
+	for id, t := range m.rules {
+		if t.ProjectID == projectID {
+			delete(m.rules, id)
+		}
+	}
+	for id, s := range m.statuses {
+		if s.projectID == projectID {
+			delete(m.statuses, id)
+		}
+	}
+	for key := range m.members {
+		if key[0] == projectID {
+			delete(m.members, key)
+		}
+	}
+	delete(m.projects, projectID)
+	return nil
+}
+
+func (r memoryProjects) SyncDirectoryRoles(userID int, roles map[int]*model.Role) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for projectID, want := range roles {
+		key := [2]int{projectID, userID}
+		pm, ok := m.members[key]
+		switch {
+		case !ok:
+			if want == nil {
+				continue
+			}
+			if m.projects[projectID] == nil || m.users[userID] == nil {
+				return errConstraint
+			}
+			m.members[key] = &memMember{role: *want, source: "ldap"}
+		case pm.role == model.RoleOwner, pm.source != "ldap":
+		case want == nil:
+			delete(m.members, key)
+		case *want != pm.role:
+			pm.role = *want
+		}
+	}
+	return nil
+}
+
+type memoryTasks struct{ m *Memory }
+
+func (r memoryTasks) RebalanceRanks(maxLen int) (int, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	long := map[int]bool{}
+	for _, t := range m.tasks {
+		if len(t.rank) > maxLen {
+			long[t.statusID] = true
+		}
+	}
+	for statusID := range long {
+		m.rebalance(statusID, 0)
+	}
+	return len(long), nil
+}
+
+func (r memoryTasks) UpdatePosition(taskID int, payload UpdateTaskPayload, userID int, role model.Role) ([]model.WIPViolation, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if err := m.statusValid(t.projectID, payload.StatusID); err != nil {
+		return nil, err
+	}
+	if payload.Version != 0 && payload.Version != t.version {
+		return nil, ErrVersionConflict
+	}
+	comment := strings.TrimSpace(payload.Comment)
+	var warnings []model.WIPViolation
+	key, err := m.placeTask(taskID, payload)
+	if err != nil {
+		return nil, err
+	}
+	if t.statusID != payload.StatusID {
+		facts := model.TaskFacts{
+			Assignees:      len(t.assignees),
+			HasDescription: t.description != nil && strings.TrimSpace(*t.description) != "",
+			HasPriority:    t.priority != nil && strings.TrimSpace(*t.priority) != "",
+			HasComment:     comment != "",
+		}
+		if unmet := model.CheckTransition(m.projectRules(t.projectID), t.statusID, payload.StatusID, role, facts); len(unmet) > 0 {
+			return nil, &TransitionError{Unmet: unmet}
+		}
+		if warnings, err = enforceWIP(m.checkWIP(t, payload.StatusID)); err != nil {
+			return nil, err
+		}
+	}
+	if comment != "" {
+		if _, ok := m.users[userID]; !ok {
+			return nil, errConstraint
+		}
+		uid := userID
+		c := &memComment{id: m.nextID(), taskID: taskID, userID: &uid, text: comment, createdAt: memoryNow()}
+		m.comments[c.id] = c
+	}
+	t.statusID = payload.StatusID
+	t.rank = key
+	t.version++
+	return warnings, nil
+}
+
+func (r memoryTasks) GetProjectID(taskID int) (int, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return t.projectID, nil
+}
+
+func (r memoryTasks) GetByID(taskID int) (*model.TaskDetail, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	p := m.projects[t.projectID]
+	s := m.status(t.statusID)
+	return &model.TaskDetail{
+		ID:             t.id,
+		Title:          t.title,
+		Description:    copyString(t.description),
+		ProjectID:      p.ID,
+		ProjectName:    p.Name,
+		StatusID:       s.id,
+		StatusName:     s.title,
+		StatusCategory: s.category,
+		DueDate:        copyString(p.dueDate),
+		Priority:       copyString(t.priority),
+		Assignees:      m.userIDs(t.assignees),
+		Collaborators:  m.userIDs(t.collaborators),
+		Attachments:    m.listAttachments(taskID),
+		Comments:       m.listComments(taskID),
+		Version:        t.version,
+	}, nil
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
+
+// bumpMemVersion mirrors bumpVersion for a memory row's version.
+func bumpMemVersion(current *int, want int) error {
+	if want != 0 && want != *current {
+		return ErrVersionConflict
+	}
+	*current++
+	return nil
+}
+
+func (r memoryTasks) UpdateFields(taskID int, payload UpdateTaskFieldsPayload) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if payload.empty() {
+		return nil
+	}
+	if err := bumpMemVersion(&t.version, payload.Version); err != nil {
+		return err
+	}
+	if payload.Title != nil {
+		t.title = *payload.Title
+	}
+	if payload.Description != nil {
+		t.description = copyString(payload.Description)
+	}
+	if payload.DueDate != nil {
+		p := m.projects[t.projectID]
+		p.dueDate = copyString(payload.DueDate)
+		p.version++
+	}
+	if payload.Priority != nil {
+		t.priority = copyString(payload.Priority)
+	}
+	return nil
+}
+
+// findUser matches an exact email or name, like findUserIDByQuery.
+func (m *Memory) findUser(q string) (int, error) {
+	email := strings.ToLower(q)
+	for _, id := range sortedKeys(m.users) {
+		if u := m.users[id]; u.Email == email || u.Name == q {
+			return id, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (r memoryTasks) AddAssigneeByQuery(taskID int, q string, version int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	uid, err := m.findUser(q)
+	if err != nil {
+		return err
+	}
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if version != 0 && version != t.version {
+		return ErrVersionConflict
+	}
+	if contains(t.assignees, uid) {
+		return nil
+	}
+	joined := *t
+	joined.assignees = append(append([]int{}, t.assignees...), uid)
+	if _, err := enforceWIP(assigneeViolations(m.checkWIP(&joined, t.statusID), uid)); err != nil {
+		return err
+	}
+	t.assignees = joined.assignees
+	t.version++
+	return nil
+}
+
+func (r memoryTasks) AddCollaboratorByQuery(taskID int, q string, version int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	uid, err := m.findUser(q)
+	if err != nil {
+		return err
+	}
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if version != 0 && version != t.version {
+		return ErrVersionConflict
+	}
+	if !contains(t.collaborators, uid) {
+		t.collaborators = append(t.collaborators, uid)
+		t.version++
+	}
+	return nil
+}
+
+func (r memoryTasks) CreateAttachment(taskID int, fileName, storedName string, size int64) (int, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tasks[taskID]; !ok {
+		return 0, errConstraint
+	}
+	a := &memAttachment{id: m.nextID(), taskID: taskID, fileName: fileName, storedName: storedName, size: size}
+	m.attachments[a.id] = a
+	return a.id, nil
+}
+
+func (r memoryTasks) ListAttachments(taskID int) ([]model.Attachment, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listAttachments(taskID), nil
+}
+
+func (m *Memory) listAttachments(taskID int) []model.Attachment {
+	var out []model.Attachment
+	ids := sortedKeys(m.attachments)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if a := m.attachments[ids[i]]; a.taskID == taskID {
+			out = append(out, model.Attachment{ID: a.id, FileName: a.fileName, Size: a.size, URL: "/uploads/" + a.storedName})
+		}
+	}
+	return out
+}
+
+func (r memoryTasks) ListComments(taskID int) ([]model.TaskComment, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listComments(taskID), nil
+}
+
+func (m *Memory) listComments(taskID int) []model.TaskComment {
+	var out []model.TaskComment
+	ids := sortedKeys(m.comments)
+	for i := len(ids) - 1; i >= 0; i-- {
+		c := m.comments[ids[i]]
+		if c.taskID != taskID {
+			continue
+		}
+		var author *model.TaskCommentAuthor
+		if c.userID != nil {
+			if u, ok := m.users[*c.userID]; ok {
+				id, name, email, avatar := u.ID, u.Name, u.Email, u.Avatar
+				author = &model.TaskCommentAuthor{ID: &id, Name: &name, Email: &email, Avatar: &avatar}
+			}
+		}
+		out = append(out, model.TaskComment{
+			ID:        c.id,
+			Text:      c.text,
+			CreatedAt: c.createdAt.Format("2006-01-02T15:04:05Z"),
+			Author:    author,
+		})
+	}
+	return out
+}
+
+func (r memoryTasks) AddComment(taskID int, userID *int, text string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tasks[taskID]; !ok {
+		return errConstraint
+	}
+	c := &memComment{id: m.nextID(), taskID: taskID, text: text, createdAt: memoryNow()}
+	if userID != nil {
+		if _, ok := m.users[*userID]; !ok {
+			return errConstraint
+		}
+		uid := *userID
+		c.userID = &uid
+	}
+	m.comments[c.id] = c
+	return nil
+}
+
+func (r memoryTasks) CreateTask(projectID, statusID int, title string, role model.Role) (int, int, []model.WIPViolation, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.statusValid(projectID, statusID); err != nil {
+		return 0, 0, nil, err
+	}
+	if rules := m.projectRules(projectID); len(rules) > 0 {
+		initial := m.projectStatuses(projectID, false)[0].id
+		if unmet := model.CheckTransition(rules, initial, statusID, role, model.TaskFacts{}); len(unmet) > 0 {
+			return 0, 0, nil, &TransitionError{Unmet: unmet}
+		}
+	}
+	warnings, err := enforceWIP(m.checkWIP(&memTask{}, statusID))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	key, err := m.placeTask(0, UpdateTaskPayload{StatusID: statusID, Position: math.MaxInt})
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	pos := len(m.columnTasks(statusID, 0))
+	t := &memTask{id: m.nextID(), projectID: projectID, statusID: statusID, title: title, rank: key, version: 1}
+	m.tasks[t.id] = t
+	return t.id, pos, warnings, nil
+}
+
+type memoryUsers struct{ m *Memory }
+
+func (u *memUser) model() *model.User {
+	out := u.User
+	out.Avatar = defaultAvatar(out.Avatar)
+	out.EmailVerified = u.verifiedAt != nil
+	return &out
+}
+
+func (r memoryUsers) GetUserByEmail(email string) (*model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.userByEmail(strings.ToLower(email))
+	if u == nil {
+		return nil, sql.ErrNoRows
+	}
+	return u.model(), nil
+}
+
+func (m *Memory) createUser(name, email, passwordHash string, verifiedAt *time.Time) (*model.User, error) {
+	email = strings.ToLower(email)
+	if m.userByEmail(email) != nil {
+		return nil, ErrEmailTaken
+	}
+	u := &memUser{
+		User:       model.User{ID: m.nextID(), Name: name, Email: email, Password: passwordHash, CreatedAt: memoryNow()},
+		verifiedAt: verifiedAt,
+	}
+	m.users[u.ID] = u
+	return u.model(), nil
+}
+
+func (r memoryUsers) Create(name, email, passwordHash string) (*model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createUser(name, email, passwordHash, nil)
+}
+
+func (r memoryUsers) CreateExternal(name, email string) (*model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	at := memoryNow()
+	return m.createUser(name, email, "", &at)
+}
+
+func (r memoryUsers) GetByIdentity(provider, subject string) (*model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.identities[[2]string{provider, subject}]
+	if !ok || m.users[id] == nil {
+		return nil, sql.ErrNoRows
+	}
+	return m.users[id].model(), nil
+}
+
+func (r memoryUsers) LinkIdentity(userID int, provider, subject, email string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]string{provider, subject}
+	if _, ok := m.identities[key]; ok || m.users[userID] == nil {
+		return errConstraint
+	}
+	m.identities[key] = userID
+	return nil
+}
+
+func (r memoryUsers) SyncDirectoryProfile(id int, name, email string) (*model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	email = strings.ToLower(email)
+	if other := m.userByEmail(email); other != nil && other.ID != id {
+		return nil, ErrEmailTaken
+	}
+	u, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	u.Name, u.Email = name, email
+	if u.verifiedAt == nil {
+		at := memoryNow()
+		u.verifiedAt = &at
+	}
+	return u.model(), nil
+}
+
+func (r memoryUsers) MarkEmailVerified(id int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok && u.verifiedAt == nil {
+		at := memoryNow()
+		u.verifiedAt = &at
+	}
+	return nil
+}
+
+func (r memoryUsers) IsAdmin(id int) (bool, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return u.admin, nil
+}
+
+func (r memoryUsers) IsEmailVerified(id int) (bool, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return u.verifiedAt != nil, nil
+}
+
+func (r memoryUsers) GetByID(id int) (*model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return u.model(), nil
+}
+
+func (r memoryUsers) SearchUsers(query string) ([]model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := strings.ToLower(query)
+	var out []model.User
+	for _, id := range sortedKeys(m.users) {
+		u := m.users[id]
+		if strings.Contains(strings.ToLower(u.Name), q) || strings.Contains(u.Email, q) {
+			pu := m.publicUser(id)
+			pu.Avatar = defaultAvatar(pu.Avatar)
+			out = append(out, pu)
+		}
+	}
+	return out, nil
+}
+
+func (r memoryUsers) GetTasksByUserID(userID int) ([]model.UserTask, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []model.UserTask
+	for _, id := range sortedKeys(m.tasks) {
+		t := m.tasks[id]
+		if !contains(t.assignees, userID) {
+			continue
+		}
+		p := m.projects[t.projectID]
+		s := m.status(t.statusID)
+		out = append(out, model.UserTask{
+			ID:          t.id,
+			Title:       t.title,
+			ProjectID:   p.ID,
+			ProjectName: p.Name,
+			StatusName:  s.title,
+			DueDate:     copyString(p.dueDate),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ProjectName < out[j].ProjectName })
+	return out, nil
+}
+
+func (r memoryUsers) UpdatePasswordHash(id int, hash string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.Password = hash
+	}
+	return nil
+}
+
+func (r memoryUsers) UpdateProfile(id int, name, email string) (*model.User, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	email = strings.ToLower(email)
+	if other := m.userByEmail(email); other != nil && other.ID != id {
+		return nil, ErrEmailTaken
+	}
+	u, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if u.Email != email {
+		u.verifiedAt = nil
+	}
+	u.Name, u.Email = name, email
+	return u.model(), nil
+}
+
+func (r memoryUsers) UpdateAvatar(id int, url string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.Avatar = url
+	}
+	return nil
+}
+
+func (r memoryUsers) GetSummary(userID int) (*Summary, error) {
+	return r.summary(userID, 0)
+}
+
+func (r memoryUsers) GetSharedSummary(userID, viewerID int) (*Summary, error) {
+	return r.summary(userID, viewerID)
+}
+
+// sharedWith mirrors the SQL helper: a viewerID of 0 sees every project.
+func (m *Memory) sharedWith(viewerID, projectID int) bool {
+	return viewerID == 0 || m.members[[2]int{projectID, viewerID}] != nil
+}
+
+func (r memoryUsers) summary(userID, viewerID int) (*Summary, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var s Summary
+	for _, t := range m.tasks {
+		if !m.sharedWith(viewerID, t.projectID) {
+			continue
+		}
+		if contains(t.assignees, userID) {
+			s.AssignedCount++
+		}
+		if contains(t.collaborators, userID) {
+			s.CollaboratorCount++
+		}
+	}
+	ids := sortedKeys(m.comments)
+	for i := len(ids) - 1; i >= 0; i-- {
+		c := m.comments[ids[i]]
+		if c.userID == nil || *c.userID != userID || !m.sharedWith(viewerID, m.tasks[c.taskID].projectID) {
+			continue
+		}
+		s.CommentCount++
+		if len(s.RecentActivity) < 10 {
+			title := m.tasks[c.taskID].title
+			s.RecentActivity = append(s.RecentActivity, RecentActivity{
+				ID:        c.id,
+				Text:      c.text,
+				CreatedAt: c.createdAt.Format(time.RFC3339),
+				TaskTitle: &title,
+			})
+		}
+	}
+	for _, id := range m.involvedProjects(userID) {
+		if m.sharedWith(viewerID, id) {
+			s.ProjectCount++
+		}
+	}
+	return &s, nil
+}
+
+// involvedProjects returns the IDs of projects userID is a member of or has
+// tasks in, newest first.
+func (m *Memory) involvedProjects(userID int) []int {
+	var out []int
+	ids := sortedKeys(m.projects)
+	for i := len(ids) - 1; i >= 0; i-- {
+		id := ids[i]
+		involved := m.members[[2]int{id, userID}] != nil
+		for _, t := range m.tasks {
+			if t.projectID == id && (contains(t.assignees, userID) || contains(t.collaborators, userID)) {
+				involved = true
+			}
+		}
+		if involved {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (m *Memory) liteProject(id int) LiteProject {
+	p := m.projects[id]
+	desc := p.Description
+	return LiteProject{ID: p.ID, Name: p.Name, Description: &desc, DueDate: copyString(p.dueDate)}
+}
+
+func (r memoryUsers) GetMyProjects(userID int) ([]LiteProject, error) {
+	return r.projects(userID, 0)
+}
+
+func (r memoryUsers) GetSharedProjects(userID, viewerID int) ([]LiteProject, error) {
+	return r.projects(userID, viewerID)
+}
+
+func (r memoryUsers) projects(userID, viewerID int) ([]LiteProject, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []LiteProject
+	for _, id := range m.involvedProjects(userID) {
+		if len(out) == 50 {
+			break
+		}
+		if m.sharedWith(viewerID, id) {
+			out = append(out, m.liteProject(id))
+		}
+	}
+	return out, nil
+}
+
+func (r memoryUsers) GetProjectsByUserID(userID int) ([]LiteProject, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []LiteProject
+	ids := sortedKeys(m.projects)
+	for i := len(ids) - 1; i >= 0; i-- {
+		for _, t := range m.tasks {
+			if t.projectID == ids[i] && contains(t.assignees, userID) {
+				out = append(out, m.liteProject(ids[i]))
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r memoryUsers) GetUserSettings(userID int) (*model.UserSettings, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.settings[userID]
+	if !ok {
+		if _, ok := m.users[userID]; !ok {
+			return nil, errConstraint
+		}
+		s = model.UserSettings{
+			UserID:                userID,
+			NotificationsAssign:   true,
+			NotificationsDueDate:  true,
+			NotificationsComments: true,
+			AppearanceTheme:       "Automatic",
+		}
+		m.settings[userID] = s
+	}
+	return &s, nil
+}
+
+func (r memoryUsers) UpdateUserSettings(settings *model.UserSettings) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.settings[settings.UserID]; ok {
+		m.settings[settings.UserID] = *settings
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"planify/backend/internal/model"
+)
+
+type memSession struct {
+	model.Session
+	revokedAt *time.Time
+}
+
+type memRefresh struct {
+	sessionID string
+	expiresAt time.Time
+	used      bool
+}
+
+type memMFA struct {
+	secret       string
+	enabledAt    *time.Time
+	lastUsedStep int64
+}
+
+type memReset struct {
+	userID    int
+	expiresAt time.Time
+	used      bool
+}
+
+type memToken struct {
+	model.AccessToken
+	hash    string
+	revoked bool
+}
+
+type memorySessions struct{ m *Memory }
+
+func (r memorySessions) Create(s *model.Session, refreshHash string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sessions[s.ID]; ok || m.users[s.UserID] == nil {
+		return errConstraint
+	}
+	m.sessions[s.ID] = &memSession{Session: model.Session{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt.UTC(),
+		LastSeenAt: s.LastSeenAt.UTC(),
+		ExpiresAt:  s.ExpiresAt.UTC(),
+	}}
+	m.refresh[refreshHash] = &memRefresh{sessionID: s.ID, expiresAt: s.ExpiresAt.UTC()}
+	return nil
+}
+
+func (r memorySessions) Rotate(oldHash, newHash, ip string, expiresAt time.Time) (*model.Session, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	rt, ok := m.refresh[oldHash]
+	if !ok || !rt.expiresAt.After(now) {
+		return nil, sql.ErrNoRows
+	}
+	s := m.sessions[rt.sessionID]
+	if s.revokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	if rt.used {
+		s.revokedAt = &now
+		return nil, ErrTokenReused
+	}
+	rt.used = true
+	m.refresh[newHash] = &memRefresh{sessionID: s.ID, expiresAt: expiresAt.UTC()}
+	s.LastSeenAt, s.IP, s.ExpiresAt = now, ip, expiresAt.UTC()
+	return &model.Session{ID: s.ID, UserID: s.UserID, ExpiresAt: expiresAt, LastSeenAt: now}, nil
+}
+
+func (r memorySessions) Validate(id string, userID int, ip string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	now := time.Now()
+	if !ok || s.UserID != userID || s.revokedAt != nil || now.After(s.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+	if now.Sub(s.LastSeenAt) > time.Minute {
+		s.LastSeenAt, s.IP = now.UTC(), ip
+	}
+	return nil
+}
+
+func (r memorySessions) ListActive(userID int) ([]model.Session, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	out := []model.Session{}
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.revokedAt == nil && s.ExpiresAt.After(now) {
+			out = append(out, s.Session)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeenAt.After(out[j].LastSeenAt) })
+	return out, nil
+}
+
+func (r memorySessions) Revoke(id string, userID int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.UserID != userID || s.revokedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := time.Now().UTC()
+	s.revokedAt = &now
+	return nil
+}
+
+func (r memorySessions) RevokeAllForUser(userID int, keep string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.ID != keep && s.revokedAt == nil {
+			s.revokedAt = &now
+		}
+	}
+	return nil
+}
+
+type memoryMFA struct{ m *Memory }
+
+func (r memoryMFA) Get(userID int) (*model.MFA, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.mfa[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &model.MFA{UserID: userID, Secret: f.secret, Enabled: f.enabledAt != nil, LastUsedStep: f.lastUsedStep}, nil
+}
+
+func (r memoryMFA) IsEnabled(userID int) (bool, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.mfa[userID]
+	return ok && f.enabledAt != nil, nil
+}
+
+func (r memoryMFA) SavePending(userID int, secret string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.mfa[userID]
+	switch {
+	case !ok:
+		if m.users[userID] == nil {
+			return errConstraint
+		}
+		m.mfa[userID] = &memMFA{secret: secret}
+	case f.enabledAt != nil:
+		return ErrMFAAlreadyEnabled
+	default:
+		f.secret, f.lastUsedStep = secret, 0
+	}
+	return nil
+}
+
+func (r memoryMFA) Enable(userID int, step int64, codeHashes []string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.mfa[userID]
+	if !ok || f.enabledAt != nil {
+		return sql.ErrNoRows
+	}
+	now := time.Now().UTC()
+	f.enabledAt, f.lastUsedStep = &now, step
+	m.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (r memoryMFA) ConsumeStep(userID int, step int64) (bool, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.mfa[userID]
+	if !ok || f.lastUsedStep >= step {
+		return false, nil
+	}
+	f.lastUsedStep = step
+	return true, nil
+}
+
+func (r memoryMFA) UseRecoveryCode(userID int, codeHash string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	codes := m.recovery[userID]
+	if used, ok := codes[codeHash]; !ok || used {
+		return sql.ErrNoRows
+	}
+	codes[codeHash] = true
+	return nil
+}
+
+func (r memoryMFA) RemainingRecoveryCodes(userID int) (int, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, used := range m.recovery[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r memoryMFA) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (m *Memory) replaceRecoveryCodes(userID int, codeHashes []string) {
+	codes := map[string]bool{}
+	for _, h := range codeHashes {
+		codes[h] = false
+	}
+	m.recovery[userID] = codes
+}
+
+func (r memoryMFA) Disable(userID int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.recovery, userID)
+	delete(m.mfa, userID)
+	return nil
+}
+
+type memoryResets struct{ m *Memory }
+
+func (r memoryResets) Create(userID int, tokenHash string, expiresAt time.Time) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.resets[tokenHash]; ok || m.users[userID] == nil {
+		return errConstraint
+	}
+	m.resets[tokenHash] = &memReset{userID: userID, expiresAt: expiresAt.UTC()}
+	return nil
+}
+
+func (r memoryResets) Consume(tokenHash string) (int, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.resets[tokenHash]
+	if !ok || t.used || !t.expiresAt.After(time.Now()) {
+		return 0, sql.ErrNoRows
+	}
+	for _, other := range m.resets {
+		if other.userID == t.userID {
+			other.used = true
+		}
+	}
+	return t.userID, nil
+}
+
+type memoryTokens struct{ m *Memory }
+
+func (t *memToken) live(now time.Time) bool {
+	return !t.revoked && t.ExpiresAt.After(now)
+}
+
+func (t *memToken) model() *model.AccessToken {
+	out := t.AccessToken
+	out.Scopes = append([]model.Scope{}, t.Scopes...)
+	if t.LastUsedAt != nil {
+		at := *t.LastUsedAt
+		out.LastUsedAt = &at
+	}
+	return &out
+}
+
+func (r memoryTokens) Create(t *model.AccessToken, tokenHash string, limit int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	n := 0
+	for _, have := range m.tokens {
+		if have.UserID == t.UserID && have.live(now) {
+			n++
+		}
+	}
+	if limit > 0 && n >= limit {
+		return ErrTokenLimit
+	}
+	if m.users[t.UserID] == nil {
+		return errConstraint
+	}
+	t.CreatedAt = now
+	t.ID = m.nextID()
+	stored := &memToken{AccessToken: *t, hash: tokenHash}
+	stored.ExpiresAt = t.ExpiresAt.UTC()
+	stored.Scopes = append([]model.Scope{}, t.Scopes...)
+	m.tokens[t.ID] = stored
+	return nil
+}
+
+func (r memoryTokens) List(userID int) ([]model.AccessToken, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	out := []model.AccessToken{}
+	ids := sortedKeys(m.tokens)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if t := m.tokens[ids[i]]; t.UserID == userID && t.live(now) {
+			out = append(out, *t.model())
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r memoryTokens) Authenticate(tokenHash string) (*model.AccessToken, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	for _, t := range m.tokens {
+		if t.hash != tokenHash || !t.live(now) {
+			continue
+		}
+		if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) > time.Minute {
+			t.LastUsedAt = &now
+		}
+		return t.model(), nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r memoryTokens) Revoke(id, userID int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[id]
+	if !ok || t.UserID != userID || t.revoked {
+		return sql.ErrNoRows
+	}
+	t.revoked = true
+	return nil
+}
+
+type memoryEvents struct{ m *Memory }
+
+func (r memoryEvents) Record(e model.AuthEvent) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.ID = m.nextID()
+	e.CreatedAt = time.Now().UTC()
+	if e.UserID != nil {
+		id := *e.UserID
+		e.UserID = &id
+	}
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (r memoryEvents) List(event string, limit int) ([]model.AuthEvent, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []model.AuthEvent{}
+	for i := len(m.events) - 1; i >= 0 && len(out) < limit; i-- {
+		if event == "" || m.events[i].Event == event {
+			out = append(out, m.events[i])
+		}
+	}
+	return out, nil
+}
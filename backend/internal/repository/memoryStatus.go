@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"planify/backend/internal/model"
+)
+
+func (s *memStatus) model() model.Status {
+	return model.Status{
+		ID:        s.id,
+		ProjectID: s.projectID,
+		Title:     s.title,
+		Category:  s.category,
+		Color:     copyString(s.color),
+		Position:  s.position,
+		Archived:  s.archived != nil,
+
+		WIPLimit:       copyLimit(s.wipLimit),
+		WIPMode:        s.wipMode,
+		WIPPerAssignee: s.wipPerAssignee,
+	}
+}
+
+// copyLimit copies a WIP limit, treating 0 as no limit.
+func copyLimit(v *int) *int {
+	if v == nil || *v == 0 {
+		return nil
+	}
+	n := *v
+	return &n
+}
+
+// assigneeCounts counts tasks per assignee, ignoring the task with skipID.
+func assigneeCounts(tasks []*memTask, skipID int) []model.AssigneeCount {
+	byUser := map[int]int{}
+	for _, t := range tasks {
+		if t.id == skipID {
+			continue
+		}
+		for _, uid := range t.assignees {
+			byUser[uid]++
+		}
+	}
+	out := []model.AssigneeCount{}
+	for _, uid := range sortedKeys(byUser) {
+		out = append(out, model.AssigneeCount{UserID: uid, Count: byUser[uid]})
+	}
+	return out
+}
+
+// checkWIP mirrors the SQL checkWIP for t joining statusID. A zero task
+// stands for one being created.
+func (m *Memory) checkWIP(t *memTask, statusID int) []model.WIPViolation {
+	s := m.status(statusID)
+	if s.wipLimit == nil {
+		return nil
+	}
+	var inColumn []*memTask
+	for _, other := range m.tasks {
+		if other.statusID == statusID && other.id != t.id {
+			inColumn = append(inColumn, other)
+		}
+	}
+	if !s.wipPerAssignee {
+		return model.CheckWIP(s.model(), len(inColumn)+1, nil)
+	}
+	counts := map[int]int{}
+	for _, c := range assigneeCounts(inColumn, 0) {
+		counts[c.UserID] = c.Count
+	}
+	var mine []model.AssigneeCount
+	for _, uid := range t.assignees {
+		mine = append(mine, model.AssigneeCount{UserID: uid, Count: counts[uid] + 1})
+	}
+	sort.Slice(mine, func(i, j int) bool { return mine[i].UserID < mine[j].UserID })
+	return model.CheckWIP(s.model(), 0, mine)
+}
+
+// projectStatuses returns the project's columns ordered by position, then ID.
+func (m *Memory) projectStatuses(projectID int, includeArchived bool) []*memStatus {
+	var out []*memStatus
+	for _, id := range sortedKeys(m.statuses) {
+		s := m.statuses[id]
+		if s.projectID == projectID && (includeArchived || s.archived == nil) {
+			out = append(out, s)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].position < out[j].position })
+	return out
+}
+
+func (m *Memory) statusValid(projectID, statusID int) error {
+	s, ok := m.statuses[statusID]
+	if !ok || s.projectID != projectID || s.archived != nil {
+		return ErrInvalidStatus
+	}
+	return nil
+}
+
+type memoryStatuses struct{ m *Memory }
+
+func (r memoryStatuses) List(projectID int, includeArchived bool) ([]model.Status, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []model.Status{}
+	for _, s := range m.projectStatuses(projectID, includeArchived) {
+		out = append(out, s.model())
+	}
+	return out, nil
+}
+
+func (r memoryStatuses) Get(projectID, statusID int) (*model.Status, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.statuses[statusID]
+	if !ok || s.projectID != projectID {
+		return nil, sql.ErrNoRows
+	}
+	out := s.model()
+	return &out, nil
+}
+
+func (r memoryStatuses) Create(projectID int, payload CreateStatusPayload) (*model.Status, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.projects[projectID] == nil {
+		return nil, errConstraint
+	}
+	if payload.Category == "" {
+		payload.Category = model.CategoryTodo
+	}
+	if payload.WIPMode == "" {
+		payload.WIPMode = model.WIPSoft
+	}
+	next := 0
+	for _, s := range m.projectStatuses(projectID, true) {
+		if s.position >= next {
+			next = s.position + 1
+		}
+	}
+	s := &memStatus{
+		id:        m.nextID(),
+		projectID: projectID,
+		title:     strings.TrimSpace(payload.Title),
+		category:  payload.Category,
+		color:     copyString(payload.Color),
+		position:  next,
+
+		wipLimit:       copyLimit(payload.WIPLimit),
+		wipMode:        payload.WIPMode,
+		wipPerAssignee: payload.WIPPerAssignee,
+	}
+	m.statuses[s.id] = s
+	out := s.model()
+	return &out, nil
+}
+
+func (r memoryStatuses) Update(projectID, statusID int, payload UpdateStatusPayload) (*model.Status, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.statuses[statusID]
+	if !ok || s.projectID != projectID {
+		return nil, sql.ErrNoRows
+	}
+	if payload.Archived != nil && *payload.Archived {
+		for _, t := range m.tasks {
+			if t.statusID == statusID {
+				return nil, ErrStatusNotEmpty
+			}
+		}
+	}
+	if payload.Title != nil {
+		s.title = strings.TrimSpace(*payload.Title)
+	}
+	if payload.Category != nil {
+		s.category = *payload.Category
+	}
+	if payload.Color != nil {
+		s.color = nil
+		if *payload.Color != "" {
+			s.color = copyString(payload.Color)
+		}
+	}
+	if payload.WIPLimit != nil {
+		s.wipLimit = copyLimit(payload.WIPLimit)
+	}
+	if payload.WIPMode != nil {
+		s.wipMode = *payload.WIPMode
+	}
+	if payload.WIPPerAssignee != nil {
+		s.wipPerAssignee = *payload.WIPPerAssignee
+	}
+	if payload.Archived != nil {
+		s.archived = nil
+		if *payload.Archived {
+			now := time.Now().UTC()
+			s.archived = &now
+		}
+	}
+	out := s.model()
+	return &out, nil
+}
+
+func (r memoryStatuses) Reorder(projectID int, ids []int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	have := map[int]bool{}
+	for _, s := range m.projectStatuses(projectID, true) {
+		have[s.id] = true
+	}
+	if err := checkStatusOrder(have, ids); err != nil {
+		return err
+	}
+	for pos, id := range ids {
+		m.statuses[id].position = pos
+	}
+	return nil
+}
+
+func copyTransition(t *model.Transition) model.Transition {
+	out := *t
+	if t.FromStatusID != nil {
+		from := *t.FromStatusID
+		out.FromStatusID = &from
+	}
+	out.Roles = append([]model.Role{}, t.Roles...)
+	out.RequiredFields = append([]model.TaskField{}, t.RequiredFields...)
+	return out
+}
+
+func (m *Memory) projectRules(projectID int) []model.Transition {
+	out := []model.Transition{}
+	for _, id := range sortedKeys(m.rules) {
+		if t := m.rules[id]; t.ProjectID == projectID {
+			out = append(out, copyTransition(t))
+		}
+	}
+	return out
+}
+
+type memoryTransitions struct{ m *Memory }
+
+func (r memoryTransitions) List(projectID int) ([]model.Transition, error) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.projectRules(projectID), nil
+}
+
+func (r memoryTransitions) Create(t *model.Transition) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := []int{t.ToStatusID}
+	if t.FromStatusID != nil {
+		ids = append(ids, *t.FromStatusID)
+	}
+	for _, id := range ids {
+		if s, ok := m.statuses[id]; !ok || s.projectID != t.ProjectID {
+			return ErrInvalidStatus
+		}
+	}
+	for _, other := range m.rules {
+		if other.ProjectID == t.ProjectID && other.ToStatusID == t.ToStatusID &&
+			fromKey(other.FromStatusID) == fromKey(t.FromStatusID) {
+			return ErrTransitionExists
+		}
+	}
+	t.ID = m.nextID()
+	stored := copyTransition(t)
+	m.rules[t.ID] = &stored
+	return nil
+}
+
+func (r memoryTransitions) Delete(projectID, id int) error {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.rules[id]
+	if !ok || t.ProjectID != projectID {
+		return sql.ErrNoRows
+	}
+	delete(m.rules, id)
+	return nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/model"
+)
+
+var ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+
+type MFARepository struct {
+	DB *database.DB
+}
+
+func (r *MFARepository) Get(userID int) (*model.MFA, error) {
+	m := model.MFA{UserID: userID}
+	err := r.DB.QueryRow(
+		`SELECT secret, enabled_at IS NOT NULL, last_used_step FROM user_mfa WHERE user_id = ?`, userID,
+	).Scan(&m.Secret, &m.Enabled, &m.LastUsedStep)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *MFARepository) IsEnabled(userID int) (bool, error) {
+	m, err := r.Get(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return m.Enabled, nil
+}
+
+// SavePending stores a new, not yet confirmed secret, replacing any earlier
+// unfinished enrollment.
+func (r *MFARepository) SavePending(userID int, secret string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var enabled bool
+	err = tx.QueryRow(`SELECT enabled_at IS NOT NULL FROM user_mfa WHERE user_id = ? FOR UPDATE`, userID).Scan(&enabled)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, err := tx.Exec(`INSERT INTO user_mfa (user_id, secret, last_used_step) VALUES (?, ?, 0)`, userID, secret); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case enabled:
+		return ErrMFAAlreadyEnabled
+	default:
+		if _, err := tx.Exec(`UPDATE user_mfa SET secret = ?, last_used_step = 0 WHERE user_id = ?`, secret, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *MFARepository) Enable(userID int, step int64, codeHashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE user_mfa SET enabled_at = ?, last_used_step = ? WHERE user_id = ? AND enabled_at IS NULL`,
+		time.Now().UTC(), step, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeStep records step as used. It returns false when that step (or a
+// later one) was already accepted, which blocks replaying a code.
+func (r *MFARepository) ConsumeStep(userID int, step int64) (bool, error) {
+	res, err := r.DB.Exec(
+		`UPDATE user_mfa SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`,
+		step, userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *MFARepository) UseRecoveryCode(userID int, codeHash string) error {
+	res, err := r.DB.Exec(
+		`UPDATE user_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now().UTC(), userID, codeHash,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *MFARepository) RemainingRecoveryCodes(userID int) (int, error) {
+	var n int
+	err := r.DB.QueryRow(
+		`SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID,
+	).Scan(&n)
+	return n, err
+}
+
+func (r *MFARepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *MFARepository) Disable(userID int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *database.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
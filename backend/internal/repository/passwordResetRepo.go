@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"time"
+
+	"planify/backend/internal/database"
+)
+
+type PasswordResetRepository struct {
+	DB *database.DB
+}
+
+func (r *PasswordResetRepository) Create(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := r.DB.Exec(
+		"INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES (?, ?, ?)",
+		userID, tokenHash, expiresAt.UTC(),
+	)
+	return err
+}
+
+// Consume marks the token as used and retires every other outstanding token
+// for the same user. It returns sql.ErrNoRows for unknown, used or expired
+// tokens.
+func (r *PasswordResetRepository) Consume(tokenHash string) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id, userID int
+	err = tx.QueryRow(`
+		SELECT id, user_id FROM password_resets
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		FOR UPDATE`, tokenHash, time.Now().UTC(),
+	).Scan(&id, &userID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(
+		"UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL",
+		time.Now().UTC(), userID,
+	); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
@@ -2,16 +2,22 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"planify/backend/internal/database"
 	"planify/backend/internal/model"
 	"strings"
 )
 
 type ProjectRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
-func (r *ProjectRepository) GetAll() ([]model.Project, error) {
-	rows, err := r.DB.Query(`SELECT id, name, description, created_at FROM projects`)
+func (r *ProjectRepository) GetAll(userID int) ([]model.Project, error) {
+	rows, err := r.DB.Query(`
+		SELECT p.id, p.name, p.description, p.created_at
+		FROM projects p
+		JOIN project_members pm ON pm.project_id = p.id
+		WHERE pm.user_id = ?`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -34,10 +40,12 @@ func (r *ProjectRepository) GetByID(id int) (map[string]interface{}, error) {
 	var name, description string
 	var createdAt sql.NullTime
 	var dueDate sql.NullString
+	var require2FA bool
+	var version int
 	err := r.DB.QueryRow(
-		`SELECT name, description, due_date, created_at FROM projects WHERE id = ?`,
+		`SELECT name, description, due_date, created_at, require_2fa, version FROM projects WHERE id = ?`,
 		id,
-	).Scan(&name, &description, &dueDate, &createdAt)
+	).Scan(&name, &description, &dueDate, &createdAt, &require2FA, &version)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +53,8 @@ func (r *ProjectRepository) GetByID(id int) (map[string]interface{}, error) {
 	projectData["id"] = id
 	projectData["name"] = name
 	projectData["description"] = description
+	projectData["require2fa"] = require2FA
+	projectData["version"] = version
 	if createdAt.Valid {
 		projectData["createdAt"] = createdAt.Time
 	} else {
@@ -75,7 +85,36 @@ func (r *ProjectRepository) GetByID(id int) (map[string]interface{}, error) {
 	}
 	projectData["team"] = team
 
-	statusRows, err := r.DB.Query(`SELECT id, title FROM statuses ORDER BY position`)
+	assigneeCounts := make(map[int][]model.AssigneeCount)
+	countRows, err := r.DB.Query(`
+		SELECT t.status_id, ta.user_id, COUNT(*)
+		FROM tasks t
+		JOIN task_assignees ta ON ta.task_id = t.id
+		WHERE t.project_id = ?
+		GROUP BY t.status_id, ta.user_id
+		ORDER BY ta.user_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	for countRows.Next() {
+		var statusID int
+		var ac model.AssigneeCount
+		if err := countRows.Scan(&statusID, &ac.UserID, &ac.Count); err != nil {
+			countRows.Close()
+			return nil, err
+		}
+		assigneeCounts[statusID] = append(assigneeCounts[statusID], ac)
+	}
+	countRows.Close()
+	if err := countRows.Err(); err != nil {
+		return nil, err
+	}
+
+	statusRows, err := r.DB.Query(`
+		SELECT `+statusColumns+`
+		FROM statuses
+		WHERE project_id = ? AND archived_at IS NULL
+		ORDER BY position, id`, id)
 	if err != nil {
 		return nil, err
 	}
@@ -84,19 +123,20 @@ func (r *ProjectRepository) GetByID(id int) (map[string]interface{}, error) {
 	tasksByStatus := make(map[int][]map[string]interface{})
 
 	taskRows, err := r.DB.Query(`
-		SELECT id, status_id, title, description, COALESCE(position, 0)
+		SELECT id, status_id, title, description, rank_key, version
 		FROM tasks
-		WHERE project_id = ?`, id)
+		WHERE project_id = ?
+		ORDER BY status_id, rank_key, id`, id)
 	if err != nil {
 		return nil, err
 	}
 	defer taskRows.Close()
 
 	for taskRows.Next() {
-		var taskID, statusID, position int
-		var title string
+		var taskID, statusID, version int
+		var title, rank string
 		var desc sql.NullString
-		if err := taskRows.Scan(&taskID, &statusID, &title, &desc, &position); err != nil {
+		if err := taskRows.Scan(&taskID, &statusID, &title, &desc, &rank, &version); err != nil {
 			return nil, err
 		}
 		var descVal string
@@ -107,7 +147,9 @@ func (r *ProjectRepository) GetByID(id int) (map[string]interface{}, error) {
 			"id":          taskID,
 			"title":       title,
 			"description": descVal,
-			"position":    position,
+			"rank":        rank,
+			"position":    len(tasksByStatus[statusID]),
+			"version":     version,
 			"assignees":   []model.User{},
 		}
 		tasksByStatus[statusID] = append(tasksByStatus[statusID], taskData)
@@ -115,25 +157,52 @@ func (r *ProjectRepository) GetByID(id int) (map[string]interface{}, error) {
 
 	var columns []map[string]interface{}
 	for statusRows.Next() {
-		var statusID int
-		var statusTitle string
-		if err := statusRows.Scan(&statusID, &statusTitle); err != nil {
+		st, err := scanStatus(statusRows)
+		if err != nil {
 			return nil, err
 		}
-		columnData := map[string]interface{}{
-			"id":    statusID,
-			"title": statusTitle,
-			"tasks": tasksByStatus[statusID],
-		}
-		columns = append(columns, columnData)
+		columns = append(columns, boardColumn(*st, tasksByStatus[st.ID], assigneeCounts[st.ID]))
 	}
 	projectData["columns"] = columns
 
 	return projectData, nil
 }
 
+func (r *ProjectRepository) GetMemberRole(projectID, userID int) (model.Role, error) {
+	var role model.Role
+	err := r.DB.QueryRow(
+		`SELECT role FROM project_members WHERE project_id = ? AND user_id = ?`,
+		projectID, userID,
+	).Scan(&role)
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *ProjectRepository) GetMemberAccess(projectID, userID int) (*model.MemberAccess, error) {
+	var a model.MemberAccess
+	err := r.DB.QueryRow(`
+		SELECT pm.role, p.require_2fa, COALESCE(mfa.enabled_at IS NOT NULL, FALSE)
+		FROM project_members pm
+		JOIN projects p ON p.id = pm.project_id
+		LEFT JOIN user_mfa mfa ON mfa.user_id = pm.user_id
+		WHERE pm.project_id = ? AND pm.user_id = ?`,
+		projectID, userID,
+	).Scan(&a.Role, &a.Require2FA, &a.HasMFA)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *ProjectRepository) SetRequire2FA(projectID int, required bool, version int) error {
+	return r.update(projectID, version, `UPDATE projects SET require_2fa = ? WHERE id = ?`, required, projectID)
+}
+
 type UpdateDueDatePayload struct {
 	DueDate *string `json:"dueDate"`
+	Version int     `json:"-"`
 }
 
 func (r *ProjectRepository) UpdateDueDate(projectID int, payload UpdateDueDatePayload) error {
@@ -143,8 +212,24 @@ func (r *ProjectRepository) UpdateDueDate(projectID int, payload UpdateDueDatePa
 	} else {
 		arg = nil
 	}
-	_, err := r.DB.Exec(`UPDATE projects SET due_date = ? WHERE id = ?`, arg, projectID)
-	return err
+	return r.update(projectID, payload.Version, `UPDATE projects SET due_date = ? WHERE id = ?`, arg, projectID)
+}
+
+// update runs a single-statement project change and bumps its version,
+// which must match version unless that is 0.
+func (r *ProjectRepository) update(projectID, version int, query string, args ...any) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := bumpVersion(tx, "projects", projectID, version); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 type CreateProjectPayload struct {
@@ -161,7 +246,7 @@ func (r *ProjectRepository) Create(payload CreateProjectPayload) (*model.Project
 		return nil, err
 	}
 
-	res, err := tx.Exec(
+	projectID, err := tx.InsertID(
 		`INSERT INTO projects (name, description, due_date, owner_id) VALUES (?, ?, ?, ?)`,
 		payload.Name, payload.Description, payload.DueDate, payload.OwnerID,
 	)
@@ -169,11 +254,6 @@ func (r *ProjectRepository) Create(payload CreateProjectPayload) (*model.Project
 		tx.Rollback()
 		return nil, err
 	}
-	projectID, err := res.LastInsertId()
-	if err != nil {
-		tx.Rollback()
-		return nil, err
-	}
 	
 	_, err = tx.Exec(
 		`INSERT INTO project_members (project_id, user_id, role) VALUES (?, ?, ?)`,
@@ -184,6 +264,11 @@ func (r *ProjectRepository) Create(payload CreateProjectPayload) (*model.Project
 		return nil, err
 	}
 
+	if err := createDefaultStatuses(tx, projectID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	if len(payload.TeamIDs) > 0 {
 		stmt, err := tx.Prepare(`INSERT INTO project_members (project_id, user_id, role) VALUES (?, ?, 'member')`)
 		if err != nil {
@@ -191,12 +276,20 @@ func (r *ProjectRepository) Create(payload CreateProjectPayload) (*model.Project
 			return nil, err
 		}
 		defer stmt.Close()
+		// Duplicates are skipped here rather than with INSERT IGNORE, which
+		// on MySQL would also swallow the foreign key error for an unknown user.
+		seen := map[int]bool{payload.OwnerID: true}
 		for _, userID := range payload.TeamIDs {
+			if seen[userID] {
+				continue
+			}
+			seen[userID] = true
 			if _, err := stmt.Exec(projectID, userID); err != nil {
-				if !strings.Contains(err.Error(), "Duplicate entry") {
-					tx.Rollback()
-					return nil, err
+				tx.Rollback()
+				if database.IsForeignKeyViolation(err) {
+					return nil, ErrUnknownMember
 				}
+				return nil, err
 			}
 		}
 	}
@@ -212,4 +305,228 @@ func (r *ProjectRepository) Create(payload CreateProjectPayload) (*model.Project
 		OwnerID:     &payload.OwnerID,
 	}
 	return newProject, nil
-}
\ No newline at end of file
+}
+var (
+	ErrAlreadyMember = errors.New("user is already a project member")
+	ErrUnknownMember = errors.New("team member does not exist")
+)
+
+func (r *ProjectRepository) ListMembers(projectID int) ([]model.ProjectMember, error) {
+	rows, err := r.DB.Query(`
+		SELECT u.id, u.name, u.email, COALESCE(u.avatar, ''), pm.role
+		FROM project_members pm
+		JOIN users u ON u.id = pm.user_id
+		WHERE pm.project_id = ?
+		ORDER BY u.name`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.ProjectMember{}
+	for rows.Next() {
+		var m model.ProjectMember
+		if err := rows.Scan(&m.ID, &m.Name, &m.Email, &m.Avatar, &m.Role); err != nil {
+			return nil, err
+		}
+		m.Avatar = defaultAvatar(m.Avatar)
+		m.Capabilities = m.Role.Capabilities()
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// AddMember reports sql.ErrNoRows when the user does not exist.
+func (r *ProjectRepository) AddMember(projectID, userID int, role model.Role) error {
+	var n int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM users WHERE id = ?`, userID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err := r.DB.Exec(
+		`INSERT INTO project_members (project_id, user_id, role) VALUES (?, ?, ?)`,
+		projectID, userID, role,
+	)
+	if database.IsUniqueViolation(err) {
+		return ErrAlreadyMember
+	}
+	return err
+}
+
+func (r *ProjectRepository) UpdateMemberRole(projectID, userID int, role model.Role) error {
+	res, err := r.DB.Exec(
+		`UPDATE project_members SET role = ? WHERE project_id = ? AND user_id = ?`,
+		role, projectID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *ProjectRepository) RemoveMember(projectID, userID int) error {
+	res, err := r.DB.Exec(
+		`DELETE FROM project_members WHERE project_id = ? AND user_id = ?`,
+		projectID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *ProjectRepository) TransferOwnership(projectID, fromUserID, toUserID int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE project_members SET role = ? WHERE project_id = ? AND user_id = ?`,
+		model.RoleOwner, projectID, toUserID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`UPDATE project_members SET role = ? WHERE project_id = ? AND user_id = ?`,
+		model.RoleAdmin, projectID, fromUserID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE projects SET owner_id = ? WHERE id = ?`, toUserID, projectID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *ProjectRepository) Delete(projectID, version int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := bumpVersion(tx, "projects", projectID, version); err != nil {
+		return err
+	}
+
+	stmts := []string{
+		`DELETE FROM task_comments WHERE task_id IN (SELECT id FROM tasks WHERE project_id = ?)`,
+		`DELETE FROM attachments WHERE task_id IN (SELECT id FROM tasks WHERE project_id = ?)`,
+		`DELETE FROM task_assignees WHERE task_id IN (SELECT id FROM tasks WHERE project_id = ?)`,
+		`DELETE FROM task_collaborators WHERE task_id IN (SELECT id FROM tasks WHERE project_id = ?)`,
+		`DELETE FROM tasks WHERE project_id = ?`,
+		`DELETE FROM status_transitions WHERE project_id = ?`,
+		`DELETE FROM statuses WHERE project_id = ?`,
+		`DELETE FROM project_members WHERE project_id = ?`,
+	}
+	for _, q := range stmts {
+		if _, err := tx.Exec(q, projectID); err != nil {
+			return err
+		}
+	}
+	res, err := tx.Exec(`DELETE FROM projects WHERE id = ?`, projectID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SyncDirectoryRoles applies roles granted by directory groups. Only rows the
+// directory created (source = 'ldap') are changed or removed; memberships
+// added by hand, owners included, are left alone.
+func (r *ProjectRepository) SyncDirectoryRoles(userID int, roles map[int]*model.Role) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for projectID, want := range roles {
+		var current model.Role
+		err := tx.QueryRow(
+			`SELECT role FROM project_members WHERE project_id = ? AND user_id = ?`,
+			projectID, userID,
+		).Scan(&current)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if want == nil {
+				continue
+			}
+			if _, err := tx.Exec(
+				`INSERT INTO project_members (project_id, user_id, role, source) VALUES (?, ?, ?, 'ldap')`,
+				projectID, userID, *want,
+			); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case current == model.RoleOwner:
+		case want == nil:
+			if _, err := tx.Exec(
+				`DELETE FROM project_members WHERE project_id = ? AND user_id = ? AND source = 'ldap'`,
+				projectID, userID,
+			); err != nil {
+				return err
+			}
+		case *want != current:
+			if _, err := tx.Exec(
+				`UPDATE project_members SET role = ? WHERE project_id = ? AND user_id = ? AND source = 'ldap'`,
+				*want, projectID, userID,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// boardColumn builds one column of the board payload, with the task counts
+// next to the WIP limit and any limits the column is currently over.
+func boardColumn(s model.Status, tasks []map[string]interface{}, counts []model.AssigneeCount) map[string]interface{} {
+	if counts == nil {
+		counts = []model.AssigneeCount{}
+	}
+	violations := model.CheckWIP(s, len(tasks), counts)
+	if violations == nil {
+		violations = []model.WIPViolation{}
+	}
+	var color interface{}
+	if s.Color != nil {
+		color = *s.Color
+	}
+	return map[string]interface{}{
+		"id":             s.ID,
+		"title":          s.Title,
+		"category":       s.Category,
+		"color":          color,
+		"tasks":          tasks,
+		"taskCount":      len(tasks),
+		"assigneeCounts": counts,
+		"wipLimit":       s.WIPLimit,
+		"wipMode":        s.WIPMode,
+		"wipPerAssignee": s.WIPPerAssignee,
+		"wipViolations":  violations,
+	}
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/model"
+)
+
+var (
+	ErrSessionRevoked = errors.New("session revoked or expired")
+	ErrTokenReused    = errors.New("refresh token reused")
+)
+
+// Sessions are the refresh-token families: every rotation adds a row to
+// refresh_tokens under the same session, and revoking the session kills the
+// whole family at once.
+type SessionRepository struct {
+	DB *database.DB
+}
+
+func (r *SessionRepository) Create(s *model.Session, refreshHash string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO sessions (id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.UserID, s.UserAgent, s.IP, s.CreatedAt.UTC(), s.LastSeenAt.UTC(), s.ExpiresAt.UTC(),
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES (?, ?, ?)`,
+		s.ID, refreshHash, s.ExpiresAt.UTC(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Rotate exchanges a refresh token for a new one. Presenting a token that was
+// already rotated revokes the session and returns ErrTokenReused.
+func (r *SessionRepository) Rotate(oldHash, newHash, ip string, expiresAt time.Time) (*model.Session, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var tokenID int
+	var usedAt sql.NullTime
+	var s model.Session
+	var revokedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT rt.id, rt.used_at, s.id, s.user_id, s.revoked_at
+		FROM refresh_tokens rt
+		JOIN sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = ? AND rt.expires_at > ?
+		FOR UPDATE`, oldHash, time.Now().UTC(),
+	).Scan(&tokenID, &usedAt, &s.ID, &s.UserID, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		return nil, ErrSessionRevoked
+	}
+	if usedAt.Valid {
+		if _, err := tx.Exec(`UPDATE sessions SET revoked_at = ? WHERE id = ?`, time.Now().UTC(), s.ID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET used_at = ? WHERE id = ?`, now, tokenID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES (?, ?, ?)`,
+		s.ID, newHash, expiresAt.UTC(),
+	); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		`UPDATE sessions SET last_seen_at = ?, ip = ?, expires_at = ? WHERE id = ?`,
+		now, ip, expiresAt.UTC(), s.ID,
+	); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.ExpiresAt = expiresAt
+	s.LastSeenAt = now
+	return &s, nil
+}
+
+// Validate reports whether the session may still be used by userID and
+// refreshes last_seen_at at most once a minute.
+func (r *SessionRepository) Validate(id string, userID int, ip string) error {
+	var owner int
+	var revokedAt sql.NullTime
+	var expiresAt, lastSeen time.Time
+	err := r.DB.QueryRow(
+		`SELECT user_id, revoked_at, expires_at, last_seen_at FROM sessions WHERE id = ?`, id,
+	).Scan(&owner, &revokedAt, &expiresAt, &lastSeen)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	now := time.Now()
+	if owner != userID || revokedAt.Valid || now.After(expiresAt) {
+		return ErrSessionRevoked
+	}
+	if now.Sub(lastSeen) > time.Minute {
+		_, err = r.DB.Exec(`UPDATE sessions SET last_seen_at = ?, ip = ? WHERE id = ?`, now.UTC(), ip, id)
+	}
+	return err
+}
+
+func (r *SessionRepository) ListActive(userID int) ([]model.Session, error) {
+	rows, err := r.DB.Query(`
+		SELECT id, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.Session{}
+	for rows.Next() {
+		s := model.Session{UserID: userID}
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *SessionRepository) Revoke(id string, userID int) error {
+	res, err := r.DB.Exec(
+		`UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), id, userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SessionRepository) RevokeAllForUser(userID int, keep string) error {
+	_, err := r.DB.Exec(
+		`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id <> ? AND revoked_at IS NULL`,
+		time.Now().UTC(), userID, keep,
+	)
+	return err
+}
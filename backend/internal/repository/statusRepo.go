@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/model"
+)
+
+var (
+	// ErrInvalidStatus means the status does not belong to the task's
+	// project or has been archived.
+	ErrInvalidStatus  = errors.New("status does not belong to this project")
+	ErrStatusNotEmpty = errors.New("status still has tasks")
+	// ErrStatusOrder means a reorder did not list every status of the
+	// project exactly once.
+	ErrStatusOrder = errors.New("status order must list every project status once")
+)
+
+// WIPLimitError is returned when a task would take a hard-limited column over
+// its work-in-progress limit.
+type WIPLimitError struct {
+	Violations []model.WIPViolation
+}
+
+func (e *WIPLimitError) Error() string {
+	return "column is at its work-in-progress limit"
+}
+
+type StatusRepository struct {
+	DB *database.DB
+}
+
+type CreateStatusPayload struct {
+	Title          string               `json:"title" binding:"required"`
+	Category       model.StatusCategory `json:"category"`
+	Color          *string              `json:"color"`
+	WIPLimit       *int                 `json:"wipLimit"`
+	WIPMode        model.WIPMode        `json:"wipMode"`
+	WIPPerAssignee bool                 `json:"wipPerAssignee"`
+}
+
+// UpdateStatusPayload changes only the fields that are set. A WIPLimit of 0
+// removes the limit.
+type UpdateStatusPayload struct {
+	Title          *string               `json:"title"`
+	Category       *model.StatusCategory `json:"category"`
+	Color          *string               `json:"color"`
+	Archived       *bool                 `json:"archived"`
+	WIPLimit       *int                  `json:"wipLimit"`
+	WIPMode        *model.WIPMode        `json:"wipMode"`
+	WIPPerAssignee *bool                 `json:"wipPerAssignee"`
+}
+
+const statusColumns = `id, project_id, title, category, color, position, archived_at IS NOT NULL,
+	wip_limit, wip_mode, wip_per_assignee`
+
+func scanStatus(row interface{ Scan(...any) error }) (*model.Status, error) {
+	var s model.Status
+	var color sql.NullString
+	var limit sql.NullInt64
+	if err := row.Scan(
+		&s.ID, &s.ProjectID, &s.Title, &s.Category, &color, &s.Position, &s.Archived,
+		&limit, &s.WIPMode, &s.WIPPerAssignee,
+	); err != nil {
+		return nil, err
+	}
+	if color.Valid {
+		s.Color = &color.String
+	}
+	if limit.Valid {
+		n := int(limit.Int64)
+		s.WIPLimit = &n
+	}
+	return &s, nil
+}
+
+// wipLimitValue maps a requested limit to the stored value; 0 clears it.
+func wipLimitValue(limit *int) any {
+	if limit == nil || *limit == 0 {
+		return nil
+	}
+	return *limit
+}
+
+func (r *StatusRepository) List(projectID int, includeArchived bool) ([]model.Status, error) {
+	q := `SELECT ` + statusColumns + ` FROM statuses WHERE project_id = ?`
+	if !includeArchived {
+		q += ` AND archived_at IS NULL`
+	}
+	rows, err := r.DB.Query(q+` ORDER BY position, id`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.Status{}
+	for rows.Next() {
+		s, err := scanStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func (r *StatusRepository) Get(projectID, statusID int) (*model.Status, error) {
+	return scanStatus(r.DB.QueryRow(
+		`SELECT `+statusColumns+` FROM statuses WHERE id = ? AND project_id = ?`, statusID, projectID,
+	))
+}
+
+func (r *StatusRepository) Create(projectID int, payload CreateStatusPayload) (*model.Status, error) {
+	if payload.Category == "" {
+		payload.Category = model.CategoryTodo
+	}
+	if payload.WIPMode == "" {
+		payload.WIPMode = model.WIPSoft
+	}
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var next int
+	if err := tx.QueryRow(
+		`SELECT COALESCE(MAX(position), -1) + 1 FROM statuses WHERE project_id = ?`, projectID,
+	).Scan(&next); err != nil {
+		return nil, err
+	}
+	id, err := tx.InsertID(`
+		INSERT INTO statuses (project_id, title, category, color, position, wip_limit, wip_mode, wip_per_assignee)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		projectID, strings.TrimSpace(payload.Title), payload.Category, payload.Color, next,
+		wipLimitValue(payload.WIPLimit), payload.WIPMode, payload.WIPPerAssignee,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.Get(projectID, int(id))
+}
+
+func (r *StatusRepository) Update(projectID, statusID int, payload UpdateStatusPayload) (*model.Status, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var set []string
+	var args []any
+	if payload.Title != nil {
+		set = append(set, "title = ?")
+		args = append(args, strings.TrimSpace(*payload.Title))
+	}
+	if payload.Category != nil {
+		set = append(set, "category = ?")
+		args = append(args, *payload.Category)
+	}
+	if payload.Color != nil {
+		set = append(set, "color = ?")
+		if *payload.Color == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *payload.Color)
+		}
+	}
+	if payload.WIPLimit != nil {
+		set = append(set, "wip_limit = ?")
+		args = append(args, wipLimitValue(payload.WIPLimit))
+	}
+	if payload.WIPMode != nil {
+		set = append(set, "wip_mode = ?")
+		args = append(args, *payload.WIPMode)
+	}
+	if payload.WIPPerAssignee != nil {
+		set = append(set, "wip_per_assignee = ?")
+		args = append(args, *payload.WIPPerAssignee)
+	}
+	if payload.Archived != nil {
+		set = append(set, "archived_at = ?")
+		if *payload.Archived {
+			// The lock keeps a concurrent move or create from landing a task
+			// between the count and the update.
+			if err := lockStatus(tx, statusID); err != nil {
+				return nil, err
+			}
+			var n int
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM tasks WHERE status_id = ? AND project_id = ?`, statusID, projectID).Scan(&n); err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				return nil, ErrStatusNotEmpty
+			}
+			args = append(args, time.Now().UTC())
+		} else {
+			args = append(args, nil)
+		}
+	}
+	if len(set) > 0 {
+		args = append(args, statusID, projectID)
+		res, err := tx.Exec(`UPDATE statuses SET `+strings.Join(set, ", ")+` WHERE id = ? AND project_id = ?`, args...)
+		if err != nil {
+			return nil, err
+		}
+		if err := requireAffected(res); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.Get(projectID, statusID)
+}
+
+// Reorder sets positions to follow ids, which must name every status of the
+// project, archived ones included.
+func (r *StatusRepository) Reorder(projectID int, ids []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM statuses WHERE project_id = ?`, projectID)
+	if err != nil {
+		return err
+	}
+	have := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		have[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := checkStatusOrder(have, ids); err != nil {
+		return err
+	}
+	for pos, id := range ids {
+		if _, err := tx.Exec(`UPDATE statuses SET position = ? WHERE id = ?`, pos, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func checkStatusOrder(have map[int]bool, ids []int) error {
+	if len(ids) != len(have) {
+		return ErrStatusOrder
+	}
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if !have[id] || seen[id] {
+			return ErrStatusOrder
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// createDefaultStatuses gives a new project the standard columns.
+func createDefaultStatuses(tx *database.Tx, projectID int64) error {
+	for pos, s := range model.DefaultStatuses {
+		if _, err := tx.Exec(
+			`INSERT INTO statuses (project_id, title, category, position) VALUES (?, ?, ?, ?)`,
+			projectID, s.Title, s.Category, pos,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskStatusValid reports whether statusID is an active column of the
+// project.
+func taskStatusValid(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, projectID, statusID int) error {
+	var n int
+	err := q.QueryRow(
+		`SELECT COUNT(*) FROM statuses WHERE id = ? AND project_id = ? AND archived_at IS NULL`,
+		statusID, projectID,
+	).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidStatus
+	}
+	return nil
+}
+
+// checkWIP lists the limits statusID would break once taskID is in it. Pass
+// a taskID of 0 for a task that does not exist yet.
+func checkWIP(q queryer, taskID, statusID int) ([]model.WIPViolation, error) {
+	s, err := scanStatus(q.QueryRow(`SELECT `+statusColumns+` FROM statuses WHERE id = ?`, statusID))
+	if err != nil || s.WIPLimit == nil {
+		return nil, err
+	}
+	if !s.WIPPerAssignee {
+		var n int
+		if err := q.QueryRow(
+			`SELECT COUNT(*) FROM tasks WHERE status_id = ? AND id <> ?`, statusID, taskID,
+		).Scan(&n); err != nil {
+			return nil, err
+		}
+		return model.CheckWIP(*s, n+1, nil), nil
+	}
+
+	rows, err := q.Query(`SELECT user_id FROM task_assignees WHERE task_id = ? ORDER BY user_id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	var assignees []int
+	for rows.Next() {
+		var uid int
+		if err := rows.Scan(&uid); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		assignees = append(assignees, uid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var counts []model.AssigneeCount
+	for _, uid := range assignees {
+		var n int
+		if err := q.QueryRow(`
+			SELECT COUNT(*) FROM tasks t
+			JOIN task_assignees ta ON ta.task_id = t.id
+			WHERE t.status_id = ? AND ta.user_id = ? AND t.id <> ?`, statusID, uid, taskID,
+		).Scan(&n); err != nil {
+			return nil, err
+		}
+		counts = append(counts, model.AssigneeCount{UserID: uid, Count: n + 1})
+	}
+	return model.CheckWIP(*s, 0, counts), nil
+}
+
+// enforceWIP turns violations of a hard limit into a WIPLimitError and passes
+// soft ones back as warnings.
+func enforceWIP(violations []model.WIPViolation) ([]model.WIPViolation, error) {
+	for _, v := range violations {
+		if v.Mode == model.WIPHard {
+			return nil, &WIPLimitError{Violations: violations}
+		}
+	}
+	return violations, nil
+}
+
+// assigneeViolations keeps the per-assignee violations that are about uid.
+func assigneeViolations(violations []model.WIPViolation, uid int) []model.WIPViolation {
+	var out []model.WIPViolation
+	for _, v := range violations {
+		if v.AssigneeID != nil && *v.AssigneeID == uid {
+			out = append(out, v)
+		}
+	}
+	return out
+}
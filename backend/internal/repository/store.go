@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"time"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/model"
+)
+
+// The handlers, middleware and authenticators depend on these interfaces
+// rather than on the SQL repositories, so they can run against the in-memory
+// stores in tests. Both implementations report missing rows with
+// sql.ErrNoRows and share the package-level errors.
+
+type ProjectStore interface {
+	GetAll(userID int) ([]model.Project, error)
+	GetByID(id int) (map[string]interface{}, error)
+	GetMemberRole(projectID, userID int) (model.Role, error)
+	GetMemberAccess(projectID, userID int) (*model.MemberAccess, error)
+	SetRequire2FA(projectID int, required bool, version int) error
+	UpdateDueDate(projectID int, payload UpdateDueDatePayload) error
+	Create(payload CreateProjectPayload) (*model.Project, error)
+	ListMembers(projectID int) ([]model.ProjectMember, error)
+	AddMember(projectID, userID int, role model.Role) error
+	UpdateMemberRole(projectID, userID int, role model.Role) error
+	RemoveMember(projectID, userID int) error
+	TransferOwnership(projectID, fromUserID, toUserID int) error
+	Delete(projectID, version int) error
+	SyncDirectoryRoles(userID int, roles map[int]*model.Role) error
+}
+
+type TaskStore interface {
+	UpdatePosition(taskID int, payload UpdateTaskPayload, userID int, role model.Role) ([]model.WIPViolation, error)
+	GetProjectID(taskID int) (int, error)
+	GetByID(taskID int) (*model.TaskDetail, error)
+	UpdateFields(taskID int, payload UpdateTaskFieldsPayload) error
+	AddAssigneeByQuery(taskID int, q string, version int) error
+	AddCollaboratorByQuery(taskID int, q string, version int) error
+	CreateAttachment(taskID int, fileName, storedName string, size int64) (int, error)
+	ListAttachments(taskID int) ([]model.Attachment, error)
+	ListComments(taskID int) ([]model.TaskComment, error)
+	AddComment(taskID int, userID *int, text string) error
+	CreateTask(projectID, statusID int, title string, role model.Role) (int, int, []model.WIPViolation, error)
+	RebalanceRanks(maxLen int) (int, error)
+}
+
+type StatusStore interface {
+	List(projectID int, includeArchived bool) ([]model.Status, error)
+	Get(projectID, statusID int) (*model.Status, error)
+	Create(projectID int, payload CreateStatusPayload) (*model.Status, error)
+	Update(projectID, statusID int, payload UpdateStatusPayload) (*model.Status, error)
+	Reorder(projectID int, ids []int) error
+}
+
+type TransitionStore interface {
+	List(projectID int) ([]model.Transition, error)
+	Create(t *model.Transition) error
+	Delete(projectID, id int) error
+}
+
+type UserStore interface {
+	GetUserByEmail(email string) (*model.User, error)
+	Create(name, email, passwordHash string) (*model.User, error)
+	CreateExternal(name, email string) (*model.User, error)
+	GetByIdentity(provider, subject string) (*model.User, error)
+	LinkIdentity(userID int, provider, subject, email string) error
+	SyncDirectoryProfile(id int, name, email string) (*model.User, error)
+	MarkEmailVerified(id int) error
+	IsAdmin(id int) (bool, error)
+	IsEmailVerified(id int) (bool, error)
+	GetByID(id int) (*model.User, error)
+	SearchUsers(query string) ([]model.User, error)
+	GetTasksByUserID(userID int) ([]model.UserTask, error)
+	UpdatePasswordHash(id int, hash string) error
+	UpdateProfile(id int, name, email string) (*model.User, error)
+	UpdateAvatar(id int, url string) error
+	GetSummary(userID int) (*Summary, error)
+	GetSharedSummary(userID, viewerID int) (*Summary, error)
+	GetMyProjects(userID int) ([]LiteProject, error)
+	GetSharedProjects(userID, viewerID int) ([]LiteProject, error)
+	GetProjectsByUserID(userID int) ([]LiteProject, error)
+	GetUserSettings(userID int) (*model.UserSettings, error)
+	UpdateUserSettings(settings *model.UserSettings) error
+}
+
+type SessionStore interface {
+	Create(s *model.Session, refreshHash string) error
+	Rotate(oldHash, newHash, ip string, expiresAt time.Time) (*model.Session, error)
+	Validate(id string, userID int, ip string) error
+	ListActive(userID int) ([]model.Session, error)
+	Revoke(id string, userID int) error
+	// RevokeAllForUser revokes every session of the user except keep, which
+	// may be empty.
+	RevokeAllForUser(userID int, keep string) error
+}
+
+type MFAStore interface {
+	Get(userID int) (*model.MFA, error)
+	IsEnabled(userID int) (bool, error)
+	SavePending(userID int, secret string) error
+	Enable(userID int, step int64, codeHashes []string) error
+	ConsumeStep(userID int, step int64) (bool, error)
+	UseRecoveryCode(userID int, codeHash string) error
+	RemainingRecoveryCodes(userID int) (int, error)
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	Disable(userID int) error
+}
+
+type PasswordResetStore interface {
+	Create(userID int, tokenHash string, expiresAt time.Time) error
+	Consume(tokenHash string) (int, error)
+}
+
+type AccessTokenStore interface {
+	Create(t *model.AccessToken, tokenHash string, limit int) error
+	List(userID int) ([]model.AccessToken, error)
+	Authenticate(tokenHash string) (*model.AccessToken, error)
+	Revoke(id, userID int) error
+}
+
+type AuthEventStore interface {
+	Record(e model.AuthEvent) error
+	List(event string, limit int) ([]model.AuthEvent, error)
+}
+
+// Stores bundles one implementation of every store.
+type Stores struct {
+	Projects       ProjectStore
+	Tasks          TaskStore
+	Statuses       StatusStore
+	Transitions    TransitionStore
+	Users          UserStore
+	Sessions       SessionStore
+	MFA            MFAStore
+	PasswordResets PasswordResetStore
+	AccessTokens   AccessTokenStore
+	AuthEvents     AuthEventStore
+}
+
+func NewSQLStores(db *database.DB) *Stores {
+	return &Stores{
+		Projects:       &ProjectRepository{DB: db},
+		Tasks:          &TaskRepository{DB: db},
+		Statuses:       &StatusRepository{DB: db},
+		Transitions:    &TransitionRepository{DB: db},
+		Users:          &UserRepository{DB: db},
+		Sessions:       &SessionRepository{DB: db},
+		MFA:            &MFARepository{DB: db},
+		PasswordResets: &PasswordResetRepository{DB: db},
+		AccessTokens:   &AccessTokenRepository{DB: db},
+		AuthEvents:     &AuthEventRepository{DB: db},
+	}
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"errors"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/rank"
+)
+
+// ErrInvalidPlacement means a move named a neighbour that is not in the
+// target column, or neighbours in the wrong order.
+var ErrInvalidPlacement = errors.New("neighbour tasks must be in the target column and in order")
+
+// maxRankLen is the longest rank a move may write. Longer ones make the
+// move rebalance the column first; the background job normally gets there
+// well before.
+const maxRankLen = 128
+
+type rankedTask struct {
+	id   int
+	rank string
+}
+
+// placement returns the ranks the moved task must sort between. column is
+// the target column in order, without the moved task.
+func placement(column []rankedTask, p UpdateTaskPayload) (lo, hi string, err error) {
+	index := func(id *int) int {
+		if id == nil {
+			return -1
+		}
+		for i, t := range column {
+			if t.id == *id {
+				return i
+			}
+		}
+		return -2
+	}
+	after, before := index(p.AfterTaskID), index(p.BeforeTaskID)
+	var at int
+	switch {
+	case after == -2 || before == -2:
+		return "", "", ErrInvalidPlacement
+	case after >= 0 && before >= 0 && after >= before:
+		return "", "", ErrInvalidPlacement
+	case after >= 0:
+		at = after + 1
+	case before >= 0:
+		at = before
+	default:
+		at = min(max(p.Position, 0), len(column))
+	}
+	if at > 0 {
+		lo = column[at-1].rank
+	}
+	if at < len(column) {
+		hi = column[at].rank
+	}
+	return lo, hi, nil
+}
+
+// newRank picks a rank between lo and hi, or reports false when the column
+// has to be rebalanced first.
+func newRank(lo, hi string) (string, bool) {
+	r, err := rank.Between(lo, hi)
+	if err != nil || len(r) > maxRankLen {
+		return "", false
+	}
+	return r, true
+}
+
+// loadColumn returns the tasks of a column in order, leaving out skipID.
+func loadColumn(q queryer, statusID, skipID int) ([]rankedTask, error) {
+	rows, err := q.Query(
+		`SELECT id, rank_key FROM tasks WHERE status_id = ? AND id <> ? ORDER BY rank_key, id`, statusID, skipID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []rankedTask
+	for rows.Next() {
+		var t rankedTask
+		if err := rows.Scan(&t.id, &t.rank); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// placeTask works out the moved task's rank in the target column, which the
+// caller has locked, rebalancing the column when there is no room left.
+func placeTask(tx *database.Tx, taskID int, p UpdateTaskPayload) (string, error) {
+	column, err := loadColumn(tx, p.StatusID, taskID)
+	if err != nil {
+		return "", err
+	}
+	lo, hi, err := placement(column, p)
+	if err != nil {
+		return "", err
+	}
+	if r, ok := newRank(lo, hi); ok {
+		return r, nil
+	}
+	if err := rebalanceColumn(tx, p.StatusID, taskID); err != nil {
+		return "", err
+	}
+	if column, err = loadColumn(tx, p.StatusID, taskID); err != nil {
+		return "", err
+	}
+	if lo, hi, err = placement(column, p); err != nil {
+		return "", err
+	}
+	r, err := rank.Between(lo, hi)
+	return r, err
+}
+
+// rebalanceColumn gives the column's tasks short, evenly spaced ranks,
+// leaving out skipID.
+func rebalanceColumn(tx *database.Tx, statusID, skipID int) error {
+	column, err := loadColumn(tx, statusID, skipID)
+	if err != nil {
+		return err
+	}
+	for i, r := range rank.Spread(len(column)) {
+		if _, err := tx.Exec(`UPDATE tasks SET rank_key = ? WHERE id = ?`, r, column[i].id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebalanceRanks rewrites the ranks of every column holding a rank longer
+// than maxLen and returns how many columns it touched.
+func (r *TaskRepository) RebalanceRanks(maxLen int) (int, error) {
+	rows, err := r.DB.Query(
+		`SELECT status_id FROM tasks GROUP BY status_id HAVING MAX(LENGTH(rank_key)) > ?`, maxLen,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var statuses []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		statuses = append(statuses, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, statusID := range statuses {
+		if err := r.rebalance(statusID); err != nil {
+			return i, err
+		}
+	}
+	return len(statuses), nil
+}
+
+func (r *TaskRepository) rebalance(statusID int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := lockStatus(tx, statusID); err != nil {
+		return err
+	}
+	if err := rebalanceColumn(tx, statusID, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockStatus serialises writes to a column's ordering. Moves, creates and
+// rebalances all take it before touching task rows.
+func lockStatus(tx *database.Tx, statusID int) error {
+	var id int
+	return tx.QueryRow(`SELECT id FROM statuses WHERE id = ? FOR UPDATE`, statusID).Scan(&id)
+}
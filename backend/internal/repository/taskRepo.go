@@ -3,21 +3,106 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"math"
+	"strings"
+	"time"
+	"planify/backend/internal/database"
 	"planify/backend/internal/model"
 )
 
 type TaskRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
+// UpdateTaskPayload places a task in a column. AfterTaskID and BeforeTaskID
+// name its new neighbours there; with neither, Position is the index to
+// insert at. A non-zero Version must match the task's current version.
 type UpdateTaskPayload struct {
-	StatusID int `json:"statusId"`
-	Position int `json:"position"`
+	StatusID     int    `json:"statusId"`
+	AfterTaskID  *int   `json:"afterTaskId"`
+	BeforeTaskID *int   `json:"beforeTaskId"`
+	Position     int    `json:"position"`
+	Comment      string `json:"comment"`
+	Version      int    `json:"-"`
 }
 
-func (r *TaskRepository) UpdatePosition(taskID int, payload UpdateTaskPayload) error {
-	_, err := r.DB.Exec("UPDATE tasks SET status_id = ?, position = ? WHERE id = ?", payload.StatusID, payload.Position, taskID)
-	return err
+// UpdatePosition moves a task on the board. Moves between columns must pass
+// the project's transition rules for the mover's role; a comment sent with
+// the move is saved and satisfies a required comment. Moves past a soft WIP
+// limit succeed and return the violations as warnings.
+func (r *TaskRepository) UpdatePosition(taskID int, payload UpdateTaskPayload, userID int, role model.Role) ([]model.WIPViolation, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockStatus(tx, payload.StatusID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidStatus
+		}
+		return nil, err
+	}
+	var projectID, fromStatus, version int
+	var desc, prio sql.NullString
+	if err := tx.QueryRow(
+		"SELECT project_id, status_id, description, priority, version FROM tasks WHERE id = ? FOR UPDATE", taskID,
+	).Scan(&projectID, &fromStatus, &desc, &prio, &version); err != nil {
+		return nil, err
+	}
+	if err := taskStatusValid(tx, projectID, payload.StatusID); err != nil {
+		return nil, err
+	}
+	if payload.Version != 0 && payload.Version != version {
+		return nil, ErrVersionConflict
+	}
+	comment := strings.TrimSpace(payload.Comment)
+	var warnings []model.WIPViolation
+	if fromStatus != payload.StatusID {
+		rules, err := loadTransitions(tx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		facts := model.TaskFacts{
+			HasDescription: strings.TrimSpace(desc.String) != "",
+			HasPriority:    strings.TrimSpace(prio.String) != "",
+			HasComment:     comment != "",
+		}
+		if err := tx.QueryRow("SELECT COUNT(*) FROM task_assignees WHERE task_id = ?", taskID).Scan(&facts.Assignees); err != nil {
+			return nil, err
+		}
+		if unmet := model.CheckTransition(rules, fromStatus, payload.StatusID, role, facts); len(unmet) > 0 {
+			return nil, &TransitionError{Unmet: unmet}
+		}
+		violations, err := checkWIP(tx, taskID, payload.StatusID)
+		if err != nil {
+			return nil, err
+		}
+		if warnings, err = enforceWIP(violations); err != nil {
+			return nil, err
+		}
+	}
+	rank, err := placeTask(tx, taskID, payload)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE tasks SET status_id = ?, rank_key = ?, version = version + 1 WHERE id = ?", payload.StatusID, rank, taskID); err != nil {
+		return nil, err
+	}
+	if comment != "" {
+		if _, err := tx.Exec("INSERT INTO task_comments (task_id, user_id, text) VALUES (?, ?, ?)", taskID, userID, comment); err != nil {
+			return nil, err
+		}
+	}
+	return warnings, tx.Commit()
+}
+
+func (r *TaskRepository) GetProjectID(taskID int) (int, error) {
+	var projectID int
+	if err := r.DB.QueryRow("SELECT project_id FROM tasks WHERE id = ?", taskID).Scan(&projectID); err != nil {
+		return 0, err
+	}
+	return projectID, nil
 }
 
 func (r *TaskRepository) GetByID(taskID int) (*model.TaskDetail, error) {
@@ -25,9 +110,9 @@ func (r *TaskRepository) GetByID(taskID int) (*model.TaskDetail, error) {
 		SELECT
 			t.id, t.title, t.description,
 			p.id, p.name,
-			s.id, s.title,
+			s.id, s.title, s.category,
 			p.due_date,
-			t.priority
+			t.priority, t.version
 		FROM tasks t
 		JOIN projects p ON p.id = t.project_id
 		JOIN statuses s ON s.id = t.status_id
@@ -42,9 +127,9 @@ func (r *TaskRepository) GetByID(taskID int) (*model.TaskDetail, error) {
 	if err := row.Scan(
 		&td.ID, &td.Title, &desc,
 		&td.ProjectID, &td.ProjectName,
-		&td.StatusID, &td.StatusName,
+		&td.StatusID, &td.StatusName, &td.StatusCategory,
 		&due,
-		&prio,
+		&prio, &td.Version,
 	); err != nil {
 		return nil, err
 	}
@@ -112,69 +197,137 @@ func (r *TaskRepository) GetByID(taskID int) (*model.TaskDetail, error) {
 	return &td, nil
 }
 
-func (r *TaskRepository) UpdateFields(taskID int, title *string, description *string, dueDate *string, priority *string) error {
-	if title != nil {
-		if _, err := r.DB.Exec("UPDATE tasks SET title = ? WHERE id = ?", *title, taskID); err != nil {
-			return err
-		}
+// UpdateTaskFieldsPayload holds the fields a PATCH changes; nil ones are
+// left alone. The due date is the project's. A non-zero Version must match
+// the task's current version.
+type UpdateTaskFieldsPayload struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	DueDate     *string `json:"dueDate"`
+	Priority    *string `json:"priority"`
+	Version     int     `json:"-"`
+}
+
+func (p UpdateTaskFieldsPayload) empty() bool {
+	return p.Title == nil && p.Description == nil && p.DueDate == nil && p.Priority == nil
+}
+
+// UpdateFields sets the fields present in payload. An empty payload changes
+// nothing, so it leaves the version alone.
+func (r *TaskRepository) UpdateFields(taskID int, payload UpdateTaskFieldsPayload) error {
+	if payload.empty() {
+		var id int
+		return r.DB.QueryRow("SELECT id FROM tasks WHERE id = ?", taskID).Scan(&id)
 	}
-	if description != nil {
-		if _, err := r.DB.Exec("UPDATE tasks SET description = ? WHERE id = ?", *description, taskID); err != nil {
-			return err
-		}
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := bumpVersion(tx, "tasks", taskID, payload.Version); err != nil {
+		return err
+	}
+	var sets []string
+	var args []any
+	if payload.Title != nil {
+		sets = append(sets, "title = ?")
+		args = append(args, *payload.Title)
 	}
-	if dueDate != nil {
-		if _, err := r.DB.Exec("UPDATE projects p JOIN tasks t ON p.id = t.project_id SET p.due_date = ? WHERE t.id = ?", *dueDate, taskID); err != nil {
+	if payload.Description != nil {
+		sets = append(sets, "description = ?")
+		args = append(args, *payload.Description)
+	}
+	if payload.Priority != nil {
+		sets = append(sets, "priority = ?")
+		args = append(args, *payload.Priority)
+	}
+	if len(sets) > 0 {
+		if _, err := tx.Exec("UPDATE tasks SET "+strings.Join(sets, ", ")+" WHERE id = ?", append(args, taskID)...); err != nil {
 			return err
 		}
 	}
-	if priority != nil {
-		if _, err := r.DB.Exec("UPDATE tasks SET priority = ? WHERE id = ?", *priority, taskID); err != nil {
+	if payload.DueDate != nil {
+		if _, err := tx.Exec(
+			"UPDATE projects SET due_date = ?, version = version + 1 WHERE id = (SELECT project_id FROM tasks WHERE id = ?)",
+			*payload.DueDate, taskID,
+		); err != nil {
 			return err
 		}
 	}
-	return nil
+	return tx.Commit()
 }
 
 func (r *TaskRepository) findUserIDByQuery(q string) (int, error) {
 	var id int
-	err := r.DB.QueryRow("SELECT id FROM users WHERE email = ? OR name = ? LIMIT 1", q, q).Scan(&id)
+	err := r.DB.QueryRow("SELECT id FROM users WHERE email = ? OR name = ? LIMIT 1", strings.ToLower(q), q).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (r *TaskRepository) AddAssigneeByQuery(taskID int, q string) error {
+func (r *TaskRepository) AddAssigneeByQuery(taskID int, q string, version int) error {
+	return r.addTaskUser("task_assignees", taskID, q, version)
+}
+
+func (r *TaskRepository) AddCollaboratorByQuery(taskID int, q string, version int) error {
+	return r.addTaskUser("task_collaborators", taskID, q, version)
+}
+
+// addTaskUser links a user to the task, bumping its version only when the
+// link is new. A new assignee must fit the column's hard per-assignee WIP
+// limit; soft limits do not stop the assignment.
+func (r *TaskRepository) addTaskUser(table string, taskID int, q string, version int) error {
 	uid, err := r.findUserIDByQuery(q)
 	if err != nil {
 		return err
 	}
-	_, _ = r.DB.Exec("INSERT IGNORE INTO task_assignees (task_id, user_id) VALUES (?, ?)", taskID, uid)
-	return nil
-}
-
-func (r *TaskRepository) AddCollaboratorByQuery(taskID int, q string) error {
-	uid, err := r.findUserIDByQuery(q)
+	tx, err := r.DB.Begin()
 	if err != nil {
 		return err
 	}
-	_, _ = r.DB.Exec("INSERT IGNORE INTO task_collaborators (task_id, user_id) VALUES (?, ?)", taskID, uid)
-	return nil
+	defer tx.Rollback()
+	assignee := table == "task_assignees"
+	var statusID int
+	if assignee {
+		if err := tx.QueryRow("SELECT status_id FROM tasks WHERE id = ?", taskID).Scan(&statusID); err != nil {
+			return err
+		}
+		if err := lockStatus(tx, statusID); err != nil {
+			return err
+		}
+	}
+	if err := bumpVersion(tx, "tasks", taskID, version); err != nil {
+		return err
+	}
+	res, err := tx.Exec(r.DB.Dialect.InsertIgnore("INSERT INTO "+table+" (task_id, user_id) VALUES (?, ?)"), taskID, uid)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if assignee {
+		violations, err := checkWIP(tx, taskID, statusID)
+		if err != nil {
+			return err
+		}
+		if _, err := enforceWIP(assigneeViolations(violations, uid)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *TaskRepository) CreateAttachment(taskID int, fileName, storedName string, size int64) (int, error) {
-	res, err := r.DB.Exec(`
+	id64, err := r.DB.InsertID(`
 		INSERT INTO attachments (task_id, file_name, stored_name, size)
 		VALUES (?, ?, ?, ?)
 	`, taskID, fileName, storedName, size)
 	if err != nil {
 		return 0, err
 	}
-	id64, err := res.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
 	return int(id64), nil
 }
 
@@ -211,7 +364,7 @@ func (r *TaskRepository) ListAttachments(taskID int) ([]model.Attachment, error)
 func (r *TaskRepository) ListComments(taskID int) ([]model.TaskComment, error) {
 	rows, err := r.DB.Query(`
 		SELECT
-			c.id, c.text, DATE_FORMAT(c.created_at, '%Y-%m-%dT%H:%i:%sZ'),
+			c.id, c.text, c.created_at,
 			u.id, u.name, u.email, COALESCE(u.avatar,'')
 		FROM task_comments c
 		LEFT JOIN users u ON u.id = c.user_id
@@ -227,7 +380,7 @@ func (r *TaskRepository) ListComments(taskID int) ([]model.TaskComment, error) {
 	for rows.Next() {
 		var id int
 		var text string
-		var created string
+		var created time.Time
 		var uid sql.NullInt64
 		var name sql.NullString
 		var email sql.NullString
@@ -261,7 +414,7 @@ func (r *TaskRepository) ListComments(taskID int) ([]model.TaskComment, error) {
 		out = append(out, model.TaskComment{
 			ID:        id,
 			Text:      text,
-			CreatedAt: created,
+			CreatedAt: created.UTC().Format("2006-01-02T15:04:05Z"),
 					Author:    author,
 		})
 	}
@@ -278,19 +431,64 @@ func (r *TaskRepository) AddComment(taskID int, userID *int, text string) error
 	return err
 }
 
-func (r *TaskRepository) CreateTask(projectID, statusID int, title string) (int, int, error) {
-	var pos sql.NullInt64
-	if err := r.DB.QueryRow("SELECT COALESCE(MAX(position), -1) FROM tasks WHERE status_id = ?", statusID).Scan(&pos); err != nil {
-		return 0, 0, err
+// CreateTask adds a task at the bottom of a column and returns its ID and
+// index in the column, with any soft WIP limits it breaks. A task created
+// outside the project's first column must pass the transition rules as if it
+// were moved there from the first.
+func (r *TaskRepository) CreateTask(projectID, statusID int, title string, role model.Role) (int, int, []model.WIPViolation, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockStatus(tx, statusID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, nil, ErrInvalidStatus
+		}
+		return 0, 0, nil, err
 	}
-	next := int(pos.Int64) + 1
-	res, err := r.DB.Exec("INSERT INTO tasks (project_id, status_id, title, position) VALUES (?, ?, ?, ?)", projectID, statusID, title, next)
+	if err := taskStatusValid(tx, projectID, statusID); err != nil {
+		return 0, 0, nil, err
+	}
+	rules, err := loadTransitions(tx, projectID)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if len(rules) > 0 {
+		var initial int
+		if err := tx.QueryRow(
+			`SELECT id FROM statuses WHERE project_id = ? AND archived_at IS NULL ORDER BY position, id LIMIT 1`, projectID,
+		).Scan(&initial); err != nil {
+			return 0, 0, nil, err
+		}
+		if unmet := model.CheckTransition(rules, initial, statusID, role, model.TaskFacts{}); len(unmet) > 0 {
+			return 0, 0, nil, &TransitionError{Unmet: unmet}
+		}
+	}
+	violations, err := checkWIP(tx, 0, statusID)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, nil, err
 	}
-	id64, err := res.LastInsertId()
+	warnings, err := enforceWIP(violations)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, nil, err
+	}
+	payload := UpdateTaskPayload{StatusID: statusID, Position: math.MaxInt}
+	rank, err := placeTask(tx, 0, payload)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	var pos int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM tasks WHERE status_id = ?", statusID).Scan(&pos); err != nil {
+		return 0, 0, nil, err
+	}
+	id64, err := tx.InsertID("INSERT INTO tasks (project_id, status_id, title, rank_key) VALUES (?, ?, ?, ?)", projectID, statusID, title, rank)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, nil, err
 	}
-	return int(id64), next, nil
+	return int(id64), pos, warnings, nil
 }
\ No newline at end of file
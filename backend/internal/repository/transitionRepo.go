@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"planify/backend/internal/database"
+	"planify/backend/internal/model"
+)
+
+var ErrTransitionExists = errors.New("a transition between these statuses already exists")
+
+// TransitionError is returned when a move breaks the project's workflow
+// rules. Unmet lists every condition that failed.
+type TransitionError struct {
+	Unmet []model.UnmetCondition
+}
+
+func (e *TransitionError) Error() string {
+	return "task move blocked by workflow rules"
+}
+
+type TransitionRepository struct {
+	DB *database.DB
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func (r *TransitionRepository) List(projectID int) ([]model.Transition, error) {
+	return loadTransitions(r.DB, projectID)
+}
+
+func loadTransitions(q queryer, projectID int) ([]model.Transition, error) {
+	rows, err := q.Query(`
+		SELECT id, project_id, from_status_id, to_status_id, roles, required_fields
+		FROM status_transitions
+		WHERE project_id = ?
+		ORDER BY id`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.Transition{}
+	for rows.Next() {
+		var t model.Transition
+		var from sql.NullInt64
+		var roles, fields string
+		if err := rows.Scan(&t.ID, &t.ProjectID, &from, &t.ToStatusID, &roles, &fields); err != nil {
+			return nil, err
+		}
+		if from.Valid {
+			id := int(from.Int64)
+			t.FromStatusID = &id
+		}
+		t.Roles = []model.Role{}
+		for _, f := range strings.Fields(roles) {
+			t.Roles = append(t.Roles, model.Role(f))
+		}
+		t.RequiredFields = []model.TaskField{}
+		for _, f := range strings.Fields(fields) {
+			t.RequiredFields = append(t.RequiredFields, model.TaskField(f))
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// Create adds a rule. Both statuses must belong to the project; archived ones
+// are allowed so rules survive a column being archived and restored.
+func (r *TransitionRepository) Create(t *model.Transition) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := []int{t.ToStatusID}
+	if t.FromStatusID != nil {
+		ids = append(ids, *t.FromStatusID)
+	}
+	for _, id := range ids {
+		var n int
+		if err := tx.QueryRow(
+			`SELECT COUNT(*) FROM statuses WHERE id = ? AND project_id = ?`, id, t.ProjectID,
+		).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrInvalidStatus
+		}
+	}
+	var dup int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM status_transitions
+		WHERE project_id = ? AND to_status_id = ? AND COALESCE(from_status_id, 0) = ?`,
+		t.ProjectID, t.ToStatusID, fromKey(t.FromStatusID),
+	).Scan(&dup); err != nil {
+		return err
+	}
+	if dup > 0 {
+		return ErrTransitionExists
+	}
+
+	roles := make([]string, len(t.Roles))
+	for i, role := range t.Roles {
+		roles[i] = string(role)
+	}
+	fields := make([]string, len(t.RequiredFields))
+	for i, f := range t.RequiredFields {
+		fields[i] = string(f)
+	}
+	id, err := tx.InsertID(`
+		INSERT INTO status_transitions (project_id, from_status_id, to_status_id, roles, required_fields)
+		VALUES (?, ?, ?, ?, ?)`,
+		t.ProjectID, t.FromStatusID, t.ToStatusID, strings.Join(roles, " "), strings.Join(fields, " "),
+	)
+	if err != nil {
+		return err
+	}
+	t.ID = int(id)
+	return tx.Commit()
+}
+
+func fromKey(from *int) int {
+	if from == nil {
+		return 0
+	}
+	return *from
+}
+
+func (r *TransitionRepository) Delete(projectID, id int) error {
+	res, err := r.DB.Exec(`DELETE FROM status_transitions WHERE id = ? AND project_id = ?`, id, projectID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
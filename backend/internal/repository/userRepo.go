@@ -2,13 +2,16 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
+	"planify/backend/internal/database"
 	"planify/backend/internal/model"
 )
 
 type UserRepository struct {
-	DB *sql.DB
+	DB *database.DB
 }
 
 type Summary struct {
@@ -43,12 +46,12 @@ func defaultAvatar(s string) string {
 func (r *UserRepository) GetUserByEmail(email string) (*model.User, error) {
 	var u model.User
 	q := `
-		SELECT id, name, email, COALESCE(avatar, ''), password, created_at
+		SELECT id, name, email, COALESCE(avatar, ''), password, email_verified_at IS NOT NULL, created_at
 		FROM users
 		WHERE email = ?
 	`
-	err := r.DB.QueryRow(q, email).Scan(
-		&u.ID, &u.Name, &u.Email, &u.Avatar, &u.Password, &u.CreatedAt,
+	err := r.DB.QueryRow(q, strings.ToLower(email)).Scan(
+		&u.ID, &u.Name, &u.Email, &u.Avatar, &u.Password, &u.EmailVerified, &u.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -57,15 +60,106 @@ func (r *UserRepository) GetUserByEmail(email string) (*model.User, error) {
 	return &u, nil
 }
 
+var ErrEmailTaken = errors.New("email already registered")
+
+// Emails are stored lowercased: MySQL compares them case-insensitively but
+// Postgres does not.
+func (r *UserRepository) Create(name, email, passwordHash string) (*model.User, error) {
+	id, err := r.DB.InsertID(
+		"INSERT INTO users (name, email, password) VALUES (?, ?, ?)",
+		name, strings.ToLower(email), passwordHash,
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return r.GetByID(int(id))
+}
+
+// CreateExternal provisions an account for a user who signs in through an
+// external identity provider. The empty password can never match, so the
+// account stays SSO-only until the user sets a password through a reset.
+func (r *UserRepository) CreateExternal(name, email string) (*model.User, error) {
+	id, err := r.DB.InsertID(
+		"INSERT INTO users (name, email, password, email_verified_at) VALUES (?, ?, '', ?)",
+		name, strings.ToLower(email), time.Now().UTC(),
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return r.GetByID(int(id))
+}
+
+func (r *UserRepository) GetByIdentity(provider, subject string) (*model.User, error) {
+	var id int
+	err := r.DB.QueryRow(
+		"SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?",
+		provider, subject,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+func (r *UserRepository) LinkIdentity(userID int, provider, subject, email string) error {
+	_, err := r.DB.Exec(
+		"INSERT INTO user_identities (user_id, provider, subject, email) VALUES (?, ?, ?, ?)",
+		userID, provider, subject, email,
+	)
+	return err
+}
+
+// SyncDirectoryProfile overwrites name and email with directory values. The
+// directory vouches for the address, so it is marked verified.
+func (r *UserRepository) SyncDirectoryProfile(id int, name, email string) (*model.User, error) {
+	_, err := r.DB.Exec(
+		"UPDATE users SET name = ?, email = ?, email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?",
+		name, strings.ToLower(email), time.Now().UTC(), id,
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+func (r *UserRepository) MarkEmailVerified(id int) error {
+	_, err := r.DB.Exec(
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?",
+		time.Now().UTC(), id,
+	)
+	return err
+}
+
+func (r *UserRepository) IsAdmin(id int) (bool, error) {
+	var admin bool
+	err := r.DB.QueryRow(`SELECT is_admin FROM users WHERE id = ?`, id).Scan(&admin)
+	return admin, err
+}
+
+func (r *UserRepository) IsEmailVerified(id int) (bool, error) {
+	var verified bool
+	err := r.DB.QueryRow("SELECT email_verified_at IS NOT NULL FROM users WHERE id = ?", id).Scan(&verified)
+	return verified, err
+}
+
 func (r *UserRepository) GetByID(id int) (*model.User, error) {
 	var u model.User
 	q := `
-		SELECT id, name, email, COALESCE(avatar, ''), password, created_at
+		SELECT id, name, email, COALESCE(avatar, ''), password, email_verified_at IS NOT NULL, created_at
 		FROM users
 		WHERE id = ?
 	`
 	err := r.DB.QueryRow(q, id).Scan(
-		&u.ID, &u.Name, &u.Email, &u.Avatar, &u.Password, &u.CreatedAt,
+		&u.ID, &u.Name, &u.Email, &u.Avatar, &u.Password, &u.EmailVerified, &u.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -79,9 +173,9 @@ func (r *UserRepository) SearchUsers(query string) ([]model.User, error) {
 	sqlq := `
 		SELECT id, name, email, COALESCE(avatar, '')
 		FROM users
-		WHERE name LIKE ? OR email LIKE ?
+		WHERE LOWER(name) LIKE ? OR LOWER(email) LIKE ?
 	`
-	like := "%" + query + "%"
+	like := "%" + strings.ToLower(query) + "%"
 	rows, err := r.DB.Query(sqlq, like, like)
 	if err != nil {
 		return nil, err
@@ -135,8 +229,17 @@ func (r *UserRepository) UpdatePasswordHash(id int, hash string) error {
 }
 
 func (r *UserRepository) UpdateProfile(id int, name, email string) (*model.User, error) {
-	_, err := r.DB.Exec("UPDATE users SET name = ?, email = ? WHERE id = ?", name, email, id)
+	email = strings.ToLower(email)
+	_, err := r.DB.Exec(`
+		UPDATE users SET
+			name = ?,
+			email_verified_at = CASE WHEN email = ? THEN email_verified_at ELSE NULL END,
+			email = ?
+		WHERE id = ?`, name, email, email, id)
 	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
 		return nil, err
 	}
 	return r.GetByID(id)
@@ -148,12 +251,46 @@ func (r *UserRepository) UpdateAvatar(id int, url string) error {
 }
 
 func (r *UserRepository) GetSummary(userID int) (*Summary, error) {
+	return r.summary(userID, 0)
+}
+
+// GetSharedSummary is GetSummary limited to projects viewerID is a member
+// of, for showing one user's profile to another.
+func (r *UserRepository) GetSharedSummary(userID, viewerID int) (*Summary, error) {
+	return r.summary(userID, viewerID)
+}
+
+// sharedWith limits a query on tasks t to projects viewerID belongs to. A
+// viewerID of 0 means no limit.
+func sharedWith(viewerID int, column string) (string, []any) {
+	if viewerID == 0 {
+		return "", nil
+	}
+	return " AND " + column + " IN (SELECT project_id FROM project_members WHERE user_id = ?)", []any{viewerID}
+}
+
+func (r *UserRepository) summary(userID, viewerID int) (*Summary, error) {
 	var s Summary
+	scope, scopeArgs := sharedWith(viewerID, "t.project_id")
+	args := append([]any{userID}, scopeArgs...)
 
-	r.DB.QueryRow("SELECT COALESCE(COUNT(DISTINCT task_id),0) FROM task_assignees WHERE user_id = ?", userID).Scan(&s.AssignedCount)
-	r.DB.QueryRow("SELECT COALESCE(COUNT(DISTINCT task_id),0) FROM task_collaborators WHERE user_id = ?", userID).Scan(&s.CollaboratorCount)
-	r.DB.QueryRow("SELECT COALESCE(COUNT(*),0) FROM task_comments WHERE user_id = ?", userID).Scan(&s.CommentCount)
+	r.DB.QueryRow(`
+		SELECT COALESCE(COUNT(DISTINCT ta.task_id),0)
+		FROM task_assignees ta
+		JOIN tasks t ON t.id = ta.task_id
+		WHERE ta.user_id = ?`+scope, args...).Scan(&s.AssignedCount)
+	r.DB.QueryRow(`
+		SELECT COALESCE(COUNT(DISTINCT tc.task_id),0)
+		FROM task_collaborators tc
+		JOIN tasks t ON t.id = tc.task_id
+		WHERE tc.user_id = ?`+scope, args...).Scan(&s.CollaboratorCount)
+	r.DB.QueryRow(`
+		SELECT COALESCE(COUNT(*),0)
+		FROM task_comments c
+		JOIN tasks t ON t.id = c.task_id
+		WHERE c.user_id = ?`+scope, args...).Scan(&s.CommentCount)
 
+	projectScope, _ := sharedWith(viewerID, "p.id")
 	r.DB.QueryRow(`
 		SELECT COALESCE(COUNT(DISTINCT p.id),0)
 		FROM projects p
@@ -161,19 +298,19 @@ func (r *UserRepository) GetSummary(userID int) (*Summary, error) {
 		LEFT JOIN tasks t            ON t.project_id = p.id
 		LEFT JOIN task_assignees ta  ON ta.task_id = t.id AND ta.user_id = ?
 		LEFT JOIN task_collaborators tc ON tc.task_id = t.id AND tc.user_id = ?
-		WHERE pm.user_id IS NOT NULL
+		WHERE (pm.user_id IS NOT NULL
 		   OR ta.user_id IS NOT NULL
-		   OR tc.user_id IS NOT NULL
-	`, userID, userID, userID).Scan(&s.ProjectCount)
+		   OR tc.user_id IS NOT NULL)`+projectScope,
+		append([]any{userID, userID, userID}, scopeArgs...)...).Scan(&s.ProjectCount)
 
 	rows, err := r.DB.Query(`
 		SELECT c.id, c.text, c.created_at, t.title
 		FROM task_comments c
 		JOIN tasks t ON t.id = c.task_id
-		WHERE c.user_id = ?
+		WHERE c.user_id = ?`+scope+`
 		ORDER BY c.id DESC
 		LIMIT 10
-	`, userID)
+	`, args...)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -195,6 +332,17 @@ func (r *UserRepository) GetSummary(userID int) (*Summary, error) {
 }
 
 func (r *UserRepository) GetMyProjects(userID int) ([]LiteProject, error) {
+	return r.projects(userID, 0)
+}
+
+// GetSharedProjects is GetMyProjects limited to projects viewerID is a
+// member of.
+func (r *UserRepository) GetSharedProjects(userID, viewerID int) ([]LiteProject, error) {
+	return r.projects(userID, viewerID)
+}
+
+func (r *UserRepository) projects(userID, viewerID int) ([]LiteProject, error) {
+	scope, scopeArgs := sharedWith(viewerID, "p.id")
 	rows, err := r.DB.Query(`
 		SELECT DISTINCT p.id, p.name, p.description, p.due_date
 		FROM projects p
@@ -202,12 +350,12 @@ func (r *UserRepository) GetMyProjects(userID int) ([]LiteProject, error) {
 		LEFT JOIN tasks t          ON t.project_id = p.id
 		LEFT JOIN task_assignees ta ON ta.task_id = t.id AND ta.user_id = ?
 		LEFT JOIN task_collaborators tc ON tc.task_id = t.id AND tc.user_id = ?
-		WHERE pm.user_id IS NOT NULL
+		WHERE (pm.user_id IS NOT NULL
 		   OR ta.user_id IS NOT NULL
-		   OR tc.user_id IS NOT NULL
+		   OR tc.user_id IS NOT NULL)`+scope+`
 		ORDER BY p.id DESC
 		LIMIT 50
-	`, userID, userID, userID)
+	`, append([]any{userID, userID, userID}, scopeArgs...)...)
 	if err != nil {
 		return nil, err
 	}
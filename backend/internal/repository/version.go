@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+
+	"planify/backend/internal/database"
+)
+
+// ErrVersionConflict means the row changed after the client read the version
+// it sent back.
+var ErrVersionConflict = errors.New("the record was changed since it was read")
+
+// bumpVersion increments a task or project version inside tx. A non-zero
+// want must match the stored version first; 0 skips the check.
+func bumpVersion(tx *database.Tx, table string, id, want int) error {
+	query := `UPDATE ` + table + ` SET version = version + 1 WHERE id = ?`
+	args := []any{id}
+	if want != 0 {
+		query += ` AND version = ?`
+		args = append(args, want)
+	}
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var version int
+	if err := tx.QueryRow(`SELECT version FROM `+table+` WHERE id = ?`, id).Scan(&version); err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+
+	"planify/backend/internal/config"
+)
+
+// Server is an http.Server that can pick up a renewed TLS certificate
+// without restarting.
+type Server struct {
+	*http.Server
+	cfg config.ServerConfig
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func New(cfg config.ServerConfig, h http.Handler) (*Server, error) {
+	s := &Server{
+		Server: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           h,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		cfg: cfg,
+	}
+	if s.TLS() {
+		if err := s.ReloadCertificate(); err != nil {
+			return nil, err
+		}
+		s.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.getCertificate,
+		}
+	}
+	return s, nil
+}
+
+func (s *Server) TLS() bool { return s.cfg.TLSCertFile != "" }
+
+// ReloadCertificate reads the certificate and key files again. On error the
+// previous certificate stays in use.
+func (s *Server) ReloadCertificate() error {
+	if !s.TLS() {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.cert = &cert
+	s.mu.Unlock()
+	log.Println("[server] loaded TLS certificate from", s.cfg.TLSCertFile)
+	return nil
+}
+
+func (s *Server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, nil
+}
+
+// Serve listens until Shutdown is called, which is not reported as an error.
+func (s *Server) Serve() error {
+	var err error
+	if s.TLS() {
+		err = s.ListenAndServeTLS("", "")
+	} else {
+		err = s.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"planify/backend/internal/config"
+)
+
+var ErrUnknownProvider = errors.New("sso: unknown provider")
+
+// Identity is the subset of ID token claims Planify uses to find or create a
+// local account.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider wraps one OIDC issuer. Discovery happens on first use rather than
+// at startup so an unreachable IdP does not keep the API from booting.
+type Provider struct {
+	cfg config.OIDCProvider
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+type Registry struct {
+	providers map[string]*Provider
+	order     []string
+}
+
+func NewRegistry(cfgs []config.OIDCProvider) (*Registry, error) {
+	r := &Registry{providers: map[string]*Provider{}}
+	for _, pc := range cfgs {
+		if pc.ID == "" || pc.Issuer == "" || pc.ClientID == "" || pc.RedirectURL == "" {
+			return nil, fmt.Errorf("sso: provider %q needs id, issuer, client id and redirect url", pc.ID)
+		}
+		if _, dup := r.providers[pc.ID]; dup {
+			return nil, fmt.Errorf("sso: duplicate provider %q", pc.ID)
+		}
+		r.providers[pc.ID] = &Provider{cfg: pc}
+		r.order = append(r.order, pc.ID)
+	}
+	return r, nil
+}
+
+func (r *Registry) Get(id string) (*Provider, error) {
+	p, ok := r.providers[id]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// List returns id/name pairs for rendering login buttons.
+func (r *Registry) List() []map[string]string {
+	out := make([]map[string]string, 0, len(r.order))
+	for _, id := range r.order {
+		name := r.providers[id].cfg.Name
+		if name == "" {
+			name = id
+		}
+		out = append(out, map[string]string{"id": id, "name": name})
+	}
+	return out
+}
+
+func (p *Provider) ID() string { return p.cfg.ID }
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+	op, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sso: discover %s: %w", p.cfg.ID, err)
+	}
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     op.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+	}
+	p.verifier = op.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// AuthCodeURL builds the authorization request with a PKCE S256 challenge.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, pkceVerifier string) (string, error) {
+	oc, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oc.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(pkceVerifier)), nil
+}
+
+// Exchange redeems the authorization code and validates the returned ID
+// token's signature, issuer, audience, expiry and nonce.
+func (p *Provider) Exchange(ctx context.Context, code, pkceVerifier, nonce string) (*Identity, error) {
+	oc, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := oc.Exchange(ctx, code, oauth2.VerifierOption(pkceVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("sso: exchange code: %w", err)
+	}
+	raw, ok := tok.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return nil, errors.New("sso: token response has no id_token")
+	}
+	idt, err := verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("sso: verify id_token: %w", err)
+	}
+	if idt.Nonce != nonce {
+		return nil, errors.New("sso: nonce mismatch")
+	}
+	var claims struct {
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+		Name          string      `json:"name"`
+		PreferredName string      `json:"preferred_username"`
+	}
+	if err := idt.Claims(&claims); err != nil {
+		return nil, err
+	}
+	id := &Identity{
+		Provider: p.cfg.ID,
+		Subject:  idt.Subject,
+		Email:    claims.Email,
+		Name:     claims.Name,
+	}
+	// Some IdPs send email_verified as the string "true".
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		id.EmailVerified = v == "true"
+	}
+	if id.Name == "" {
+		id.Name = claims.PreferredName
+	}
+	return id, nil
+}
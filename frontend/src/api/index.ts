@@ -109,7 +109,41 @@ export interface RegisterCredentials {
 
 const API_BASE_URL = (import.meta as any).env?.VITE_API_BASE_URL ?? "http://localhost:8080/api"
 
-async function api<T>(path: string, init: RequestInit = {}): Promise<T> {
+function saveSession(data: { token?: string; refresh_token?: string }) {
+  if (data?.token) localStorage.setItem("planify_token", data.token)
+  if (data?.refresh_token) localStorage.setItem("planify_refresh_token", data.refresh_token)
+}
+
+export function clearSession() {
+  localStorage.removeItem("planify_token")
+  localStorage.removeItem("planify_refresh_token")
+}
+
+// Access tokens are short-lived. The first request to hit a 401 swaps the
+// refresh token for a new pair; requests failing meanwhile wait for it.
+let refreshing: Promise<boolean> | null = null
+
+function refreshSession(): Promise<boolean> {
+  const refreshToken = localStorage.getItem("planify_refresh_token")
+  if (!refreshToken) return Promise.resolve(false)
+  refreshing ??= fetch(`${API_BASE_URL}/token/refresh`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ refresh_token: refreshToken })
+  })
+    .then(async (res) => {
+      if (!res.ok) return false
+      saveSession(await res.json())
+      return true
+    })
+    .catch(() => false)
+    .finally(() => {
+      refreshing = null
+    })
+  return refreshing
+}
+
+async function api<T>(path: string, init: RequestInit = {}, retry = true): Promise<T> {
   const url = `${API_BASE_URL}${path}`
   const headers = new Headers(init.headers || {})
   const token = localStorage.getItem("planify_token")
@@ -117,7 +151,8 @@ async function api<T>(path: string, init: RequestInit = {}): Promise<T> {
   if (token && !headers.has("Authorization")) headers.set("Authorization", `Bearer ${token}`)
   const res = await fetch(url, { ...init, headers })
   if (res.status === 401) {
-    localStorage.removeItem("planify_token")
+    if (retry && (await refreshSession())) return api<T>(path, init, false)
+    clearSession()
     if (!location.hash.startsWith("#/login")) location.hash = "#/login"
     throw new Error("Unauthorized")
   }
@@ -151,7 +186,7 @@ export async function loginUser(credentials: LoginCredentials): Promise<{ token:
     throw new Error(msg || "Login failed")
   }
   const data = await res.json()
-  if (data?.token) localStorage.setItem("planify_token", data.token)
+  saveSession(data)
   return data
 }
 
@@ -289,14 +324,7 @@ export async function addCollaborator(taskId: string, query: string) {
 export async function uploadAttachment(taskId: string, file: File) {
   const fd = new FormData()
   fd.append("file", file)
-  const token = localStorage.getItem("planify_token")
-  const res = await fetch(`${API_BASE_URL}/tasks/${taskId}/attachments`, {
-    method: "POST",
-    headers: token ? { Authorization: `Bearer ${token}` } : undefined,
-    body: fd
-  })
-  if (!res.ok) throw new Error("Upload failed")
-  return res.json()
+  return api<any>(`/tasks/${taskId}/attachments`, { method: "POST", body: fd })
 }
 
 export async function fetchMyTasks() {
@@ -305,12 +333,12 @@ export async function fetchMyTasks() {
 
 export async function searchUsers(query: string, signal?: AbortSignal) {
   if (!query) return [] as User[]
-  const url = `${API_BASE_URL}/users/search?q=${encodeURIComponent(query)}`
-  const token = localStorage.getItem("planify_token")
-  const res = await fetch(url, { signal, headers: token ? { Authorization: `Bearer ${token}` } : undefined })
-  if (!res.ok) return []
-  const users = await res.json()
-  return users as User[]
+  try {
+    return await api<User[]>(`/users/search?q=${encodeURIComponent(query)}`, { signal })
+  } catch (e) {
+    if (signal?.aborted) throw e
+    return [] as User[]
+  }
 }
 
 export async function getMe(): Promise<User> {
@@ -324,18 +352,11 @@ export async function updateMe(patch: { name: string; email: string }): Promise<
 export async function uploadAvatar(file: File): Promise<{ url: string }> {
   const fd = new FormData()
   fd.append("file", file)
-  const token = localStorage.getItem("planify_token")
-  const res = await fetch(`${API_BASE_URL}/me/avatar`, {
-    method: "POST",
-    headers: token ? { Authorization: `Bearer ${token}` } : undefined,
-    body: fd
-  })
-  if (!res.ok) throw new Error("Upload failed")
-  return res.json()
+  return api<{ url: string }>("/me/avatar", { method: "POST", body: fd })
 }
 
-export async function changePassword(newPassword: string) {
-  return api("/me/password", { method: "PATCH", body: JSON.stringify({ password: newPassword }) })
+export async function changePassword(currentPassword: string, newPassword: string) {
+  return api("/me/password", { method: "PATCH", body: JSON.stringify({ currentPassword, newPassword }) })
 }
 
 export async function createTask(projectId: number, statusId: number, title: string): Promise<CreatedTask> {
@@ -371,4 +392,11 @@ export async function updateSettings(settings: Partial<UserSettings>): Promise<U
 
 export async function fetchMySummary(): Promise<UserSummary> {
   return api<UserSummary>("/me/summary");
-}
\ No newline at end of file
+}
+
+export async function logoutUser() {
+  try {
+    await api("/logout", { method: "POST" }, false)
+  } catch {}
+  clearSession()
+}
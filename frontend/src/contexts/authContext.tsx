@@ -1,5 +1,5 @@
 import { createContext, useState, useContext, useEffect, type ReactNode } from 'react';
-import { loginUser, getMe, type LoginCredentials, type User } from '../api';
+import { loginUser, logoutUser, clearSession, getMe, type LoginCredentials, type User } from '../api';
 
 interface AuthContextType {
   token: string | null;
@@ -26,10 +26,10 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         } catch {
           setToken(null);
           setUser(null);
-          localStorage.removeItem('planify_token');
+          clearSession();
         }
       } else {
-        localStorage.removeItem('planify_token');
+        clearSession();
         setUser(null);
       }
       setIsLoading(false);
@@ -43,6 +43,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   };
 
   const logout = () => {
+    logoutUser();
     setToken(null);
     setUser(null);
   };
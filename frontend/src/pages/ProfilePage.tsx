@@ -43,6 +43,7 @@ export function ProfilePage() {
   const [avatar, setAvatar] = useState<string | null>(null);
 
   const [saving, setSaving] = useState(false);
+  const [currentPw, setCurrentPw] = useState("");
   const [pw, setPw] = useState("");
   const [pw2, setPw2] = useState("");
   const [pwSaving, setPwSaving] = useState(false);
@@ -294,7 +295,14 @@ export function ProfilePage() {
               <Lock className="w-4 h-4" />
               <span>Security</span>
             </div>
-            <label className="text-sm text-secondary">New password</label>
+            <label className="text-sm text-secondary">Current password</label>
+            <input
+              type="password"
+              className="mt-1 w-full px-3 py-2 rounded-md border border-secondary/20 bg-background text-primary"
+              value={currentPw}
+              onChange={(e) => setCurrentPw(e.target.value)}
+            />
+            <label className="mt-4 text-sm text-secondary">New password</label>
             <input
               type="password"
               className="mt-1 w-full px-3 py-2 rounded-md border border-secondary/20 bg-background text-primary"
@@ -309,14 +317,14 @@ export function ProfilePage() {
               onChange={(e) => setPw2(e.target.value)}
             />
             <button
-              disabled={pwSaving || !pw || pw !== pw2}
+              disabled={pwSaving || !currentPw || !pw || pw !== pw2}
               className="mt-4 px-4 py-2 rounded-md bg-accent text-on-accent font-semibold disabled:opacity-60 w-full"
               onClick={async () => {
                 setPwSaving(true);
                 setError(null); setNotice(null);
                 try {
-                  await changePassword(pw);
-                  setPw(""); setPw2("");
+                  await changePassword(currentPw, pw);
+                  setCurrentPw(""); setPw(""); setPw2("");
                   setNotice("Password updated");
                 } catch (e: any) {
                   setError(e?.message || "Failed to update password");